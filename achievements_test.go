@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"cryptcrawl/internal/dungeon"
+)
+
+func TestAchieved(t *testing.T) {
+	def := dungeon.CreateExampleDungeon()
+	m := newDefinitionModel(t, def)
+	m.kills = map[string]int{"skeleton": 9, "rat": 2}
+
+	boneCollector := def.Achievements[0]
+	if m.achieved(boneCollector) {
+		t.Error("Expected 9 skeletons not to be enough for Bone Collector")
+	}
+	m.kills["skeleton"]++
+	if !m.achieved(boneCollector) {
+		t.Error("Expected 10 skeletons to unlock Bone Collector")
+	}
+
+	firstBlood := builtinAchievements[0]
+	if !m.achieved(firstBlood) {
+		t.Error("Expected any kill to count for First Blood")
+	}
+	if m.achieved(dungeon.AchievementDefinition{Trigger: dungeon.AchievementGold, Count: 500}) {
+		t.Error("Expected no gold achievement with an empty purse")
+	}
+}
+
+func TestCheckAchievements(t *testing.T) {
+	m := newModel(1)
+	m.checkAchievements()
+	if m.toast != "" {
+		t.Error("Expected untracked games never to unlock achievements")
+	}
+
+	m.achievements = make(unlockedAchievements)
+	m.gold = 600
+	m.checkAchievements()
+	if _, ok := m.achievements["treasure_hunter"]; !ok {
+		t.Error("Expected Treasure Hunter to be unlocked")
+	}
+	if m.toastView() == "" {
+		t.Error("Expected a toast for the new achievement")
+	}
+
+	// Toasts fade after a few turns and achievements only unlock once
+	m.turns += toastTurns
+	m.checkAchievements()
+	if m.toastView() != "" {
+		t.Error("Expected the toast to have faded")
+	}
+}
+
+func TestFlawlessLevel(t *testing.T) {
+	m := newModel(1)
+	m.descend()
+	if m.flawlessLevels != 1 {
+		t.Errorf("Expected an untouched level to count as flawless, got %d", m.flawlessLevels)
+	}
+
+	m.hurtPlayer(1, "Ouch", "hurt")
+	m.descend()
+	if m.flawlessLevels != 1 {
+		t.Errorf("Expected a level with damage not to count, got %d", m.flawlessLevels)
+	}
+}
+
+func TestAchievementsStore(t *testing.T) {
+	store, err := newAchievementsStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create achievement store: %v", err)
+	}
+
+	unlocked, err := store.Load("player")
+	if err != nil || len(unlocked) != 0 {
+		t.Fatalf("Expected no achievements yet, got %v, %v", unlocked, err)
+	}
+	unlocked["first_blood"] = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if err := store.Save("player", unlocked); err != nil {
+		t.Fatalf("Failed to save achievements: %v", err)
+	}
+	loaded, err := store.Load("player")
+	if err != nil {
+		t.Fatalf("Failed to load achievements: %v", err)
+	}
+	if _, ok := loaded["first_blood"]; !ok {
+		t.Error("Expected the unlocked achievement to be persisted")
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"cryptcrawl/internal/dungeon"
+)
+
+// newBossModel builds a game on a small level with a boss guarding a side room
+func newBossModel(t *testing.T) model {
+	t.Helper()
+	def := &dungeon.DungeonDefinition{
+		Name: "Boss Test",
+		Levels: []dungeon.LevelDefinition{
+			{
+				ID:     "throne",
+				Width:  11,
+				Height: 5,
+				Layout: []string{
+					"###########",
+					"#...#.....#",
+					"#...+.....#",
+					"#...#.....#",
+					"###########",
+				},
+				Rooms: []dungeon.RoomDefinition{
+					{ID: "throne_room", X: 4, Y: 0, Width: 7, Height: 5, Doors: []dungeon.Position{{X: 4, Y: 2}}},
+				},
+				Encounters: []dungeon.EncounterSpawn{
+					{MonsterID: "lord", Count: 1, MinLevel: 1, MaxLevel: 1, Position: &dungeon.Position{X: 8, Y: 2}},
+				},
+				StartPos: dungeon.Position{X: 2, Y: 2},
+				ExitPos:  dungeon.Position{X: 1, Y: 1},
+			},
+		},
+		Monsters: []dungeon.MonsterTemplate{
+			{ID: "skeleton", Name: "Skeleton", Symbol: "S", Health: 5, Damage: "1d4"},
+			{
+				ID: "lord", Name: "Lord", Symbol: "L", Health: 40, Damage: "1d4", Boss: true,
+				Phases: []dungeon.BossPhase{
+					{Threshold: 0.6, Name: "Summoning", Adds: []dungeon.BossAdd{{MonsterID: "skeleton", Count: 2}}},
+					{Threshold: 0.3, Name: "Fury", Damage: "2d6", Abilities: []string{dungeon.AbilityDoubleStrike}},
+				},
+			},
+		},
+		Items: []dungeon.ItemTemplate{
+			{ID: "crown", Name: "Crown", Type: "treasure"},
+		},
+		Events: []dungeon.EventDefinition{
+			{
+				ID: "lord_defeated", Trigger: dungeon.EventBossDefeated, Target: "lord",
+				Actions: []dungeon.EventAction{
+					{Type: dungeon.ActionMessage, Value: "The throne room falls silent."},
+					{Type: dungeon.ActionGiveItem, Value: "crown"},
+				},
+			},
+			{ID: "other_boss", Trigger: dungeon.EventBossDefeated, Target: "someone_else",
+				Actions: []dungeon.EventAction{{Type: dungeon.ActionMessage, Value: "Wrong boss."}}},
+		},
+	}
+	m := newDefinitionModel(t, def)
+	if len(m.monsters) != 1 || !m.isBoss(m.monsters[0]) {
+		t.Fatalf("Expected the boss to be loaded, got %v", m.monsters)
+	}
+	return m
+}
+
+func TestBossPhases(t *testing.T) {
+	m := newBossModel(t)
+	if m.monsters[0].Symbol != 'L' || m.dungeon[2][8] != Monster {
+		t.Fatalf("Expected the boss to keep its own symbol, got %q", m.monsters[0].Symbol)
+	}
+
+	m.monsters[0].Health = 30
+	m.checkBossPhase(0)
+	if m.monsters[0].Phase != 0 {
+		t.Fatal("Expected the boss to stay in its first form above the threshold")
+	}
+
+	m.monsters[0].Health = 20
+	m.checkBossPhase(0)
+	if m.monsters[0].Phase != 1 {
+		t.Fatalf("Expected the boss to enter its first phase, got %d", m.monsters[0].Phase)
+	}
+	if len(m.monsters) != 3 {
+		t.Fatalf("Expected the boss to summon 2 adds, got %d monsters", len(m.monsters))
+	}
+	for _, add := range m.monsters[1:] {
+		if add.TemplateID != "skeleton" || abs(add.Pos.X-8) > 1 || abs(add.Pos.Y-2) > 1 {
+			t.Errorf("Expected a skeleton next to the boss, got %+v", add)
+		}
+	}
+	if m.hasAbility(m.monsters[0], dungeon.AbilityDoubleStrike) {
+		t.Error("Expected no double strike before the last phase")
+	}
+
+	// A big hit skips straight through to the last phase
+	m.monsters[0].Health = 5
+	m.checkBossPhase(0)
+	if m.monsters[0].Phase != 2 || m.monsters[0].DamageDice != "2d6" {
+		t.Errorf("Expected the fury phase with 2d6 damage, got phase %d with %s", m.monsters[0].Phase, m.monsters[0].DamageDice)
+	}
+	if !m.hasAbility(m.monsters[0], dungeon.AbilityDoubleStrike) {
+		t.Error("Expected the fury phase to grant double strike")
+	}
+	if len(m.monsters) != 3 {
+		t.Errorf("Expected phases to only summon once, got %d monsters", len(m.monsters))
+	}
+}
+
+func TestBossRoomLocksUntilDefeated(t *testing.T) {
+	m := newBossModel(t)
+
+	// Nothing happens outside the room
+	m.endTurn()
+	if m.dungeon[2][4] != Door {
+		t.Fatal("Expected the door to stay open while the player is outside")
+	}
+
+	m.vacate(m.player.Pos.X, m.player.Pos.Y)
+	m.player.Pos = Position{X: 7, Y: 2}
+	m.dungeon[2][7] = Player
+	m.endTurn()
+	if m.dungeon[2][4] != LockedDoor {
+		t.Fatal("Expected the door to lock once the player entered the boss room")
+	}
+	if !strings.Contains(m.bossBarView(), "Lord") {
+		t.Errorf("Expected a boss health bar, got %q", m.bossBarView())
+	}
+
+	m.monsters[0].Health = 1
+	m.player.Accuracy = 50
+	killed := false
+	for i := 0; i < 20 && !killed; i++ {
+		killed = m.playerAttack(0)
+	}
+	if !killed {
+		t.Fatal("Expected the player to kill the boss")
+	}
+	if m.dungeon[2][4] != Door {
+		t.Error("Expected the door to open once the boss died")
+	}
+	if m.bossKills != 1 {
+		t.Errorf("Expected a boss kill to be counted, got %d", m.bossKills)
+	}
+	if m.inventoryCount("crown") != 1 {
+		t.Errorf("Expected the boss_defeated event to give the crown, got %v", m.inventory)
+	}
+	log := strings.Join(m.messages, "\n")
+	if !strings.Contains(log, "The throne room falls silent.") || strings.Contains(log, "Wrong boss.") {
+		t.Errorf("Expected only the lord's event to fire, got %v", m.messages)
+	}
+	if m.bossBarView() != "" {
+		t.Error("Expected the boss bar to disappear with the boss")
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"cryptcrawl/internal/dungeon"
+)
+
+// toastTurns is how many turns an achievement toast stays on screen
+const toastTurns = 5
+
+// Global achievement store
+var achievementStore *achievementsStore
+
+// builtinAchievements can be unlocked in every dungeon
+var builtinAchievements = []dungeon.AchievementDefinition{
+	{ID: "first_blood", Name: "First Blood", Description: "Kill your first monster.", Trigger: dungeon.AchievementKills, Count: 1},
+	{ID: "untouchable", Name: "Untouchable", Description: "Clear a level without taking any damage.", Trigger: dungeon.AchievementFlawless, Count: 1},
+	{ID: "escape_artist", Name: "Escape Artist", Description: "Escape a dungeon alive.", Trigger: dungeon.AchievementWin},
+	{ID: "treasure_hunter", Name: "Treasure Hunter", Description: "Hold 500 gold at once.", Trigger: dungeon.AchievementGold, Count: 500},
+	{ID: "giant_slayer", Name: "Giant Slayer", Description: "Defeat a boss.", Trigger: dungeon.AchievementBoss, Count: 1},
+}
+
+// achievementKey identifies an achievement across dungeons; custom
+// achievements are scoped to the dungeon that defines them
+func achievementKey(def *dungeon.DungeonDefinition, a dungeon.AchievementDefinition) string {
+	if def == nil {
+		return a.ID
+	}
+	return def.Name + "/" + a.ID
+}
+
+// unlockedAchievements maps achievement keys to when they were unlocked
+type unlockedAchievements map[string]time.Time
+
+// killsOf counts the player's kills of a monster template, matched by name
+func (m model) killsOf(id string) int {
+	name := strings.ToLower(id)
+	if m.def != nil {
+		for _, template := range m.def.Monsters {
+			if template.ID == id {
+				name = strings.ToLower(template.Name)
+			}
+		}
+	}
+	return m.kills[name]
+}
+
+// achieved reports whether the run has met an achievement's condition
+func (m model) achieved(a dungeon.AchievementDefinition) bool {
+	count := max(a.Count, 1)
+	switch a.Trigger {
+	case dungeon.AchievementKills:
+		if a.Target != "" {
+			return m.killsOf(a.Target) >= count
+		}
+		return m.totalKills() >= count
+	case dungeon.AchievementGold:
+		return m.gold >= count
+	case dungeon.AchievementDepth:
+		return m.level >= count
+	case dungeon.AchievementWin:
+		return m.gameWon
+	case dungeon.AchievementFlawless:
+		return m.flawlessLevels >= count
+	case dungeon.AchievementBoss:
+		if a.Target != "" {
+			return m.killsOf(a.Target) >= count
+		}
+		return m.bossKills >= count
+	}
+	return false
+}
+
+// checkAchievements unlocks every achievement the run has just earned. Only
+// games attached to a player track achievements, so replays never unlock any.
+func (m *model) checkAchievements() {
+	if m.achievements == nil {
+		return
+	}
+
+	check := func(def *dungeon.DungeonDefinition, a dungeon.AchievementDefinition) {
+		id := achievementKey(def, a)
+		if _, ok := m.achievements[id]; ok || !m.achieved(a) {
+			return
+		}
+		m.achievements[id] = time.Now().UTC()
+		m.toast = "Achievement unlocked: " + a.Name
+		m.toastTurn = m.turns
+		m.addMessage(fmt.Sprintf("Achievement unlocked: %s! %s", a.Name, a.Description))
+		if id, ok := saveID(m.playerKey); ok && achievementStore != nil {
+			if err := achievementStore.Save(id, m.achievements); err != nil {
+				m.addMessage(fmt.Sprintf("Could not save the achievement: %v", err))
+			}
+		}
+	}
+	for _, a := range builtinAchievements {
+		check(nil, a)
+	}
+	if m.def != nil {
+		for _, a := range m.def.Achievements {
+			check(m.def, a)
+		}
+	}
+}
+
+// toastView renders the latest achievement toast while it is fresh
+func (m model) toastView() string {
+	if m.toast == "" || m.turns-m.toastTurn >= toastTurns {
+		return ""
+	}
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color("#000000")).Background(lipgloss.Color("#ffd700")).Bold(true).Padding(0, 1)
+	return style.Render("★ "+m.toast) + "\n"
+}
+
+// loadAchievements reads a player's unlocked achievements, starting empty if
+// there are none or they can't be read
+func loadAchievements(key string) unlockedAchievements {
+	if id, ok := saveID(key); ok && achievementStore != nil {
+		if unlocked, err := achievementStore.Load(id); err == nil {
+			return unlocked
+		}
+	}
+	return make(unlockedAchievements)
+}
+
+// achievementsStore keeps each player's unlocked achievements on disk
+type achievementsStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// newAchievementsStore creates an achievement store in the given directory
+func newAchievementsStore(dir string) (*achievementsStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create achievements directory: %w", err)
+	}
+	return &achievementsStore{dir: dir}, nil
+}
+
+// path returns the file holding a player's achievements
+func (s *achievementsStore) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// Save writes a player's unlocked achievements
+func (s *achievementsStore) Save(id string, unlocked unlockedAchievements) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.MarshalIndent(unlocked, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode achievements: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never loses earlier unlocks
+	tmp := s.path(id) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write achievements: %w", err)
+	}
+	return os.Rename(tmp, s.path(id))
+}
+
+// Load reads a player's unlocked achievements
+func (s *achievementsStore) Load(id string) (unlockedAchievements, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlocked := make(unlockedAchievements)
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return unlocked, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read achievements: %w", err)
+	}
+	if err := json.Unmarshal(data, &unlocked); err != nil {
+		return nil, fmt.Errorf("failed to parse achievements: %w", err)
+	}
+	return unlocked, nil
+}
+
+// achievementKeys are the achievement list's key bindings
+var achievementKeys = struct {
+	Back key.Binding
+	Quit key.Binding
+}{
+	Back: key.NewBinding(key.WithKeys("esc", "v"), key.WithHelp("esc", "back")),
+	Quit: key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "quit")),
+}
+
+// achievementsModel lists every achievement and which ones the player has unlocked
+type achievementsModel struct {
+	parent   tea.Model // Screen to go back to, if any
+	viewport viewport.Model
+}
+
+// newAchievementsModel creates the achievement list for a player
+func newAchievementsModel(parent tea.Model, unlocked unlockedAchievements) achievementsModel {
+	am := achievementsModel{parent: parent, viewport: viewport.New(80, 24)}
+	am.viewport.SetContent(renderAchievements(unlocked))
+	return am
+}
+
+// renderAchievements lists the built-in achievements, then each dungeon's own
+func renderAchievements(unlocked unlockedAchievements) string {
+	done := lipgloss.NewStyle().Foreground(lipgloss.Color("#ffd700"))
+	locked := lipgloss.NewStyle().Foreground(lipgloss.Color("#888888"))
+
+	var b strings.Builder
+	total, count := 0, 0
+	section := func(title string, def *dungeon.DungeonDefinition, list []dungeon.AchievementDefinition) {
+		if len(list) == 0 {
+			return
+		}
+		b.WriteString("\n  " + lipgloss.NewStyle().Bold(true).Render(title) + "\n")
+		for _, a := range list {
+			total++
+			if at, ok := unlocked[achievementKey(def, a)]; ok {
+				count++
+				b.WriteString("  " + done.Render(fmt.Sprintf("★ %-20s %s", a.Name, a.Description)))
+				b.WriteString(locked.Render("  "+at.Format("2006-01-02")) + "\n")
+			} else {
+				b.WriteString("  " + locked.Render(fmt.Sprintf("☆ %-20s %s", a.Name, a.Description)) + "\n")
+			}
+		}
+	}
+
+	section("General", nil, builtinAchievements)
+	if dungeonLoader != nil {
+		for _, def := range dungeonLoader.Dungeons {
+			section(def.Name, def, def.Achievements)
+		}
+	}
+	return fmt.Sprintf("\n  ACHIEVEMENTS (%d/%d unlocked)\n%s", count, total, b.String())
+}
+
+// Init initializes the achievement list
+func (am achievementsModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update scrolls the list and leaves the screen
+func (am achievementsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, achievementKeys.Quit):
+			return am, tea.Quit
+		case key.Matches(msg, achievementKeys.Back):
+			if am.parent != nil {
+				return am.parent, nil
+			}
+			return am, tea.Quit
+		}
+	case tea.WindowSizeMsg:
+		am.viewport.Width = msg.Width
+		am.viewport.Height = msg.Height - 2 // Leave room for the footer
+	}
+
+	var cmd tea.Cmd
+	am.viewport, cmd = am.viewport.Update(msg)
+	return am, cmd
+}
+
+// View renders the achievement list
+func (am achievementsModel) View() string {
+	footer := "↑/↓ scroll • q quit"
+	if am.parent != nil {
+		footer = "↑/↓ scroll • esc back • q quit"
+	}
+	return am.viewport.View() + "\n" + lipgloss.NewStyle().Foreground(lipgloss.Color("#888888")).Render(footer)
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"cryptcrawl/internal/dungeon"
+)
+
+// bossBarWidth is how many cells the boss health bar spans
+const bossBarWidth = 20
+
+// isBoss reports whether a monster is a boss
+func (m model) isBoss(monster Entity) bool {
+	template := m.monsterTemplate(monster.TemplateID)
+	return template != nil && template.Boss
+}
+
+// bossPhase returns the phase a boss is in, or nil before its first phase
+func (m model) bossPhase(monster Entity) *dungeon.BossPhase {
+	template := m.monsterTemplate(monster.TemplateID)
+	if template == nil || monster.Phase < 1 || monster.Phase > len(template.Phases) {
+		return nil
+	}
+	return &template.Phases[monster.Phase-1]
+}
+
+// hasAbility reports whether a monster currently has an ability. A boss
+// phase with abilities replaces the ones of the monster's template.
+func (m model) hasAbility(monster Entity, ability string) bool {
+	template := m.monsterTemplate(monster.TemplateID)
+	if template == nil {
+		return false
+	}
+	abilities := template.Abilities
+	if phase := m.bossPhase(monster); phase != nil && len(phase.Abilities) > 0 {
+		abilities = phase.Abilities
+	}
+	for _, a := range abilities {
+		if a == ability {
+			return true
+		}
+	}
+	return false
+}
+
+// checkBossPhase moves the boss at index i into every phase its health has
+// fallen to. Adds are appended to the monster list, so callers must not hold
+// on to pointers into it.
+func (m *model) checkBossPhase(i int) {
+	template := m.monsterTemplate(m.monsters[i].TemplateID)
+	if template == nil || !template.Boss {
+		return
+	}
+	for m.monsters[i].Health > 0 && m.monsters[i].Phase < len(template.Phases) {
+		phase := template.Phases[m.monsters[i].Phase]
+		if float64(m.monsters[i].Health) > phase.Threshold*float64(m.monsters[i].MaxHealth) {
+			return
+		}
+		m.monsters[i].Phase++
+		m.enterBossPhase(i, phase)
+	}
+}
+
+// enterBossPhase applies a phase's changes to the boss at index i
+func (m *model) enterBossPhase(i int, phase dungeon.BossPhase) {
+	boss := &m.monsters[i]
+	if phase.Message != "" {
+		m.addMessage(phase.Message)
+	} else {
+		m.addMessage(fmt.Sprintf("The %s grows more dangerous!", monsterName(*boss)))
+	}
+	if phase.Damage != "" && phase.Damage.Valid() {
+		boss.DamageDice = phase.Damage
+		boss.Damage = int(phase.Damage.Average())
+	}
+	if phase.Accuracy != 0 {
+		boss.Accuracy = phase.Accuracy
+	}
+	if phase.Evasion != 0 {
+		boss.Evasion = phase.Evasion
+	}
+
+	pos := boss.Pos
+	summoned := 0
+	for _, add := range phase.Adds {
+		template := m.monsterTemplate(add.MonsterID)
+		if template == nil {
+			continue
+		}
+		for n := 0; n < max(add.Count, 1); n++ {
+			spot, ok := m.emptyNeighbour(pos)
+			if !ok {
+				break
+			}
+			m.monsters = append(m.monsters, monsterFromTemplate(*template, spot))
+			m.dungeon[spot.Y][spot.X] = Monster
+			summoned++
+		}
+	}
+	if summoned > 0 {
+		m.addMessage(fmt.Sprintf("The %s calls for help!", monsterName(m.monsters[i])))
+	}
+}
+
+// monsterFromTemplate creates an unscaled monster from a template
+func monsterFromTemplate(template dungeon.MonsterTemplate, pos Position) Entity {
+	monster := Entity{
+		Pos:        pos,
+		Symbol:     TileMonster,
+		Health:     max(template.Health, 1),
+		MaxHealth:  max(template.Health, 1),
+		Damage:     int(template.Damage.Average()),
+		DamageDice: template.Damage,
+		Accuracy:   template.Accuracy,
+		Evasion:    template.Evasion,
+		Name:       template.Name,
+		TemplateID: template.ID,
+		Alert:      defaultAlarmDuration, // Summoned monsters come straight for the player
+	}
+	if template.Symbol != "" {
+		monster.Symbol = []rune(template.Symbol)[0]
+	}
+	return monster
+}
+
+// emptyNeighbour finds a free floor tile next to a position
+func (m model) emptyNeighbour(pos Position) (Position, bool) {
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			x, y := pos.X+dx, pos.Y+dy
+			if m.inBounds(x, y) && m.dungeon[y][x] == Empty {
+				return Position{X: x, Y: y}, true
+			}
+		}
+	}
+	return Position{}, false
+}
+
+// bossRoom returns the room of the current level a position lies in, or nil
+func (m model) bossRoom(pos Position) *dungeon.RoomDefinition {
+	levelDef := m.currentLevelDef()
+	if levelDef == nil {
+		return nil
+	}
+	rooms := levelDef.Rooms
+	for i := range rooms {
+		r := rooms[i]
+		if pos.X >= r.X && pos.X < r.X+r.Width && pos.Y >= r.Y && pos.Y < r.Y+r.Height {
+			return &rooms[i]
+		}
+	}
+	return nil
+}
+
+// lockBossRooms shuts the doors of a room once the player is inside it with
+// a living boss
+func (m *model) lockBossRooms() {
+	room := m.bossRoom(m.player.Pos)
+	if room == nil {
+		return
+	}
+	guarded := false
+	for _, monster := range m.monsters {
+		if monster.Health > 0 && m.isBoss(monster) {
+			if r := m.bossRoom(monster.Pos); r != nil && r.ID == room.ID {
+				guarded = true
+				break
+			}
+		}
+	}
+	if !guarded {
+		return
+	}
+
+	locked := false
+	for _, door := range room.Doors {
+		if m.inBounds(door.X, door.Y) && (m.dungeon[door.Y][door.X] == Door || m.dungeon[door.Y][door.X] == Empty) {
+			m.dungeon[door.Y][door.X] = LockedDoor
+			locked = true
+		}
+	}
+	if locked {
+		m.addMessage("The doors slam shut behind you!")
+	}
+}
+
+// unlockBossRooms opens every locked door on the level once no boss is left alive
+func (m *model) unlockBossRooms() {
+	for _, monster := range m.monsters {
+		if monster.Health > 0 && m.isBoss(monster) {
+			return
+		}
+	}
+	unlocked := false
+	for y := range m.dungeon {
+		for x, tile := range m.dungeon[y] {
+			if tile == LockedDoor {
+				m.dungeon[y][x] = Door
+				unlocked = true
+			}
+		}
+	}
+	if unlocked {
+		m.addMessage("The doors grind open.")
+	}
+}
+
+// defeatBoss opens the boss's room and fires its boss_defeated events
+func (m *model) defeatBoss(boss Entity) {
+	m.addMessage(fmt.Sprintf("The %s has been defeated!", monsterName(boss)))
+	m.unlockBossRooms()
+	m.fireEvents(dungeon.EventBossDefeated, boss.TemplateID)
+}
+
+// fireEvents runs the dungeon's events for a trigger. Events with a target
+// only fire for that target.
+func (m *model) fireEvents(trigger, target string) {
+	if m.def == nil {
+		return
+	}
+	for _, event := range m.def.Events {
+		if event.Trigger != trigger || (event.Target != "" && event.Target != target) {
+			continue
+		}
+		for _, a := range event.Actions {
+			switch a.Type {
+			case dungeon.ActionMessage:
+				if text, ok := a.Value.(string); ok {
+					m.addMessage(text)
+				}
+			case dungeon.ActionGiveItem:
+				id, _ := a.Value.(string)
+				if template := m.itemTemplate(id); template != nil {
+					item := ItemInstance{Template: *template, Count: 1}
+					m.addToInventory(item)
+					m.addMessage(fmt.Sprintf("You receive %s.", withArticle(m.itemName(item))))
+				}
+			case dungeon.ActionSetFlag:
+				if m.flags == nil {
+					m.flags = make(map[string]bool)
+				}
+				m.flags[a.Target] = true
+			}
+		}
+	}
+}
+
+// regenerateMonsters heals the monsters that regenerate
+func (m *model) regenerateMonsters() {
+	for i := range m.monsters {
+		monster := &m.monsters[i]
+		if monster.Health > 0 && monster.Health < monster.MaxHealth && m.hasAbility(*monster, dungeon.AbilityRegenerate) {
+			monster.Health++
+		}
+	}
+}
+
+// engagedBoss returns the living boss the player can see, or nil
+func (m model) engagedBoss() *Entity {
+	for i := range m.monsters {
+		monster := m.monsters[i]
+		if monster.Health > 0 && m.isBoss(monster) && m.isVisible(monster.Pos.X, monster.Pos.Y) {
+			return &m.monsters[i]
+		}
+	}
+	return nil
+}
+
+// bossBarView renders the health bar of the boss the player is facing
+func (m model) bossBarView() string {
+	boss := m.engagedBoss()
+	if boss == nil {
+		return ""
+	}
+
+	filled := 0
+	if boss.MaxHealth > 0 {
+		filled = min(bossBarWidth*boss.Health/boss.MaxHealth, bossBarWidth)
+	}
+	if boss.Health > 0 {
+		filled = max(filled, 1)
+	}
+	bar := lipgloss.NewStyle().Foreground(lipgloss.Color("#ff0000")).Render(strings.Repeat("█", filled)) +
+		lipgloss.NewStyle().Foreground(lipgloss.Color("#444444")).Render(strings.Repeat("░", bossBarWidth-filled))
+
+	line := fmt.Sprintf("☠ %s %s %d/%d", lipgloss.NewStyle().Bold(true).Render(boss.Name), bar, boss.Health, boss.MaxHealth)
+	if phase := m.bossPhase(*boss); phase != nil && phase.Name != "" {
+		line += " | " + lipgloss.NewStyle().Foreground(lipgloss.Color("#ff8800")).Render(phase.Name)
+	}
+	return line + "\n"
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"cryptcrawl/internal/dungeon"
+)
+
+// attackResult describes how an attack roll landed
+type attackResult int
+
+// Attack results, from worst to best
+const (
+	attackMiss attackResult = iota
+	attackGlancing
+	attackHit
+	attackCritical
+)
+
+// Hit roll tuning: a d20 plus accuracy must reach baseDefense plus the
+// target's evasion, and rolls falling just short still graze the target
+const (
+	baseDefense    = 8
+	glancingMargin = 3
+	playerAccuracy = 2
+)
+
+// rollDamage rolls an entity's damage dice, or uses its flat damage when it has none
+func rollDamage(rng *rand.Rand, e Entity) int {
+	if e.DamageDice != "" && e.DamageDice.Valid() {
+		return max(e.DamageDice.Roll(rng), 0)
+	}
+	return e.Damage
+}
+
+// resolveAttack rolls an attack and returns how it landed and the damage it deals.
+// A natural 1 always misses and a natural 20 is a critical hit for double damage.
+func resolveAttack(rng *rand.Rand, attacker, defender Entity) (attackResult, int) {
+	roll := rng.Intn(20) + 1
+	total := roll + attacker.Accuracy
+	defense := baseDefense + defender.Evasion
+
+	switch {
+	case roll == 1:
+		return attackMiss, 0
+	case roll == 20:
+		return attackCritical, rollDamage(rng, attacker) * 2
+	case total >= defense:
+		return attackHit, rollDamage(rng, attacker)
+	case total >= defense-glancingMargin:
+		return attackGlancing, max(rollDamage(rng, attacker)/2, 1)
+	}
+	return attackMiss, 0
+}
+
+// monsterName returns how a monster is referred to in the message log
+func monsterName(monster Entity) string {
+	if monster.Name == "" {
+		return "monster"
+	}
+	return strings.ToLower(monster.Name)
+}
+
+// playerAttack makes the player attack the monster at index i and reports
+// whether the monster was killed
+func (m *model) playerAttack(i int) bool {
+	monster := &m.monsters[i]
+	name := monsterName(*monster)
+
+	result, damage := resolveAttack(m.rng, m.player, *monster)
+	switch result {
+	case attackMiss:
+		m.addMessage(fmt.Sprintf("You miss the %s.", name))
+		return false
+	case attackGlancing:
+		m.addMessage(fmt.Sprintf("You graze the %s for %d damage.", name, damage))
+	case attackCritical:
+		m.addMessage(fmt.Sprintf("Critical hit! You strike the %s for %d damage!", name, damage))
+	default:
+		m.addMessage(fmt.Sprintf("You hit the %s for %d damage!", name, damage))
+	}
+	monster.Health -= damage
+
+	// Check if monster is dead
+	if monster.Health > 0 {
+		m.checkBossPhase(i)
+		return false
+	}
+	m.addMessage(fmt.Sprintf("You killed the %s!", name))
+	m.recordKill(*monster)
+	m.vacate(monster.Pos.X, monster.Pos.Y)
+	m.dropLoot(*monster)
+	// Remove the monster from the list
+	killed := *monster
+	m.monsters = append(m.monsters[:i], m.monsters[i+1:]...)
+	if m.isBoss(killed) {
+		m.defeatBoss(killed)
+	}
+	return true
+}
+
+// recordKill counts a monster killed by the player
+func (m *model) recordKill(monster Entity) {
+	if m.kills == nil {
+		m.kills = make(map[string]int)
+	}
+	m.kills[monsterName(monster)]++
+	if m.isBoss(monster) {
+		m.bossKills++
+	}
+}
+
+// monsterAttack makes the monster at index i attack the player
+func (m *model) monsterAttack(i int) {
+	name := monsterName(m.monsters[i])
+
+	strikes := 1
+	if m.hasAbility(m.monsters[i], dungeon.AbilityDoubleStrike) {
+		strikes = 2
+	}
+	for ; strikes > 0 && !m.gameOver; strikes-- {
+		result, damage := resolveAttack(m.rng, m.monsters[i], m.player)
+		cause := "killed by " + withArticle(name)
+		switch result {
+		case attackMiss:
+			m.addMessage(fmt.Sprintf("The %s misses you.", name))
+		case attackGlancing:
+			m.hurtPlayer(damage, fmt.Sprintf("The %s grazes you for %d damage.", name, damage), cause)
+		case attackCritical:
+			m.hurtPlayer(damage, fmt.Sprintf("The %s lands a critical hit for %d damage!", name, damage), cause)
+		default:
+			m.hurtPlayer(damage, fmt.Sprintf("The %s hits you for %d damage!", name, damage), cause)
+		}
+	}
+}
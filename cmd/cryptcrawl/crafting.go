@@ -0,0 +1,284 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"cryptcrawl/internal/dungeon"
+)
+
+// stationTemplate looks up a crafting station template in the loaded dungeon definition
+func (m model) stationTemplate(id string) *dungeon.StationTemplate {
+	if m.def == nil {
+		return nil
+	}
+	for i := range m.def.Stations {
+		if m.def.Stations[i].ID == id {
+			return &m.def.Stations[i]
+		}
+	}
+	return nil
+}
+
+// stationAt returns the crafting station at the given position, or nil
+func (m model) stationAt(x, y int) *dungeon.StationTemplate {
+	levelDef := m.currentLevelDef()
+	if levelDef == nil {
+		return nil
+	}
+	for _, spawn := range levelDef.Stations {
+		if spawn.Position.X == x && spawn.Position.Y == y {
+			return m.stationTemplate(spawn.StationID)
+		}
+	}
+	return nil
+}
+
+// nearStation reports whether the player stands next to a station of the given type
+func (m model) nearStation(id string) bool {
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			x, y := m.player.Pos.X+dx, m.player.Pos.Y+dy
+			if !m.inBounds(x, y) || m.dungeon[y][x] != Station {
+				continue
+			}
+			if station := m.stationAt(x, y); station != nil && station.ID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// renderStationAt renders the crafting station at the given position
+func (m model) renderStationAt(x, y int) string {
+	station := m.stationAt(x, y)
+	if station == nil || station.Symbol == "" {
+		return RenderTile(Station)
+	}
+	style := lipgloss.NewStyle().Bold(true)
+	if station.Color != "" {
+		style = style.Foreground(lipgloss.Color(station.Color))
+	}
+	return style.Render(string([]rune(station.Symbol)[0]))
+}
+
+// inventoryCount counts the items of a template the player carries
+func (m model) inventoryCount(id string) int {
+	count := 0
+	for _, item := range m.inventory {
+		if item.Template.ID == id {
+			count += item.Count
+		}
+	}
+	return count
+}
+
+// takeFromInventory removes a number of items of a template from the inventory
+func (m *model) takeFromInventory(id string, count int) {
+	for i := 0; i < len(m.inventory) && count > 0; {
+		if m.inventory[i].Template.ID != id {
+			i++
+			continue
+		}
+		taken := min(count, m.inventory[i].Count)
+		m.inventory[i].Count -= taken
+		count -= taken
+		if m.inventory[i].Count <= 0 {
+			m.inventory = append(m.inventory[:i], m.inventory[i+1:]...)
+		}
+	}
+}
+
+// recipeName names a recipe after the item it makes
+func (m model) recipeName(recipe dungeon.RecipeDefinition) string {
+	if template := m.itemTemplate(recipe.Output); template != nil {
+		return m.itemName(ItemInstance{Template: *template, Count: max(recipe.Count, 1)})
+	}
+	return recipe.ID
+}
+
+// missingForRecipe explains why a recipe can't be crafted right now, or
+// returns an empty string if it can
+func (m model) missingForRecipe(recipe dungeon.RecipeDefinition) string {
+	if m.itemTemplate(recipe.Output) == nil {
+		return "unknown item"
+	}
+	var missing []string
+	for _, input := range recipe.Inputs {
+		if have := m.inventoryCount(input.ItemID); have < max(input.Count, 1) {
+			missing = append(missing, fmt.Sprintf("%d more %s", max(input.Count, 1)-have, m.inputName(input.ItemID)))
+		}
+	}
+	if recipe.Station != "" && !m.nearStation(recipe.Station) {
+		name := recipe.Station
+		if station := m.stationTemplate(recipe.Station); station != nil {
+			name = station.Name
+		}
+		missing = append(missing, "a "+name+" nearby")
+	}
+	return strings.Join(missing, ", ")
+}
+
+// inputName names a recipe ingredient
+func (m model) inputName(id string) string {
+	if template := m.itemTemplate(id); template != nil {
+		return m.itemName(ItemInstance{Template: *template, Count: 1})
+	}
+	return id
+}
+
+// craftAction returns the action that crafts the recipe at index i
+func craftAction(i int) action {
+	return action(actionCraftFirst + action(i))
+}
+
+// craft makes the item of the recipe at index i from the player's inventory
+func (m *model) craft(i int) {
+	if m.def == nil || i < 0 || i >= len(m.def.Recipes) {
+		m.addMessage("You don't know how to craft that.")
+		return
+	}
+
+	recipe := m.def.Recipes[i]
+	if missing := m.missingForRecipe(recipe); missing != "" {
+		m.addMessage(fmt.Sprintf("To craft %s you need %s.", m.recipeName(recipe), missing))
+		return
+	}
+
+	for _, input := range recipe.Inputs {
+		m.takeFromInventory(input.ItemID, max(input.Count, 1))
+	}
+	output := ItemInstance{Template: *m.itemTemplate(recipe.Output), Count: max(recipe.Count, 1)}
+	m.addToInventory(output)
+	m.addMessage(fmt.Sprintf("You craft %s.", m.itemName(output)))
+
+	// Crafting takes a turn, and the monsters don't wait for you
+	m.moveMonsters()
+	m.endTurn()
+}
+
+// craftMsg asks the game to craft a recipe, so the crafting screen goes
+// through the game's usual update
+type craftMsg struct {
+	recipe int
+}
+
+// craftingKeys are the crafting screen's key bindings
+var craftingKeys = struct {
+	Up    key.Binding
+	Down  key.Binding
+	Craft key.Binding
+	Back  key.Binding
+	Quit  key.Binding
+}{
+	Up:    key.NewBinding(key.WithKeys("up", "w", "k"), key.WithHelp("↑", "up")),
+	Down:  key.NewBinding(key.WithKeys("down", "s", "j"), key.WithHelp("↓", "down")),
+	Craft: key.NewBinding(key.WithKeys("enter", "space"), key.WithHelp("enter", "craft")),
+	Back:  key.NewBinding(key.WithKeys("esc", "c"), key.WithHelp("esc", "back")),
+	Quit:  key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "quit")),
+}
+
+// craftingModel lists the dungeon's recipes and crafts them for the player
+type craftingModel struct {
+	game   model
+	cursor int
+}
+
+// newCraftingModel creates the crafting screen for a game
+func newCraftingModel(m model) craftingModel {
+	return craftingModel{game: m}
+}
+
+// Init initializes the crafting screen
+func (c craftingModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update picks a recipe and crafts it
+func (c craftingModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	recipes := 0
+	if c.game.def != nil {
+		recipes = min(len(c.game.def.Recipes), maxRecipes)
+	}
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, craftingKeys.Quit):
+			return c, tea.Quit
+		case key.Matches(msg, craftingKeys.Back):
+			return c.game, nil
+		case key.Matches(msg, craftingKeys.Up) && recipes > 0:
+			c.cursor = (c.cursor + recipes - 1) % recipes
+		case key.Matches(msg, craftingKeys.Down) && recipes > 0:
+			c.cursor = (c.cursor + 1) % recipes
+		case key.Matches(msg, craftingKeys.Craft) && recipes > 0:
+			next, cmd := c.game.Update(craftMsg{recipe: c.cursor})
+			c.game = next.(model)
+			if c.game.gameOver || c.game.gameWon {
+				return c.game, cmd
+			}
+			return c, cmd
+		}
+	case tea.WindowSizeMsg:
+		next, cmd := c.game.Update(msg)
+		c.game = next.(model)
+		return c, cmd
+	}
+	return c, nil
+}
+
+// View renders the recipes, with the ones the player can craft highlighted
+func (c craftingModel) View() string {
+	m := c.game
+	ready := lipgloss.NewStyle().Foreground(lipgloss.Color("#00ff00"))
+	blocked := lipgloss.NewStyle().Foreground(lipgloss.Color("#888888"))
+	selected := lipgloss.NewStyle().Foreground(lipgloss.Color("#ffff00")).Bold(true)
+
+	var b strings.Builder
+	b.WriteString("\n  CRAFTING\n\n")
+	if m.def == nil || len(m.def.Recipes) == 0 {
+		b.WriteString("  There is nothing to craft in this dungeon.\n")
+	} else {
+		for i, recipe := range m.def.Recipes {
+			if i >= maxRecipes {
+				break
+			}
+			var inputs []string
+			for _, input := range recipe.Inputs {
+				inputs = append(inputs, fmt.Sprintf("%d/%d %s", m.inventoryCount(input.ItemID), max(input.Count, 1), m.inputName(input.ItemID)))
+			}
+			line := fmt.Sprintf("%s  ← %s", m.recipeName(recipe), strings.Join(inputs, ", "))
+			if recipe.Station != "" {
+				name := recipe.Station
+				if station := m.stationTemplate(recipe.Station); station != nil {
+					name = station.Name
+				}
+				line += " at a " + name
+			}
+
+			cursor := "  "
+			if i == c.cursor {
+				cursor = selected.Render("> ")
+			}
+			if m.missingForRecipe(recipe) == "" {
+				b.WriteString("  " + cursor + ready.Render(line) + "\n")
+			} else {
+				b.WriteString("  " + cursor + blocked.Render(line) + "\n")
+			}
+		}
+	}
+
+	b.WriteString("\n")
+	start := max(len(m.messages)-3, 0)
+	for _, msg := range m.messages[start:] {
+		fmt.Fprintf(&b, "  %s\n", msg)
+	}
+	b.WriteString("\n  " + blocked.Render("↑/↓ choose • enter craft • esc back • q quit") + "\n")
+	return b.String()
+}
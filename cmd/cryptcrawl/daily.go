@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// dailyDateFormat is how daily challenge dates are written in seeds and file names
+const dailyDateFormat = "2006-01-02"
+
+// dailyBoardSize is how many entries the daily leaderboard shows
+const dailyBoardSize = 10
+
+// errAlreadyPlayed is returned when a player starts a second daily attempt on the same day
+var errAlreadyPlayed = errors.New("you have already played today's daily challenge")
+
+// Global daily challenge store
+var dailyBoard *dailyStore
+
+// dailyDate returns the daily challenge date for a moment in time, in UTC
+func dailyDate(t time.Time) string {
+	return t.UTC().Format(dailyDateFormat)
+}
+
+// dailySeed derives the seed every player shares for a day's challenge
+func dailySeed(date string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("cryptcrawl-daily-" + date))
+	return int64(h.Sum64() & (1<<63 - 1))
+}
+
+// dailyEntry is one player's attempt at a daily challenge
+type dailyEntry struct {
+	Key      string    `json:"key"`
+	Name     string    `json:"name"`
+	Started  time.Time `json:"started"`
+	Finished bool      `json:"finished"`
+	Won      bool      `json:"won"`
+	Level    int       `json:"level"`
+	Gold     int       `json:"gold"`
+	Turns    int       `json:"turns"`
+}
+
+// dailyStore keeps the daily leaderboards on disk, one file per day
+type dailyStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// newDailyStore creates a daily challenge store in the given directory
+func newDailyStore(dir string) (*dailyStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create daily directory: %w", err)
+	}
+	return &dailyStore{dir: dir}, nil
+}
+
+// path returns the file holding the entries for a date
+func (s *dailyStore) path(date string) string {
+	return filepath.Join(s.dir, date+".json")
+}
+
+// load reads the entries for a date; the caller must hold the lock
+func (s *dailyStore) load(date string) ([]dailyEntry, error) {
+	data, err := os.ReadFile(s.path(date))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read daily leaderboard: %w", err)
+	}
+
+	var entries []dailyEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse daily leaderboard: %w", err)
+	}
+	return entries, nil
+}
+
+// save writes the entries for a date; the caller must hold the lock
+func (s *dailyStore) save(date string, entries []dailyEntry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode daily leaderboard: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a half-written board
+	tmp := s.path(date) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write daily leaderboard: %w", err)
+	}
+	return os.Rename(tmp, s.path(date))
+}
+
+// Start records a player's attempt at a day's challenge, refusing a second one
+func (s *dailyStore) Start(date, key, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.load(date)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Key == key {
+			return errAlreadyPlayed
+		}
+	}
+
+	entries = append(entries, dailyEntry{Key: key, Name: name, Started: time.Now().UTC()})
+	return s.save(date, entries)
+}
+
+// Finish records the result of a player's attempt
+func (s *dailyStore) Finish(date, key string, result dailyEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.load(date)
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		if entries[i].Key != key || entries[i].Finished {
+			continue
+		}
+		entries[i].Finished = true
+		entries[i].Won = result.Won
+		entries[i].Level = result.Level
+		entries[i].Gold = result.Gold
+		entries[i].Turns = result.Turns
+		return s.save(date, entries)
+	}
+	return fmt.Errorf("no daily attempt in progress for this player")
+}
+
+// Entries returns the finished attempts for a date, best first
+func (s *dailyStore) Entries(date string) ([]dailyEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.load(date)
+	if err != nil {
+		return nil, err
+	}
+
+	finished := entries[:0]
+	for _, entry := range entries {
+		if entry.Finished {
+			finished = append(finished, entry)
+		}
+	}
+	sort.SliceStable(finished, func(i, j int) bool {
+		return dailyEntryLess(finished[i], finished[j])
+	})
+	return finished, nil
+}
+
+// dailyEntryLess ranks escapes first, then depth, gold and fewest turns
+func dailyEntryLess(a, b dailyEntry) bool {
+	if a.Won != b.Won {
+		return a.Won
+	}
+	if a.Level != b.Level {
+		return a.Level > b.Level
+	}
+	if a.Gold != b.Gold {
+		return a.Gold > b.Gold
+	}
+	return a.Turns < b.Turns
+}
+
+// newDailyModel starts the shared daily challenge for a date
+func newDailyModel(date string) model {
+	m := newGame(dailySeed(date), nil)
+	m.dailyDate = date
+	m.addMessage(fmt.Sprintf("Daily challenge for %s. You only get one attempt, make it count!", date))
+	return m
+}
+
+// submitDaily records the result of a finished daily challenge
+func (m *model) submitDaily() {
+	if m.dailyDate == "" || dailyBoard == nil {
+		return
+	}
+	result := dailyEntry{Won: m.gameWon, Level: m.level, Gold: m.gold, Turns: m.turns}
+	if err := dailyBoard.Finish(m.dailyDate, m.playerKey, result); err != nil {
+		m.addMessage(fmt.Sprintf("Could not record your daily result: %v", err))
+	}
+	m.dailyEntries, m.dailyErr = dailyBoard.Entries(m.dailyDate)
+}
+
+// dailyBoardView renders the leaderboard for the current daily challenge
+func (m model) dailyBoardView() string {
+	if m.dailyDate == "" || !m.finished || dailyBoard == nil {
+		return ""
+	}
+	if m.dailyErr != nil {
+		return fmt.Sprintf("  Daily leaderboard unavailable: %v\n\n", m.dailyErr)
+	}
+	return renderDailyBoard(m.dailyDate, m.dailyEntries, m.playerKey) + "\n"
+}
+
+// renderDailyBoard renders the top daily entries, highlighting the given player
+func renderDailyBoard(date string, entries []dailyEntry, key string) string {
+	title := lipgloss.NewStyle().Bold(true)
+	highlight := lipgloss.NewStyle().Foreground(lipgloss.Color("#ffff00"))
+
+	var b strings.Builder
+	b.WriteString("  " + title.Render(fmt.Sprintf("Daily leaderboard %s", date)) + "\n")
+	if len(entries) == 0 {
+		b.WriteString("  No finished runs yet.\n")
+		return b.String()
+	}
+
+	for i, entry := range entries {
+		if i >= dailyBoardSize {
+			break
+		}
+		result := fmt.Sprintf("died on level %d", entry.Level)
+		if entry.Won {
+			result = "escaped"
+		}
+		line := fmt.Sprintf("%2d. %-16s %-18s %5d gold %6d turns", i+1, entry.Name, result, entry.Gold, entry.Turns)
+		if entry.Key == key {
+			line = highlight.Render(line)
+		}
+		b.WriteString("  " + line + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"cryptcrawl/internal/dungeon"
+)
+
+// Endless descent defaults, each can be overridden by the dungeon's endless settings
+const (
+	defaultTreasureEvery = 5  // Every this many levels is a treasure vault
+	defaultBossEvery     = 10 // Every this many levels is a boss floor
+	endlessSuffix        = " (endless)"
+)
+
+// Default difficulty curves of endless descent
+var (
+	defaultMonsterCurve = dungeon.DifficultyCurve{Linear: 0.1, Growth: 1.05, Max: 10}
+	defaultLootCurve    = dungeon.DifficultyCurve{Linear: 0.15, Max: 5}
+)
+
+// wardenTemplate guards the boss floors of dungeons without a boss of their own
+var wardenTemplate = dungeon.MonsterTemplate{
+	ID:       "warden",
+	Name:     "Warden of the Deep",
+	Symbol:   "W",
+	Health:   20,
+	Damage:   "1d6+1",
+	Accuracy: 2,
+	Boss:     true,
+	Phases: []dungeon.BossPhase{
+		{
+			Threshold: 0.5,
+			Name:      "Enraged",
+			Message:   "The Warden of the Deep bellows and lashes out wildly!",
+			Damage:    "2d4+2",
+		},
+	},
+}
+
+// levelKind tells ordinary levels from the special levels of endless descent
+type levelKind int
+
+// Kinds of level
+const (
+	normalLevel levelKind = iota
+	treasureLevel
+	bossLevel
+)
+
+// newEndlessModel starts an endless descent, where the stairs keep going down
+func newEndlessModel(seed int64) model {
+	m := newGame(seed, currentDungeon())
+	m.endless = true
+	m.addMessage("Endless descent: the stairs never stop going down. How deep can you get?")
+	return m
+}
+
+// endlessConfig returns the dungeon's endless settings, with defaults filled in
+func (m model) endlessConfig() dungeon.EndlessConfig {
+	var c dungeon.EndlessConfig
+	if m.def != nil && m.def.Endless != nil {
+		c = *m.def.Endless
+	}
+	if c.MonsterCurve == nil {
+		c.MonsterCurve = &defaultMonsterCurve
+	}
+	if c.LootCurve == nil {
+		c.LootCurve = &defaultLootCurve
+	}
+	if c.TreasureEvery <= 0 {
+		c.TreasureEvery = defaultTreasureEvery
+	}
+	if c.BossEvery <= 0 {
+		c.BossEvery = defaultBossEvery
+	}
+	return c
+}
+
+// monsterScale returns how much tougher monsters are at the current depth
+func (m model) monsterScale() float64 {
+	if !m.endless {
+		return 1
+	}
+	return m.endlessConfig().MonsterCurve.At(m.level)
+}
+
+// lootScale returns how much more gold is found at the current depth
+func (m model) lootScale() float64 {
+	if !m.endless {
+		return 1
+	}
+	return m.endlessConfig().LootCurve.At(m.level)
+}
+
+// levelKind returns what kind of level the current depth is. Boss floors
+// win over treasure vaults when both fall on the same depth.
+func (m model) levelKind() levelKind {
+	if !m.endless {
+		return normalLevel
+	}
+	c := m.endlessConfig()
+	switch {
+	case m.level%c.BossEvery == 0:
+		return bossLevel
+	case m.level%c.TreasureEvery == 0:
+		return treasureLevel
+	}
+	return normalLevel
+}
+
+// deepestLevel returns the deepest level the player has reached
+func (m model) deepestLevel() int {
+	return max(m.deepest, m.level)
+}
+
+// scaleGold scales a find of gold by the current depth
+func (m model) scaleGold(amount int) int {
+	return max(int(math.Round(float64(amount)*m.lootScale())), 1)
+}
+
+// floorBoss returns the template of the boss guarding boss floors: the one
+// the dungeon names, else its first boss, else the Warden of the Deep
+func (m model) floorBoss() dungeon.MonsterTemplate {
+	if id := m.endlessConfig().Boss; id != "" {
+		if template := m.monsterTemplate(id); template != nil {
+			return *template
+		}
+	}
+	if m.def != nil {
+		for _, template := range m.def.Monsters {
+			if template.Boss {
+				return template
+			}
+		}
+	}
+	return wardenTemplate
+}
+
+// placeFloorBoss puts the boss of a boss floor next to the exit, scaled to the depth
+func (m *model) placeFloorBoss(exit Position) {
+	pos, ok := m.emptyNeighbour(exit)
+	if !ok {
+		return
+	}
+	boss := monsterFromTemplate(m.floorBoss(), pos)
+	scale := m.monsterScale()
+	boss.MaxHealth = max(int(math.Round(float64(boss.MaxHealth)*scale)), 1)
+	boss.Health = boss.MaxHealth
+	boss.Damage = max(int(math.Round(float64(boss.Damage)*scale)), 1)
+	if boss.DamageDice != "" {
+		boss.DamageDice = boss.DamageDice.Scale(scale)
+	}
+	boss.Alert = 0 // Bosses wait for the player by the exit
+	m.monsters = append(m.monsters, boss)
+	m.dungeon[pos.Y][pos.X] = Monster
+	m.addMessage(fmt.Sprintf("You sense the %s waiting by the way down...", boss.Name))
+}
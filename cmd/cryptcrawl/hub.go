@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"cryptcrawl/internal/dungeon"
+)
+
+// Buying the ware at index i of the open shop is stored as actionBuyFirst+i
+const (
+	actionBuyFirst action = '!'
+	maxWares              = 15
+)
+
+// newCampaignModel starts a campaign in a hub town, whose portals lead to
+// the loaded dungeons
+func newCampaignModel(seed int64, hub *dungeon.HubDefinition) model {
+	m := newGame(seed, nil)
+	m.hub = hub
+	m.appearances = assignAppearances(seed, m.runItems())
+	m.conquered = make(map[string]bool)
+	m.messages = []string{fmt.Sprintf("Welcome to %s! Step into a portal to enter a dungeon.", hub.Name)}
+	if hub.Description != "" {
+		m.addMessage(hub.Description)
+	}
+	m.enterHub()
+	m.viewport.SetContent(m.dungeonToString())
+	return m
+}
+
+// enterHub builds the hub town and puts the player at its start
+func (m *model) enterHub() {
+	m.inHub = true
+	m.def = nil
+	m.levelDef = nil
+	m.level = 0
+	m.levels = nil
+	m.monsters = []Entity{}
+	m.items = []ItemInstance{}
+	m.traps = []TrapInstance{}
+	m.stairs = nil
+	m.explored = nil
+
+	grid := m.hub.Grid()
+	m.dungeon = make([][]TileType, len(grid))
+	for y := range grid {
+		m.dungeon[y] = make([]TileType, len(grid[y]))
+		for x, r := range grid[y] {
+			switch r {
+			case '#':
+				m.dungeon[y][x] = Wall
+			case '~':
+				m.dungeon[y][x] = Water
+			default:
+				m.dungeon[y][x] = Empty
+			}
+		}
+	}
+	for _, portal := range m.hub.Portals {
+		m.dungeon[portal.Position.Y][portal.Position.X] = Portal
+	}
+	for _, shop := range m.hub.Shops {
+		m.dungeon[shop.Position.Y][shop.Position.X] = Shop
+	}
+	for _, inn := range m.hub.Inns {
+		m.dungeon[inn.Position.Y][inn.Position.X] = Inn
+	}
+
+	m.placePlayer(Position{X: m.hub.StartPos.X, Y: m.hub.StartPos.Y})
+	m.updateExplored()
+}
+
+// enterPortal takes the player through the portal at the given position
+func (m *model) enterPortal(x, y int) {
+	var portal *dungeon.PortalDefinition
+	for i := range m.hub.Portals {
+		if m.hub.Portals[i].Position.X == x && m.hub.Portals[i].Position.Y == y {
+			portal = &m.hub.Portals[i]
+			break
+		}
+	}
+	if portal == nil {
+		return
+	}
+	if m.conquered[portal.Dungeon] {
+		m.addMessage(fmt.Sprintf("The portal to %s has gone dark. You have already conquered it.", portal.Dungeon))
+		return
+	}
+	def, err := findDungeon(portal.Dungeon)
+	if err != nil || def == nil {
+		m.addMessage(fmt.Sprintf("The portal to %s flickers, but nothing is on the other side.", portal.Dungeon))
+		return
+	}
+
+	m.inHub = false
+	m.shop = nil
+	m.def = def
+	m.level = 1
+	m.levels = nil
+	m.flags = nil
+	m.hurtOnLevel = false
+	m.hunger = newHungerClock(def.Hunger)
+	m.appearances = assignAppearances(m.seed, m.runItems())
+	m.buildLevel()
+
+	// There is always a way back to town from the first level
+	fled := false
+	for _, stairs := range m.stairs {
+		fled = fled || stairs.Target < 1
+	}
+	if !fled {
+		m.addStairs(m.player.Pos, StairsUp, 0)
+	}
+
+	m.addMessage(fmt.Sprintf("You step through the portal into %s.", def.Name))
+	if def.Description != "" {
+		m.addMessage(def.Description)
+	}
+	m.updateExplored()
+}
+
+// leaveDungeon brings the player back to the hub town, either victorious or
+// fleeing. Conquering the last dungeon wins the campaign.
+func (m *model) leaveDungeon(conquered bool) {
+	name := m.def.Name
+	if conquered {
+		m.conquered[name] = true
+	}
+	m.enterHub()
+
+	if !conquered {
+		m.addMessage(fmt.Sprintf("You flee %s and make it back to %s.", name, m.hub.Name))
+		return
+	}
+	for _, portal := range m.hub.Portals {
+		if !m.conquered[portal.Dungeon] {
+			m.addMessage(fmt.Sprintf("You return to %s having conquered %s!", m.hub.Name, name))
+			return
+		}
+	}
+	m.gameWon = true
+	m.addMessage("You have conquered every dungeon!")
+}
+
+// shopAt returns the shop at the given position, or nil
+func (m model) shopAt(x, y int) *dungeon.ShopDefinition {
+	for i := range m.hub.Shops {
+		if m.hub.Shops[i].Position.X == x && m.hub.Shops[i].Position.Y == y {
+			return &m.hub.Shops[i]
+		}
+	}
+	return nil
+}
+
+// rest spends a night at the inn at the given position, healing the player
+// and curing what ails them
+func (m *model) rest(x, y int) {
+	for _, inn := range m.hub.Inns {
+		if inn.Position.X != x || inn.Position.Y != y {
+			continue
+		}
+		if m.gold < inn.Price {
+			m.addMessage(fmt.Sprintf("A room at %s costs %d gold. You can't afford it.", inn.Name, inn.Price))
+			return
+		}
+		m.gold -= inn.Price
+		m.player.Health = m.player.MaxHealth
+		m.status = statusEffects{}
+		if m.hunger.Enabled() {
+			m.hunger.Satiation = m.hunger.Config.MaxSatiation
+		}
+		m.addMessage(fmt.Sprintf("You rest at %s for %d gold and wake up refreshed.", inn.Name, inn.Price))
+		return
+	}
+}
+
+// buy buys the ware at the given index of the open shop
+func (m *model) buy(index int) {
+	if m.shop == nil || index < 0 || index >= len(m.shop.Wares) {
+		return
+	}
+	ware := m.shop.Wares[index]
+	template := m.hub.Item(ware.ItemID)
+	if template == nil {
+		return
+	}
+	item := ItemInstance{Template: *template, Count: 1}
+	if m.gold < ware.Price {
+		m.addMessage(fmt.Sprintf("The %s costs %d gold. You can't afford it.", m.itemName(item), ware.Price))
+		return
+	}
+	m.gold -= ware.Price
+	m.addToInventory(item)
+	m.addMessage(fmt.Sprintf("You buy the %s for %d gold.", m.itemName(item), ware.Price))
+	// The shopkeeper tells you what you bought
+	m.identify(*template)
+}
+
+// buyAction returns the action buying the ware at the given index of the open shop
+func buyAction(index int) action {
+	return actionBuyFirst + action(index)
+}
+
+// shopMenuView lists the wares of the open shop
+func (m model) shopMenuView() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "  %s. Buy what? You have %d gold. (esc to leave)\n", m.shop.Name, m.gold)
+	for i, ware := range m.shop.Wares {
+		if i >= maxWares {
+			break
+		}
+		if template := m.hub.Item(ware.ItemID); template != nil {
+			fmt.Fprintf(&b, "  %c) %-24s %d gold\n", 'a'+i, m.itemName(ItemInstance{Template: *template, Count: 1}), ware.Price)
+		}
+	}
+	return b.String()
+}
+
+// updateShopMenu handles a key press while a shop is open
+func (m *model) updateShopMenu(msg tea.KeyMsg) {
+	if msg.Type != tea.KeyRunes || len(msg.Runes) != 1 {
+		m.shop = nil
+		return
+	}
+	if i := int(msg.Runes[0] - 'a'); i >= 0 && i < min(len(m.shop.Wares), maxWares) {
+		m.act(buyAction(i))
+		return
+	}
+	m.shop = nil
+}
+
+// locationName returns what the status bar calls where the player is
+func (m model) locationName() string {
+	if m.inHub {
+		return m.hub.Name
+	}
+	return fmt.Sprintf("Level %d", m.level)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"cryptcrawl/internal/dungeon"
+)
+
+// HungerState represents how hungry the player is
+type HungerState int
+
+// Hunger states, from fed to starving
+const (
+	NotHungry HungerState = iota
+	Hungry
+	Weak
+	Fainting
+	Starving
+)
+
+// Default hunger settings used when a dungeon enables hunger without tuning it
+const (
+	defaultMaxSatiation     = 1000
+	defaultStartSatiation   = 900
+	defaultDrainPerTurn     = 1
+	defaultHungryAt         = 150
+	defaultWeakAt           = 50
+	defaultFaintingAt       = 10
+	defaultStarvationDamage = 1
+)
+
+// String returns the status label for a hunger state
+func (s HungerState) String() string {
+	switch s {
+	case Hungry:
+		return "Hungry"
+	case Weak:
+		return "Weak"
+	case Fainting:
+		return "Fainting"
+	case Starving:
+		return "Starving"
+	}
+	return ""
+}
+
+// hungerClock tracks the player's satiation
+type hungerClock struct {
+	Config    dungeon.HungerConfig
+	Satiation int
+}
+
+// newHungerClock creates a hunger clock from a dungeon's hunger settings
+func newHungerClock(cfg *dungeon.HungerConfig) hungerClock {
+	if cfg == nil || !cfg.Enabled {
+		return hungerClock{}
+	}
+
+	c := *cfg
+	if c.MaxSatiation <= 0 {
+		c.MaxSatiation = defaultMaxSatiation
+	}
+	if c.StartSatiation <= 0 {
+		c.StartSatiation = min(defaultStartSatiation, c.MaxSatiation)
+	}
+	if c.DrainPerTurn <= 0 {
+		c.DrainPerTurn = defaultDrainPerTurn
+	}
+	if c.HungryAt <= 0 {
+		c.HungryAt = defaultHungryAt
+	}
+	if c.WeakAt <= 0 {
+		c.WeakAt = defaultWeakAt
+	}
+	if c.FaintingAt <= 0 {
+		c.FaintingAt = defaultFaintingAt
+	}
+	if c.StarvationDamage <= 0 {
+		c.StarvationDamage = defaultStarvationDamage
+	}
+
+	return hungerClock{Config: c, Satiation: min(c.StartSatiation, c.MaxSatiation)}
+}
+
+// Enabled reports whether the hunger clock is running
+func (h hungerClock) Enabled() bool {
+	return h.Config.Enabled
+}
+
+// State returns the hunger state for the current satiation
+func (h hungerClock) State() HungerState {
+	switch {
+	case !h.Enabled():
+		return NotHungry
+	case h.Satiation <= 0:
+		return Starving
+	case h.Satiation <= h.Config.FaintingAt:
+		return Fainting
+	case h.Satiation <= h.Config.WeakAt:
+		return Weak
+	case h.Satiation <= h.Config.HungryAt:
+		return Hungry
+	}
+	return NotHungry
+}
+
+// hungerStyle returns the status bar style for a hunger state
+func hungerStyle(state HungerState) lipgloss.Style {
+	switch state {
+	case Hungry:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("#ffaa00"))
+	case Weak:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("#ff5500"))
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color("#ff0000")).Bold(true)
+}
+
+// tickHunger drains satiation for one turn and applies its consequences.
+// There is always food to be had in the hub town, so nobody starves there.
+func (m *model) tickHunger() {
+	if !m.hunger.Enabled() || m.inHub || m.gameOver || m.gameWon {
+		return
+	}
+
+	before := m.hunger.State()
+	m.hunger.Satiation = max(m.hunger.Satiation-m.hunger.Config.DrainPerTurn, 0)
+	after := m.hunger.State()
+
+	if after > before {
+		switch after {
+		case Hungry:
+			m.addMessage("You are getting hungry.")
+		case Weak:
+			m.addMessage("You feel weak from hunger.")
+		case Fainting:
+			m.addMessage("You are fainting from lack of food!")
+		case Starving:
+			m.addMessage("You are starving!")
+		}
+	}
+
+	switch after {
+	case Fainting:
+		// Fainting costs the player a turn now and then
+		if m.rng.Intn(4) == 0 {
+			m.addMessage("You faint from lack of food.")
+			m.moveMonsters()
+		}
+	case Starving:
+		m.player.Health -= m.hunger.Config.StarvationDamage
+		m.hurtOnLevel = true
+		if m.player.Health <= 0 {
+			m.die("starved to death")
+			m.addMessage("You starved to death!")
+		}
+	}
+}
+
+// eat consumes the first edible item in the inventory
+func (m *model) eat() {
+	for i, item := range m.inventory {
+		if item.Template.Nutrition <= 0 {
+			continue
+		}
+
+		m.removeFromInventory(i)
+		if !m.hunger.Enabled() {
+			m.addMessage(fmt.Sprintf("You eat the %s.", item.Template.Name))
+		} else {
+			m.hunger.Satiation = min(m.hunger.Satiation+item.Template.Nutrition, m.hunger.Config.MaxSatiation)
+			if m.hunger.State() == NotHungry {
+				m.addMessage(fmt.Sprintf("You eat the %s. That hit the spot.", item.Template.Name))
+			} else {
+				m.addMessage(fmt.Sprintf("You eat the %s. You are still %s.", item.Template.Name, strings.ToLower(m.hunger.State().String())))
+			}
+		}
+
+		// Eating takes a turn, and the monsters don't wait for you to finish
+		m.moveMonsters()
+		m.endTurn()
+		return
+	}
+
+	m.addMessage("You have nothing to eat.")
+}
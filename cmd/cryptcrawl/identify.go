@@ -0,0 +1,339 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"cryptcrawl/internal/dungeon"
+)
+
+// appearancePools are the disguises unidentified items of each class are drawn from
+var appearancePools = map[string][]string{
+	dungeon.ItemPotion: {
+		"murky", "bubbling", "fizzy", "cloudy", "smoky", "golden", "crimson",
+		"violet", "milky", "glowing", "oily", "effervescent", "inky", "amber",
+	},
+	dungeon.ItemScroll: {
+		"ZELGO MER", "FOOBIE BLETCH", "XIXAXA XOXAXA", "ELBIB YLOH", "VERR YED HORRE",
+		"ANDOVA BEGARIN", "KIRJE", "PRATYAVAYAH", "THARR", "YUM YUM", "VE FORBRYDERNE",
+	},
+}
+
+// appearanceName formats an appearance as an item name for its class
+func appearanceName(class, appearance string) string {
+	switch class {
+	case dungeon.ItemPotion:
+		return appearance + " potion"
+	case dungeon.ItemScroll:
+		return fmt.Sprintf("scroll labelled %s", appearance)
+	}
+	return appearance
+}
+
+// assignAppearances picks a random appearance for every unidentified item
+// template. Appearances come from their own generator seeded by the run, so
+// they don't disturb the rest of the run's randomness.
+func assignAppearances(seed int64, templates []dungeon.ItemTemplate) map[string]string {
+	if len(templates) == 0 {
+		return nil
+	}
+
+	// Shuffle the classes in a fixed order, as map order would change the rolls
+	classes := make([]string, 0, len(appearancePools))
+	for class := range appearancePools {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+
+	rng := rand.New(rand.NewSource(seed))
+	shuffled := make(map[string][]string)
+	for _, class := range classes {
+		pool := append([]string(nil), appearancePools[class]...)
+		rng.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+		shuffled[class] = pool
+	}
+
+	appearances := make(map[string]string)
+	for _, template := range templates {
+		if _, ok := appearances[template.ID]; ok || !template.Unidentified {
+			continue
+		}
+		pool := shuffled[template.Type]
+		if len(pool) == 0 {
+			// Ran out of appearances, or a class without any
+			appearances[template.ID] = "strange " + template.Type
+			continue
+		}
+		appearances[template.ID] = appearanceName(template.Type, pool[0])
+		shuffled[template.Type] = pool[1:]
+	}
+	return appearances
+}
+
+// runItems returns the item templates of the run: the hub town's wares first,
+// so they keep their appearances in every dungeon, then the dungeon's items
+func (m model) runItems() []dungeon.ItemTemplate {
+	var templates []dungeon.ItemTemplate
+	if m.hub != nil {
+		templates = append(templates, m.hub.Items...)
+	}
+	if m.def != nil {
+		templates = append(templates, m.def.Items...)
+	}
+	return templates
+}
+
+// isIdentified reports whether the player knows what an item template really is
+func (m model) isIdentified(template dungeon.ItemTemplate) bool {
+	return !template.Unidentified || m.identified[template.ID]
+}
+
+// itemName returns the name the player knows an item by, including the stack size
+func (m model) itemName(item ItemInstance) string {
+	if m.isIdentified(item.Template) {
+		return item.Name()
+	}
+	name := m.appearances[item.Template.ID]
+	if name == "" {
+		name = "unidentified " + item.Template.Type
+	}
+	if item.Count > 1 {
+		return fmt.Sprintf("%d x %s", item.Count, name)
+	}
+	return name
+}
+
+// identify reveals an item template's true name for the rest of the run
+func (m *model) identify(template dungeon.ItemTemplate) {
+	if m.isIdentified(template) {
+		return
+	}
+	appearance := m.itemName(ItemInstance{Template: template, Count: 1})
+	if m.identified == nil {
+		m.identified = make(map[string]bool)
+	}
+	m.identified[template.ID] = true
+	m.addMessage(fmt.Sprintf("The %s is %s!", appearance, withArticle(template.Name)))
+}
+
+// usable reports whether an item can be used from the inventory
+func usable(template dungeon.ItemTemplate) bool {
+	switch template.Type {
+	case dungeon.ItemPotion, dungeon.ItemScroll, "consumable":
+		return true
+	}
+	return false
+}
+
+// useAction returns the action that uses the item in an inventory slot
+func useAction(slot int) action {
+	return action(actionUseFirst + action(slot))
+}
+
+// useItem uses up one item from the inventory slot at index, applying its
+// effects. Using an item identifies it.
+func (m *model) useItem(slot int) {
+	if slot < 0 || slot >= len(m.inventory) || !usable(m.inventory[slot].Template) {
+		m.addMessage("You can't use that.")
+		return
+	}
+
+	item := m.inventory[slot]
+	name := m.itemName(ItemInstance{Template: item.Template, Count: 1})
+	m.removeFromInventory(slot)
+
+	switch item.Template.Type {
+	case dungeon.ItemPotion:
+		m.addMessage(fmt.Sprintf("You drink the %s.", name))
+	case dungeon.ItemScroll:
+		m.addMessage(fmt.Sprintf("You read the %s.", name))
+	default:
+		m.addMessage(fmt.Sprintf("You use the %s.", name))
+	}
+
+	for _, effect := range item.Template.Effects {
+		m.applyItemEffect(effect)
+	}
+	m.identify(item.Template)
+
+	// Using an item takes a turn, and the monsters don't wait for you
+	m.moveMonsters()
+	m.endTurn()
+}
+
+// applyItemEffect applies one effect of a used item to the player
+func (m *model) applyItemEffect(effect dungeon.ItemEffect) {
+	switch effect.Type {
+	case dungeon.EffectHeal:
+		amount := effect.Value.Roll(m.rng)
+		healed := min(amount, m.player.MaxHealth-m.player.Health)
+		m.player.Health += healed
+		m.addMessage(fmt.Sprintf("You feel better. +%d HP", healed))
+	case dungeon.EffectIdentify:
+		for _, item := range m.inventory {
+			if !m.isIdentified(item.Template) {
+				m.identify(item.Template)
+				return
+			}
+		}
+		m.addMessage("You feel knowledgeable, but there is nothing to identify.")
+	case dungeon.EffectMapping:
+		for y := range m.explored {
+			for x := range m.explored[y] {
+				m.explored[y][x] = true
+			}
+		}
+		m.addMessage("A map of the level forms in your mind.")
+	case dungeon.EffectPoison:
+		m.status.Poisoned = max(m.status.Poisoned, max(effect.Duration, 1))
+		m.status.PoisonDamage = max(effect.Value.Roll(m.rng), 1)
+		m.addMessage("You feel very sick.")
+	}
+}
+
+// usableSlots returns the inventory slots holding items that can be used
+func (m model) usableSlots() []int {
+	var slots []int
+	for i, item := range m.inventory {
+		if usable(item.Template) && len(slots) < maxUseSlots {
+			slots = append(slots, i)
+		}
+	}
+	return slots
+}
+
+// useMenuView lists the items the player can pick from after pressing U
+func (m model) useMenuView() string {
+	slots := m.usableSlots()
+	if len(slots) == 0 {
+		return "  You have nothing to use.\n"
+	}
+	var b strings.Builder
+	b.WriteString("  Use which item? (esc to cancel)\n")
+	for i, slot := range slots {
+		fmt.Fprintf(&b, "  %c) %s\n", 'a'+i, m.itemName(m.inventory[slot]))
+	}
+	return b.String()
+}
+
+// updateUseMenu handles a key press while the use menu is open
+func (m *model) updateUseMenu(msg tea.KeyMsg) {
+	m.choosing = false
+	if msg.Type != tea.KeyRunes || len(msg.Runes) != 1 {
+		return
+	}
+	slots := m.usableSlots()
+	if i := int(msg.Runes[0] - 'a'); i >= 0 && i < len(slots) {
+		m.act(useAction(slots[i]))
+	}
+}
+
+// discovery is an identifiable item and what the player knows about it
+type discovery struct {
+	appearance string
+	name       string // Empty while unidentified
+}
+
+// discoveries lists the run's unidentified item types, identified ones first
+func (m model) discoveries() []discovery {
+	var list []discovery
+	seen := make(map[string]bool)
+	for _, template := range m.runItems() {
+		if seen[template.ID] || !template.Unidentified {
+			continue
+		}
+		seen[template.ID] = true
+		d := discovery{appearance: m.appearances[template.ID]}
+		if m.identified[template.ID] {
+			d.name = template.Name
+		}
+		list = append(list, d)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if (list[i].name != "") != (list[j].name != "") {
+			return list[i].name != ""
+		}
+		return list[i].appearance < list[j].appearance
+	})
+	return list
+}
+
+// renderDiscoveries renders the discoveries list
+func (m model) renderDiscoveries() string {
+	known := lipgloss.NewStyle().Foreground(lipgloss.Color("#00ff00"))
+	unknown := lipgloss.NewStyle().Foreground(lipgloss.Color("#888888"))
+
+	list := m.discoveries()
+	var b strings.Builder
+	b.WriteString("\n  DISCOVERIES\n\n")
+	if len(list) == 0 {
+		b.WriteString("  There is nothing to identify in this dungeon.\n")
+	}
+	for _, d := range list {
+		if d.name != "" {
+			b.WriteString("  " + known.Render(fmt.Sprintf("%-32s %s", d.appearance, d.name)) + "\n")
+		} else {
+			b.WriteString("  " + unknown.Render(fmt.Sprintf("%-32s ?", d.appearance)) + "\n")
+		}
+	}
+	return b.String()
+}
+
+// discoveriesKeys are the discoveries list's key bindings
+var discoveriesKeys = struct {
+	Back key.Binding
+	Quit key.Binding
+}{
+	Back: key.NewBinding(key.WithKeys("esc", "\\"), key.WithHelp("esc", "back")),
+	Quit: key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "quit")),
+}
+
+// discoveriesModel shows which item appearances the player has identified
+type discoveriesModel struct {
+	parent   tea.Model
+	viewport viewport.Model
+}
+
+// newDiscoveriesModel creates the discoveries list for a game
+func newDiscoveriesModel(m model) discoveriesModel {
+	dm := discoveriesModel{parent: m, viewport: viewport.New(m.width, m.height-2)}
+	dm.viewport.SetContent(m.renderDiscoveries())
+	return dm
+}
+
+// Init initializes the discoveries list
+func (dm discoveriesModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update scrolls the list and goes back to the game
+func (dm discoveriesModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, discoveriesKeys.Quit):
+			return dm, tea.Quit
+		case key.Matches(msg, discoveriesKeys.Back):
+			return dm.parent, nil
+		}
+	case tea.WindowSizeMsg:
+		dm.viewport.Width = msg.Width
+		dm.viewport.Height = msg.Height - 2 // Leave room for the footer
+	}
+
+	var cmd tea.Cmd
+	dm.viewport, cmd = dm.viewport.Update(msg)
+	return dm, cmd
+}
+
+// View renders the discoveries list
+func (dm discoveriesModel) View() string {
+	return dm.viewport.View() + "\n" + lipgloss.NewStyle().Foreground(lipgloss.Color("#888888")).Render("↑/↓ scroll • esc back • q quit")
+}
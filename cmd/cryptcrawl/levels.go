@@ -0,0 +1,341 @@
+package main
+
+import (
+	"fmt"
+
+	"cryptcrawl/internal/dungeon"
+)
+
+// randomDungeonDepth is how many levels a dungeon has when nothing says otherwise
+const randomDungeonDepth = 3
+
+// Size of randomly generated levels, independent of the terminal so seeds
+// always generate the same levels
+const (
+	randomDungeonWidth  = 97
+	randomDungeonHeight = 30
+)
+
+// Stairs represents a staircase or exit and the depth it leads to
+type Stairs struct {
+	Pos    Position
+	Tile   TileType // Exit, StairsUp or StairsDown
+	Target int      // Depth the stairs lead to
+
+	RequiresItem string `json:",omitempty"` // Item ID the player must carry to pass
+	RequiresFlag string `json:",omitempty"` // Quest flag that must be set to pass
+	Message      string `json:",omitempty"` // Shown while the way is locked
+}
+
+// levelState is a snapshot of a level the player has left
+type levelState struct {
+	Dungeon  [][]TileType
+	Monsters []Entity
+	Items    []ItemInstance
+	Traps    []TrapInstance
+	Stairs   []Stairs
+	Explored [][]bool
+	LevelDef *dungeon.LevelDefinition `json:",omitempty"` // Set for generated definition levels
+}
+
+// maxDepth returns the depth of the deepest level in the current dungeon
+func (m model) maxDepth() int {
+	if m.def != nil {
+		return max(len(m.def.Levels), randomDungeonDepth)
+	}
+	return randomDungeonDepth
+}
+
+// currentLevelDef returns the definition the current level was built from,
+// or nil for random levels
+func (m model) currentLevelDef() *dungeon.LevelDefinition {
+	if m.levelDef != nil {
+		return m.levelDef
+	}
+	if m.def == nil || m.level < 1 || m.level > len(m.def.Levels) {
+		return nil
+	}
+	return &m.def.Levels[m.level-1]
+}
+
+// stairsAt returns the index of the stairs at the given position, or -1
+func (m model) stairsAt(x, y int) int {
+	for i, stairs := range m.stairs {
+		if stairs.Pos.X == x && stairs.Pos.Y == y {
+			return i
+		}
+	}
+	return -1
+}
+
+// addStairs places a staircase leading to the target depth
+func (m *model) addStairs(pos Position, tile TileType, target int) {
+	if !m.inBounds(pos.X, pos.Y) {
+		return
+	}
+	if i := m.stairsAt(pos.X, pos.Y); i >= 0 {
+		m.stairs[i] = Stairs{Pos: pos, Tile: tile, Target: target}
+	} else {
+		m.stairs = append(m.stairs, Stairs{Pos: pos, Tile: tile, Target: target})
+	}
+	if m.dungeon[pos.Y][pos.X] != Player {
+		m.dungeon[pos.Y][pos.X] = tile
+	}
+}
+
+// linkDefinitionStairs registers the exit and staircases of a definition level
+func (m *model) linkDefinitionStairs(levelDef dungeon.LevelDefinition) {
+	depth := m.level
+	m.addStairs(Position{X: levelDef.ExitPos.X, Y: levelDef.ExitPos.Y}, Exit, depth+1)
+
+	// Unlinked staircases lead to the neighbouring levels
+	for y := range m.dungeon {
+		for x, tile := range m.dungeon[y] {
+			switch tile {
+			case StairsUp:
+				m.addStairs(Position{X: x, Y: y}, StairsUp, depth-1)
+			case StairsDown:
+				m.addStairs(Position{X: x, Y: y}, StairsDown, depth+1)
+			}
+		}
+	}
+
+	for _, link := range levelDef.Stairs {
+		tile := StairsDown
+		if link.Direction == dungeon.StairsUp {
+			tile = StairsUp
+		}
+		target := m.def.LevelIndex(link.TargetLevel) + 1
+		if target <= 0 {
+			continue
+		}
+		m.addStairs(Position{X: link.Position.X, Y: link.Position.Y}, tile, target)
+	}
+
+	// Extra exits lead to levels by ID or out of the dungeon, and may be locked
+	for _, exit := range levelDef.Exits {
+		target := m.maxDepth() + 1
+		if exit.TargetLevel != dungeon.ExitOut {
+			target = m.def.LevelIndex(exit.TargetLevel) + 1
+		}
+		if target <= 0 {
+			continue
+		}
+		pos := Position{X: exit.Position.X, Y: exit.Position.Y}
+		m.addStairs(pos, Exit, target)
+		if i := m.stairsAt(pos.X, pos.Y); i >= 0 {
+			m.stairs[i].RequiresItem = exit.RequiresItem
+			m.stairs[i].RequiresFlag = exit.RequiresFlag
+			m.stairs[i].Message = exit.Message
+		}
+	}
+}
+
+// unlocked reports whether the player can take the stairs, telling them
+// what they are missing if not
+func (m *model) unlocked(stairs Stairs) bool {
+	locked := false
+	if stairs.RequiresItem != "" {
+		locked = true
+		for _, item := range m.inventory {
+			if item.Template.ID == stairs.RequiresItem {
+				locked = false
+				break
+			}
+		}
+	}
+	if stairs.RequiresFlag != "" && !m.flags[stairs.RequiresFlag] {
+		locked = true
+	}
+	if !locked {
+		return true
+	}
+
+	switch {
+	case stairs.Message != "":
+		m.addMessage(stairs.Message)
+	case stairs.RequiresItem != "" && m.itemTemplate(stairs.RequiresItem) != nil:
+		m.addMessage(fmt.Sprintf("The way is locked. You need %s.", withArticle(m.itemTemplate(stairs.RequiresItem).Name)))
+	default:
+		m.addMessage("The way is locked.")
+	}
+	return false
+}
+
+// useStairs follows the stairs at the given position
+func (m *model) useStairs(x, y int) {
+	i := m.stairsAt(x, y)
+	if i < 0 {
+		return
+	}
+
+	stairs := m.stairs[i]
+	if !m.unlocked(stairs) {
+		return
+	}
+	switch {
+	case stairs.Target < 1 && m.hub != nil:
+		m.leaveDungeon(false)
+	case stairs.Target < 1:
+		m.addMessage("These stairs lead out of the dungeon, but you have unfinished business here.")
+	case stairs.Target > m.maxDepth() && !m.endless:
+		m.escape()
+	case stairs.Target < m.level:
+		m.travel(stairs.Target)
+		m.addMessage(fmt.Sprintf("You climb up to level %d...", m.level))
+	default:
+		m.travel(stairs.Target)
+		m.addMessage(fmt.Sprintf("You descend to level %d...", m.level))
+	}
+}
+
+// descend takes the player to the next level, or out of the dungeon from the
+// last one. Endless descents have no last level.
+func (m *model) descend() {
+	if m.level < m.maxDepth() || m.endless {
+		m.travel(m.level + 1)
+		m.addMessage(fmt.Sprintf("You descend to level %d...", m.level))
+	} else {
+		m.escape()
+	}
+}
+
+// escape takes the player out of the bottom of the dungeon, winning the run
+// or going back to the hub town of a campaign
+func (m *model) escape() {
+	m.clearLevel()
+	if m.hub != nil {
+		m.leaveDungeon(true)
+		return
+	}
+	m.gameWon = true
+	m.addMessage("You escaped the dungeon!")
+}
+
+// clearLevel records that the player has made it past the current level
+func (m *model) clearLevel() {
+	if !m.hurtOnLevel {
+		m.flawlessLevels++
+	}
+}
+
+// travel leaves the current level and arrives at the given depth, restoring
+// it exactly as it was left if the player has been there before
+func (m *model) travel(depth int) {
+	from := m.level
+	if depth > from {
+		m.clearLevel()
+	}
+	m.hurtOnLevel = false
+	m.saveLevel()
+	m.deepest = max(m.deepestLevel(), depth)
+	m.level = depth
+
+	restored := false
+	if state, ok := m.levels[depth]; ok {
+		m.restoreLevel(state)
+		restored = true
+	} else {
+		m.buildLevel()
+	}
+
+	// Arrive on the stairs leading back where the player came from
+	arrival, found := Position{}, false
+	for _, stairs := range m.stairs {
+		if stairs.Target == from {
+			arrival, found = stairs.Pos, true
+			break
+		}
+	}
+	if !found && restored {
+		arrival, found = m.randomEmptyPosition()
+	}
+	if found {
+		if !restored {
+			m.vacate(m.player.Pos.X, m.player.Pos.Y)
+		}
+		m.player.Pos = arrival
+		m.dungeon[arrival.Y][arrival.X] = Player
+	}
+
+	m.updateExplored()
+}
+
+// placePlayer puts the player at a position, creating a fresh character on first use
+func (m *model) placePlayer(pos Position) {
+	if m.player.MaxHealth == 0 {
+		m.player = Entity{
+			Symbol:    TilePlayer,
+			Health:    10,
+			MaxHealth: 10,
+			Damage:    2,
+			Accuracy:  playerAccuracy,
+			Name:      "Player",
+		}
+	}
+	m.player.Pos = pos
+	m.dungeon[pos.Y][pos.X] = Player
+}
+
+// buildLevel creates the level at the current depth for the first time
+func (m *model) buildLevel() {
+	m.levelRNG = levelRNG(m.seed, m.level)
+	m.stairs = nil
+	m.explored = nil
+	m.levelDef = nil
+	if m.def == nil || m.level > len(m.def.Levels) || !m.loadDefinitionLevel() {
+		m.generateDungeon()
+	}
+}
+
+// saveLevel caches the current level so it can be restored later
+func (m *model) saveLevel() {
+	if m.levels == nil {
+		m.levels = make(map[int]*levelState)
+	}
+
+	// Take the player out of the snapshot
+	m.vacate(m.player.Pos.X, m.player.Pos.Y)
+	m.levels[m.level] = &levelState{
+		Dungeon:  m.dungeon,
+		Monsters: m.monsters,
+		Items:    m.items,
+		Traps:    m.traps,
+		Stairs:   m.stairs,
+		Explored: m.explored,
+		LevelDef: m.levelDef,
+	}
+}
+
+// restoreLevel brings back a cached level
+func (m *model) restoreLevel(state *levelState) {
+	m.dungeon = state.Dungeon
+	m.monsters = state.Monsters
+	m.items = state.Items
+	m.traps = state.Traps
+	m.stairs = state.Stairs
+	m.explored = state.Explored
+	m.levelDef = state.LevelDef
+}
+
+// updateExplored remembers every tile the player can currently see
+func (m *model) updateExplored() {
+	if len(m.explored) != len(m.dungeon) {
+		m.explored = make([][]bool, len(m.dungeon))
+		for y := range m.dungeon {
+			m.explored[y] = make([]bool, len(m.dungeon[y]))
+		}
+	}
+	for y := range m.dungeon {
+		for x := range m.dungeon[y] {
+			if m.isVisible(x, y) {
+				m.explored[y][x] = true
+			}
+		}
+	}
+}
+
+// isExplored reports whether the player has seen a tile before
+func (m model) isExplored(x, y int) bool {
+	return y < len(m.explored) && x < len(m.explored[y]) && m.explored[y][x]
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Morgue file settings
+const (
+	morgueMapRadiusX = 20 // Columns shown on each side of the player
+	morgueMapRadiusY = 8  // Rows shown above and below the player
+	morgueMessages   = 15 // Messages included from the end of the log
+	morgueViewLimit  = 20 // Morgue files shown in the viewer, newest first
+)
+
+// Global morgue file store
+var morgues *morgueStore
+
+// withArticle puts "a" or "an" in front of a noun
+func withArticle(noun string) string {
+	if noun != "" && strings.ContainsRune("aeiouAEIOU", rune(noun[0])) {
+		return "an " + noun
+	}
+	return "a " + noun
+}
+
+// plainSymbolAt returns the unstyled symbol the player remembers at a position
+func (m model) plainSymbolAt(x, y int) rune {
+	visible := m.isVisible(x, y)
+	if !visible && !m.isExplored(x, y) {
+		return ' '
+	}
+
+	tile := m.dungeon[y][x]
+	switch tile {
+	case Monster:
+		if !visible {
+			return ' '
+		}
+		for _, monster := range m.monsters {
+			if monster.Pos.X == x && monster.Pos.Y == y && monster.Symbol != 0 {
+				return monster.Symbol
+			}
+		}
+	case Item:
+		for _, item := range m.items {
+			if item.Pos.X == x && item.Pos.Y == y && item.Template.Symbol != "" {
+				return []rune(item.Template.Symbol)[0]
+			}
+		}
+	case Trap:
+		if i := m.trapAt(x, y); i >= 0 && m.traps[i].Template.Symbol != "" {
+			return []rune(m.traps[i].Template.Symbol)[0]
+		}
+	case Station:
+		if station := m.stationAt(x, y); station != nil && station.Symbol != "" {
+			return []rune(station.Symbol)[0]
+		}
+	}
+
+	if disguise, ok := DisguisedTiles[tile]; ok {
+		tile = disguise
+	}
+	return TileMap[tile].Symbol
+}
+
+// morgue writes a plain-text account of the finished run
+func (m model) morgue() string {
+	var b strings.Builder
+
+	b.WriteString("CryptCrawl morgue file\n")
+	b.WriteString("======================\n\n")
+
+	player := m.playerName
+	if player == "" {
+		player = "Anonymous"
+	}
+	dungeonName := randomDungeonName
+	switch {
+	case m.dailyDate != "":
+		dungeonName = "Daily challenge " + m.dailyDate
+	case m.hub != nil:
+		dungeonName = m.hub.Name
+	case m.def != nil:
+		dungeonName = m.def.Name
+	}
+
+	fmt.Fprintf(&b, "Player:  %s\n", player)
+	fmt.Fprintf(&b, "Dungeon: %s\n", dungeonName)
+	fmt.Fprintf(&b, "Seed:    %d\n", m.seed)
+	fmt.Fprintf(&b, "Date:    %s\n", time.Now().UTC().Format("2006-01-02 15:04 MST"))
+	if m.replayID != "" {
+		fmt.Fprintf(&b, "Replay:  %s\n", m.replayID)
+	}
+	b.WriteString("\n")
+
+	switch {
+	case m.gameWon && m.hub != nil:
+		fmt.Fprintf(&b, "Conquered every dungeon with %d gold after %d turns.\n\n", m.gold, m.turns)
+	case m.gameWon:
+		fmt.Fprintf(&b, "Escaped the dungeon with %d gold after %d turns.\n\n", m.gold, m.turns)
+	case m.gameOver:
+		cause := m.deathCause
+		if cause == "" {
+			cause = "died"
+		}
+		fmt.Fprintf(&b, "%s%s on level %d after %d turns.\n\n", strings.ToUpper(cause[:1]), cause[1:], m.level, m.turns)
+	default:
+		fmt.Fprintf(&b, "Still exploring level %d after %d turns.\n\n", m.level, m.turns)
+	}
+
+	b.WriteString("Stats\n-----\n")
+	fmt.Fprintf(&b, "  Health:   %d/%d\n", max(m.player.Health, 0), m.player.MaxHealth)
+	damage := fmt.Sprintf("%d", m.player.Damage)
+	if m.player.DamageDice != "" {
+		damage = string(m.player.DamageDice)
+	}
+	fmt.Fprintf(&b, "  Damage:   %s\n", damage)
+	fmt.Fprintf(&b, "  Accuracy: %d  Evasion: %d\n", m.player.Accuracy, m.player.Evasion)
+	fmt.Fprintf(&b, "  Gold:     %d\n", m.gold)
+	if m.endless {
+		fmt.Fprintf(&b, "  Depth:    %d, deepest %d (endless)\n", m.level, m.deepestLevel())
+	} else {
+		fmt.Fprintf(&b, "  Depth:    %d of %d\n", m.level, m.maxDepth())
+	}
+	fmt.Fprintf(&b, "  Turns:    %d\n", m.turns)
+	if m.hub != nil {
+		fmt.Fprintf(&b, "  Dungeons: %d of %d conquered\n", len(m.conquered), len(m.hub.Portals))
+	}
+	if m.hunger.Enabled() {
+		state := m.hunger.State().String()
+		if state == "" {
+			state = "Not hungry"
+		}
+		fmt.Fprintf(&b, "  Hunger:   %s (%d/%d)\n", state, m.hunger.Satiation, m.hunger.Config.MaxSatiation)
+	}
+	b.WriteString("\n")
+
+	b.WriteString("Inventory\n---------\n")
+	if len(m.inventory) == 0 {
+		b.WriteString("  (empty)\n")
+	}
+	for _, item := range m.inventory {
+		fmt.Fprintf(&b, "  %s\n", item.Name())
+	}
+	b.WriteString("\n")
+
+	total := 0
+	names := make([]string, 0, len(m.kills))
+	for name, count := range m.kills {
+		names = append(names, name)
+		total += count
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if m.kills[names[i]] != m.kills[names[j]] {
+			return m.kills[names[i]] > m.kills[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	fmt.Fprintf(&b, "Kills (%d)\n---------\n", total)
+	if total == 0 {
+		b.WriteString("  (none)\n")
+	}
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %3d %s\n", m.kills[name], name)
+	}
+	b.WriteString("\n")
+
+	b.WriteString("Map\n---\n")
+	for y := m.player.Pos.Y - morgueMapRadiusY; y <= m.player.Pos.Y+morgueMapRadiusY; y++ {
+		if y < 0 || y >= len(m.dungeon) {
+			continue
+		}
+		var row strings.Builder
+		for x := m.player.Pos.X - morgueMapRadiusX; x <= m.player.Pos.X+morgueMapRadiusX; x++ {
+			if x < 0 || x >= len(m.dungeon[y]) {
+				continue
+			}
+			row.WriteRune(m.plainSymbolAt(x, y))
+		}
+		fmt.Fprintf(&b, "  %s\n", strings.TrimRight(row.String(), " "))
+	}
+	b.WriteString("\n")
+
+	b.WriteString("Last messages\n-------------\n")
+	start := max(len(m.messages)-morgueMessages, 0)
+	for _, msg := range m.messages[start:] {
+		fmt.Fprintf(&b, "  %s\n", msg)
+	}
+
+	return b.String()
+}
+
+// writeMorgue stores the morgue file of a finished run
+func (m *model) writeMorgue() {
+	id, ok := saveID(m.playerKey)
+	if morgues == nil || !ok {
+		return
+	}
+	name, err := morgues.Save(id, m.morgue())
+	if err != nil {
+		m.addMessage(fmt.Sprintf("Could not write the morgue file: %v", err))
+		return
+	}
+	m.morgueName = name
+}
+
+// morgueStore keeps morgue files on disk, in one directory per player
+type morgueStore struct {
+	dir string
+}
+
+// newMorgueStore creates a morgue file store in the given directory
+func newMorgueStore(dir string) (*morgueStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create morgue directory: %w", err)
+	}
+	return &morgueStore{dir: dir}, nil
+}
+
+// Save writes a morgue file for a player and returns its name
+func (s *morgueStore) Save(playerID, text string) (string, error) {
+	dir := filepath.Join(s.dir, playerID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create morgue directory: %w", err)
+	}
+
+	name := time.Now().UTC().Format("20060102-150405.000000") + ".txt"
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0644); err != nil {
+		return "", fmt.Errorf("failed to write morgue file: %w", err)
+	}
+	return name, nil
+}
+
+// List returns the names of a player's morgue files, newest first
+func (s *morgueStore) List(playerID string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, playerID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list morgue files: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".txt") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+	return names, nil
+}
+
+// Load reads one of a player's morgue files
+func (s *morgueStore) Load(playerID, name string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, playerID, filepath.Base(name)))
+	if err != nil {
+		return "", fmt.Errorf("failed to read morgue file: %w", err)
+	}
+	return string(data), nil
+}
+
+// morguePage is one morgue file shown in the viewer
+type morguePage struct {
+	name string
+	text string
+}
+
+// morgueKeys are the morgue viewer's key bindings
+var morgueKeys = struct {
+	Older key.Binding
+	Newer key.Binding
+	Back  key.Binding
+	Quit  key.Binding
+}{
+	Older: key.NewBinding(key.WithKeys("right", "l", "n"), key.WithHelp("→", "older")),
+	Newer: key.NewBinding(key.WithKeys("left", "h", "p"), key.WithHelp("←", "newer")),
+	Back:  key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "back")),
+	Quit:  key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "quit")),
+}
+
+// morgueModel shows a player's morgue files
+type morgueModel struct {
+	parent   tea.Model // Screen to go back to, if any
+	pages    []morguePage
+	index    int
+	viewport viewport.Model
+}
+
+// newMorgueModel loads a player's most recent morgue files into a viewer
+func newMorgueModel(key string) morgueModel {
+	var pages []morguePage
+	if id, ok := saveID(key); ok && morgues != nil {
+		names, err := morgues.List(id)
+		if err != nil {
+			pages = append(pages, morguePage{text: err.Error()})
+		}
+		for i, file := range names {
+			if i >= morgueViewLimit {
+				break
+			}
+			text, err := morgues.Load(id, file)
+			if err != nil {
+				text = err.Error()
+			}
+			pages = append(pages, morguePage{name: file, text: text})
+		}
+	}
+	return newMorgueViewer(pages)
+}
+
+// newMorgueViewer creates a viewer for the given morgue pages
+func newMorgueViewer(pages []morguePage) morgueModel {
+	mm := morgueModel{pages: pages, viewport: viewport.New(80, 24)}
+	mm.show(0)
+	return mm
+}
+
+// show switches to the page at index i
+func (mm *morgueModel) show(i int) {
+	if len(mm.pages) == 0 {
+		mm.viewport.SetContent("No morgue files yet. One is written every time a run ends.")
+		return
+	}
+	mm.index = max(0, min(i, len(mm.pages)-1))
+	mm.viewport.SetContent(mm.pages[mm.index].text)
+	mm.viewport.GotoTop()
+}
+
+// Init initializes the morgue viewer
+func (mm morgueModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles paging and scrolling
+func (mm morgueModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, morgueKeys.Quit):
+			return mm, tea.Quit
+		case key.Matches(msg, morgueKeys.Back) && mm.parent != nil:
+			return mm.parent, nil
+		case key.Matches(msg, morgueKeys.Older):
+			mm.show(mm.index + 1)
+			return mm, nil
+		case key.Matches(msg, morgueKeys.Newer):
+			mm.show(mm.index - 1)
+			return mm, nil
+		}
+	case tea.WindowSizeMsg:
+		mm.viewport.Width = msg.Width
+		mm.viewport.Height = msg.Height - 2 // Leave room for the footer
+	}
+
+	var cmd tea.Cmd
+	mm.viewport, cmd = mm.viewport.Update(msg)
+	return mm, cmd
+}
+
+// View renders the current morgue file
+func (mm morgueModel) View() string {
+	footer := "No morgue files"
+	if len(mm.pages) > 0 {
+		footer = fmt.Sprintf("Morgue %d/%d %s", mm.index+1, len(mm.pages), strings.TrimSuffix(mm.pages[mm.index].name, ".txt"))
+	}
+	footer += " | ↑/↓ scroll • ← newer • → older"
+	if mm.parent != nil {
+		footer += " • esc back"
+	}
+	footer += " • q quit"
+	return mm.viewport.View() + "\n" + lipgloss.NewStyle().Foreground(lipgloss.Color("#888888")).Render(footer)
+}
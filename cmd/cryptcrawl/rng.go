@@ -0,0 +1,52 @@
+package main
+
+import "math/rand"
+
+// countingSource is a seeded random source that counts how many values it has
+// produced, so its exact state can be saved and restored by replaying the draws
+type countingSource struct {
+	src   rand.Source64
+	seed  int64
+	draws uint64
+}
+
+// newRNG creates a random generator for a seed together with its counting source
+func newRNG(seed int64) (*rand.Rand, *countingSource) {
+	src := &countingSource{src: rand.NewSource(seed).(rand.Source64), seed: seed}
+	return rand.New(src), src
+}
+
+// levelRNG returns the random generator a level is built with. Every level
+// has its own, seeded by the run and the depth, so the levels of a seed are
+// the same however the run is played.
+func levelRNG(seed int64, depth int) *rand.Rand {
+	return rand.New(rand.NewSource(seed*1000003 + int64(depth)))
+}
+
+// restoreRNG recreates a random generator that has already produced the given number of draws
+func restoreRNG(seed int64, draws uint64) (*rand.Rand, *countingSource) {
+	rng, src := newRNG(seed)
+	for src.draws < draws {
+		src.Uint64()
+	}
+	return rng, src
+}
+
+// Int63 returns a non-negative pseudo-random 63-bit integer
+func (s *countingSource) Int63() int64 {
+	s.draws++
+	return s.src.Int63()
+}
+
+// Uint64 returns a pseudo-random 64-bit integer
+func (s *countingSource) Uint64() uint64 {
+	s.draws++
+	return s.src.Uint64()
+}
+
+// Seed reseeds the source and resets the draw count
+func (s *countingSource) Seed(seed int64) {
+	s.src.Seed(seed)
+	s.seed = seed
+	s.draws = 0
+}
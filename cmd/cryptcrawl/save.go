@@ -0,0 +1,344 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"cryptcrawl/internal/dungeon"
+)
+
+// saveVersion is the version of the save file format written by this build
+const saveVersion = 1
+
+// errNoSave is returned when a player has no saved game
+var errNoSave = errors.New("no saved game")
+
+// Global saved game store
+var saves *saveStore
+
+// saveFile is the on-disk format of a saved game
+type saveFile struct {
+	Version   int                 `json:"version"`
+	SavedAt   time.Time           `json:"savedAt"`
+	Dungeon   string              `json:"dungeon,omitempty"` // Dungeon definition name, empty for random dungeons
+	Seed      int64               `json:"seed"`
+	Draws     uint64              `json:"draws"` // Values drawn from the RNG so far
+	Level     int                 `json:"level"`
+	Map       [][]TileType        `json:"map"`
+	Player    Entity              `json:"player"`
+	Monsters  []Entity            `json:"monsters"`
+	Items     []ItemInstance      `json:"items"`
+	Traps     []TrapInstance      `json:"traps"`
+	Stairs    []Stairs            `json:"stairs"`
+	Explored  [][]bool            `json:"explored"`
+	Levels    map[int]*levelState `json:"levels"`
+	Inventory []ItemInstance      `json:"inventory"`
+	Turns     int                 `json:"turns"`
+	Gold      int                 `json:"gold"`
+	Hunger    hungerClock         `json:"hunger"`
+	Status    statusEffects       `json:"status"`
+	Messages  []string            `json:"messages"`
+	Actions   string              `json:"actions"` // Actions taken so far, for the replay
+	Kills     map[string]int      `json:"kills,omitempty"`
+
+	FlawlessLevels int  `json:"flawlessLevels,omitempty"`
+	HurtOnLevel    bool `json:"hurtOnLevel,omitempty"`
+	BossKills      int  `json:"bossKills,omitempty"`
+	Endless        bool `json:"endless,omitempty"`
+	Deepest        int  `json:"deepest,omitempty"`
+
+	Identified map[string]bool `json:"identified,omitempty"`
+	Flags      map[string]bool `json:"flags,omitempty"`
+
+	Campaign  bool            `json:"campaign,omitempty"` // Whether the game is a campaign from the hub town
+	InHub     bool            `json:"inHub,omitempty"`
+	Conquered map[string]bool `json:"conquered,omitempty"`
+
+	LevelDef *dungeon.LevelDefinition `json:"levelDef,omitempty"` // Set for generated definition levels
+}
+
+// snapshot captures everything needed to resume the game
+func (m model) snapshot() saveFile {
+	save := saveFile{
+		Version:   saveVersion,
+		SavedAt:   time.Now().UTC(),
+		Seed:      m.seed,
+		Level:     m.level,
+		Map:       m.dungeon,
+		Player:    m.player,
+		Monsters:  m.monsters,
+		Items:     m.items,
+		Traps:     m.traps,
+		Stairs:    m.stairs,
+		Explored:  m.explored,
+		Levels:    m.levels,
+		Inventory: m.inventory,
+		Turns:     m.turns,
+		Gold:      m.gold,
+		Hunger:    m.hunger,
+		Status:    m.status,
+		Messages:  m.messages,
+		Actions:   string(m.actions),
+		Kills:     m.kills,
+
+		FlawlessLevels: m.flawlessLevels,
+		HurtOnLevel:    m.hurtOnLevel,
+		BossKills:      m.bossKills,
+		Endless:        m.endless,
+		Deepest:        m.deepest,
+
+		Identified: m.identified,
+		Flags:      m.flags,
+
+		Campaign:  m.hub != nil,
+		InHub:     m.inHub,
+		Conquered: m.conquered,
+
+		LevelDef: m.levelDef,
+	}
+	if m.def != nil {
+		save.Dungeon = m.def.Name
+	}
+	if m.rngSource != nil {
+		save.Draws = m.rngSource.draws
+	}
+	return save
+}
+
+// restoreGame rebuilds a game from a saved snapshot
+func restoreGame(save saveFile) (model, error) {
+	if save.Version > saveVersion {
+		return model{}, fmt.Errorf("save file version %d is newer than supported version %d", save.Version, saveVersion)
+	}
+
+	def, err := findDungeon(save.Dungeon)
+	if err != nil {
+		return model{}, err
+	}
+	if len(save.Map) == 0 {
+		return model{}, fmt.Errorf("save file has no map")
+	}
+
+	m := newGame(save.Seed, def)
+	m.level = save.Level
+	m.dungeon = save.Map
+	m.player = save.Player
+	m.monsters = save.Monsters
+	m.items = save.Items
+	m.traps = save.Traps
+	m.stairs = save.Stairs
+	m.explored = save.Explored
+	m.levels = save.Levels
+	m.inventory = save.Inventory
+	m.turns = save.Turns
+	m.gold = save.Gold
+	m.hunger = save.Hunger
+	m.status = save.Status
+	m.messages = save.Messages
+	m.actions = []action(save.Actions)
+	m.kills = save.Kills
+	m.flawlessLevels = save.FlawlessLevels
+	m.hurtOnLevel = save.HurtOnLevel
+	m.bossKills = save.BossKills
+	m.endless = save.Endless
+	m.deepest = save.Deepest
+	m.identified = save.Identified
+	m.flags = save.Flags
+	m.levelDef = save.LevelDef
+	if save.Campaign {
+		if m.hub, err = findHub(); err != nil {
+			return model{}, err
+		}
+		m.inHub = save.InHub
+		m.conquered = save.Conquered
+		if m.conquered == nil {
+			m.conquered = make(map[string]bool)
+		}
+		m.appearances = assignAppearances(m.seed, m.runItems())
+	}
+	m.rng, m.rngSource = restoreRNG(save.Seed, save.Draws)
+
+	m.updateExplored()
+	m.viewport.SetContent(m.dungeonToString())
+	return m, nil
+}
+
+// findDungeon looks up a loaded dungeon definition by name; an empty name
+// means a random dungeon
+func findDungeon(name string) (*dungeon.DungeonDefinition, error) {
+	if name == "" {
+		return nil, nil
+	}
+	if dungeonLoader != nil {
+		for _, def := range dungeonLoader.Dungeons {
+			if def.Name == name {
+				return def, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("dungeon %q is no longer available", name)
+}
+
+// findHub returns the loaded hub town for a campaign
+func findHub() (*dungeon.HubDefinition, error) {
+	if dungeonLoader == nil || dungeonLoader.Hub == nil {
+		return nil, fmt.Errorf("the hub town is no longer available")
+	}
+	return dungeonLoader.Hub, nil
+}
+
+// saveID returns the file name used for a player's saved data. Only players
+// who logged in with a public key have one, since anyone can claim a user name.
+func saveID(key string) (string, bool) {
+	if key == "" {
+		return "", false
+	}
+	sum := sha256.Sum256([]byte("key:" + key))
+	return hex.EncodeToString(sum[:16]), true
+}
+
+// saveStore keeps saved games on disk, one file per player
+type saveStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// newSaveStore creates a saved game store in the given directory
+func newSaveStore(dir string) (*saveStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create saves directory: %w", err)
+	}
+	return &saveStore{dir: dir}, nil
+}
+
+// path returns the file holding a player's saved game
+func (s *saveStore) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// Save writes a player's saved game
+func (s *saveStore) Save(id string, save saveFile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.Marshal(save)
+	if err != nil {
+		return fmt.Errorf("failed to encode saved game: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a half-written save
+	tmp := s.path(id) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write saved game: %w", err)
+	}
+	return os.Rename(tmp, s.path(id))
+}
+
+// Load reads a player's saved game
+func (s *saveStore) Load(id string) (saveFile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var save saveFile
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return save, errNoSave
+	}
+	if err != nil {
+		return save, fmt.Errorf("failed to read saved game: %w", err)
+	}
+	if err := json.Unmarshal(data, &save); err != nil {
+		return save, fmt.Errorf("failed to parse saved game: %w", err)
+	}
+	return save, nil
+}
+
+// Exists reports whether a player has a saved game
+func (s *saveStore) Exists(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := os.Stat(s.path(id))
+	return err == nil
+}
+
+// Delete removes a player's saved game
+func (s *saveStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(s.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete saved game: %w", err)
+	}
+	return nil
+}
+
+// saveGame saves the game so the player can resume it, or removes the save
+// once the run is over. Daily challenges and players without a public key
+// are never saved.
+func (m model) saveGame() error {
+	if saves == nil || m.dailyDate != "" {
+		return nil
+	}
+	id, ok := saveID(m.playerKey)
+	if !ok {
+		return nil
+	}
+	if m.gameOver || m.gameWon {
+		return saves.Delete(id)
+	}
+	return saves.Save(id, m.snapshot())
+}
+
+// resumeGame loads a player's saved game
+func resumeGame(key, name string) (model, error) {
+	id, ok := saveID(key)
+	if saves == nil || !ok {
+		return model{}, errNoSave
+	}
+	save, err := saves.Load(id)
+	if err != nil {
+		return model{}, err
+	}
+	m, err := restoreGame(save)
+	if err != nil {
+		return model{}, err
+	}
+	m.playerKey = key
+	m.playerName = name
+	m.addMessage("Welcome back! Your game has been restored.")
+	return m, nil
+}
+
+// sessionGame holds the latest state of a session's game, so it can be saved
+// however the session ends
+type sessionGame struct {
+	mu      sync.Mutex
+	game    model
+	started bool // Whether a game has been started, e.g. not just the title menu
+}
+
+// set records the latest state of the game
+func (g *sessionGame) set(m model) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.game = m
+	g.started = true
+}
+
+// save saves the latest state of the game
+func (g *sessionGame) save() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.started {
+		return nil
+	}
+	return g.game.saveGame()
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// titleOption is an entry of the title menu
+type titleOption int
+
+// Title menu entries
+const (
+	titleContinue titleOption = iota
+	titleNewGame
+	titleEndless
+	titleDaily
+	titleLeaderboard
+	titleAchievements
+	titleMorgue
+	titleQuit
+)
+
+// String returns the label shown in the title menu
+func (o titleOption) String() string {
+	switch o {
+	case titleContinue:
+		return "Continue"
+	case titleNewGame:
+		return "New game"
+	case titleEndless:
+		return "Endless descent"
+	case titleDaily:
+		return "Daily challenge"
+	case titleLeaderboard:
+		return "Leaderboard"
+	case titleAchievements:
+		return "Achievements"
+	case titleMorgue:
+		return "Morgue files"
+	case titleQuit:
+		return "Quit"
+	}
+	return ""
+}
+
+// titleKeys are the title menu's key bindings
+var titleKeys = struct {
+	Up     key.Binding
+	Down   key.Binding
+	Select key.Binding
+	Quit   key.Binding
+}{
+	Up:     key.NewBinding(key.WithKeys("up", "w", "k"), key.WithHelp("↑", "up")),
+	Down:   key.NewBinding(key.WithKeys("down", "s", "j"), key.WithHelp("↓", "down")),
+	Select: key.NewBinding(key.WithKeys("enter", "space"), key.WithHelp("enter", "select")),
+	Quit:   key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "quit")),
+}
+
+// titleModel is the menu shown when a player connects
+type titleModel struct {
+	key     string
+	name    string
+	session *sessionGame
+	options []titleOption
+	cursor  int
+	err     string
+	size    tea.WindowSizeMsg // Last window size, passed on to the game
+}
+
+// newTitleModel creates the title menu for a player
+func newTitleModel(key, name string, session *sessionGame) titleModel {
+	t := titleModel{key: key, name: name, session: session}
+	if id, ok := saveID(key); ok && saves != nil && saves.Exists(id) {
+		t.options = append(t.options, titleContinue)
+	}
+	t.options = append(t.options, titleNewGame, titleEndless)
+	if key != "" && dailyBoard != nil {
+		t.options = append(t.options, titleDaily)
+	}
+	t.options = append(t.options, titleLeaderboard, titleAchievements, titleMorgue, titleQuit)
+	return t
+}
+
+// Init initializes the title menu
+func (t titleModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update moves the cursor and starts the selected entry
+func (t titleModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, titleKeys.Quit):
+			return t, tea.Quit
+		case key.Matches(msg, titleKeys.Up):
+			t.cursor = (t.cursor + len(t.options) - 1) % len(t.options)
+		case key.Matches(msg, titleKeys.Down):
+			t.cursor = (t.cursor + 1) % len(t.options)
+		case key.Matches(msg, titleKeys.Select):
+			return t.choose(t.options[t.cursor])
+		}
+	case tea.WindowSizeMsg:
+		t.size = msg
+	}
+	return t, nil
+}
+
+// choose acts on a title menu entry
+func (t titleModel) choose(option titleOption) (tea.Model, tea.Cmd) {
+	t.err = ""
+	switch option {
+	case titleContinue:
+		m, err := resumeGame(t.key, t.name)
+		if err != nil {
+			if !errors.Is(err, errNoSave) {
+				log.Printf("Failed to resume game for %s: %v", t.name, err)
+			}
+			t.err = fmt.Sprintf("Can't continue: %v", err)
+			return t, nil
+		}
+		return t.play(m)
+	case titleNewGame:
+		return t.play(newModel(newSeed()))
+	case titleEndless:
+		return t.play(newEndlessModel(newSeed()))
+	case titleDaily:
+		date := dailyDate(time.Now())
+		if err := dailyBoard.Start(date, t.key, t.name); err != nil {
+			t.err = err.Error()
+			return t, nil
+		}
+		return t.play(newDailyModel(date))
+	case titleLeaderboard:
+		return newLeaderboardModel(t, t.key, ""), nil
+	case titleAchievements:
+		am := newAchievementsModel(t, loadAchievements(t.key))
+		if t.size.Width > 0 {
+			return am.Update(t.size)
+		}
+		return am, nil
+	case titleMorgue:
+		mm := newMorgueModel(t.key)
+		mm.parent = t
+		if t.size.Width > 0 {
+			return mm.Update(t.size)
+		}
+		return mm, nil
+	case titleQuit:
+		return t, tea.Quit
+	}
+	return t, nil
+}
+
+// play hands the session over to a game
+func (t titleModel) play(m model) (tea.Model, tea.Cmd) {
+	m.attach(t.key, t.name, t.session)
+	if t.size.Width > 0 {
+		return m.Update(t.size)
+	}
+	return m, nil
+}
+
+// View renders the title menu
+func (t titleModel) View() string {
+	titleStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#ff5555")).Bold(true)
+	selected := lipgloss.NewStyle().Foreground(lipgloss.Color("#ffff00"))
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("#888888"))
+
+	var b strings.Builder
+	b.WriteString("\n\n  " + titleStyle.Render("C R Y P T C R A W L") + "\n\n")
+	if t.name != "" {
+		fmt.Fprintf(&b, "  Welcome, %s.\n\n", t.name)
+	}
+	for i, option := range t.options {
+		if i == t.cursor {
+			b.WriteString("  " + selected.Render("> "+option.String()) + "\n")
+		} else {
+			b.WriteString("    " + option.String() + "\n")
+		}
+	}
+	if t.err != "" {
+		b.WriteString("\n  " + t.err + "\n")
+	}
+	b.WriteString("\n  " + dim.Render("↑/↓ choose • enter select • q quit") + "\n")
+	return b.String()
+}
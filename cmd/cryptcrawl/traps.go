@@ -0,0 +1,348 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"cryptcrawl/internal/dungeon"
+)
+
+// TrapInstance represents a trap set in the dungeon
+type TrapInstance struct {
+	Pos      Position
+	Template dungeon.TrapTemplate
+	Hidden   bool
+}
+
+// statusEffects tracks lingering effects on the player
+type statusEffects struct {
+	Poisoned     int // Turns of poison left
+	PoisonDamage int // Damage taken per poisoned turn
+	Netted       int // Struggles needed to escape a net
+}
+
+// plainTrap is used for traps drawn in a layout without a trap template
+var plainTrap = dungeon.TrapTemplate{
+	ID:           "trap",
+	Name:         "Trap",
+	Description:  "A crude spring-loaded trap.",
+	Symbol:       "^",
+	Effect:       dungeon.TrapDart,
+	DisarmChance: 0.5,
+}
+
+// Defaults for trap templates that leave fields unset
+const (
+	defaultAlarmDuration  = 20
+	defaultPoisonDuration = 5
+	defaultNetDuration    = 3
+	defaultDisarmChance   = 0.5
+)
+
+// trapTemplate looks up a trap template in the dungeon definition or the built-in traps
+func (m model) trapTemplate(id string) *dungeon.TrapTemplate {
+	if m.def != nil {
+		for i := range m.def.Traps {
+			if m.def.Traps[i].ID == id {
+				return &m.def.Traps[i]
+			}
+		}
+	}
+	for _, trap := range dungeon.DefaultTrapTemplates() {
+		if trap.ID == id {
+			return &trap
+		}
+	}
+	return nil
+}
+
+// trapAt returns the index of the trap at the given position, or -1
+func (m model) trapAt(x, y int) int {
+	for i, trap := range m.traps {
+		if trap.Pos.X == x && trap.Pos.Y == y {
+			return i
+		}
+	}
+	return -1
+}
+
+// addTrap sets a trap at the given position
+func (m *model) addTrap(pos Position, template dungeon.TrapTemplate, hidden bool) {
+	if !m.inBounds(pos.X, pos.Y) {
+		return
+	}
+	m.traps = append(m.traps, TrapInstance{Pos: pos, Template: template, Hidden: hidden})
+	if m.dungeon[pos.Y][pos.X] == Empty || m.dungeon[pos.Y][pos.X] == Trap || m.dungeon[pos.Y][pos.X] == HiddenTrap {
+		m.vacate(pos.X, pos.Y)
+	}
+}
+
+// revealTrap marks the trap at the given position as discovered
+func (m *model) revealTrap(x, y int) {
+	if m.dungeon[y][x] == HiddenTrap {
+		m.dungeon[y][x] = Trap
+	}
+	if i := m.trapAt(x, y); i >= 0 {
+		m.traps[i].Hidden = false
+	}
+}
+
+// removeTrap takes the trap at the given position out of the dungeon
+func (m *model) removeTrap(x, y int) {
+	i := m.trapAt(x, y)
+	if i < 0 {
+		return
+	}
+	m.traps = append(m.traps[:i], m.traps[i+1:]...)
+	if m.dungeon[y][x] == Trap || m.dungeon[y][x] == HiddenTrap {
+		m.dungeon[y][x] = Empty
+	}
+}
+
+// trapDamage rolls the damage dealt by a trap
+func trapDamage(rng *rand.Rand, template dungeon.TrapTemplate) int {
+	if template.Damage > 0 {
+		return template.Damage
+	}
+	return rng.Intn(3) + 1
+}
+
+// triggerTrap sets off the trap under the player
+func (m *model) triggerTrap(x, y int) {
+	i := m.trapAt(x, y)
+	if i < 0 {
+		return
+	}
+
+	trap := m.traps[i]
+	if trap.Hidden {
+		m.addMessage(fmt.Sprintf("You stepped on a hidden %s!", trap.Template.Name))
+		m.revealTrap(x, y)
+	}
+	m.springTrap(trap.Template)
+}
+
+// springTrap applies a trap's effect to the player
+func (m *model) springTrap(template dungeon.TrapTemplate) {
+	switch template.Effect {
+	case dungeon.TrapPit:
+		damage := trapDamage(m.rng, template)
+		m.hurtPlayer(damage, fmt.Sprintf("You fall into a pit! -%d HP", damage), "fell into a pit")
+		if !m.gameOver && (m.level < m.maxDepth() || m.endless) {
+			m.addMessage("The pit drops you to the level below!")
+			m.descend()
+		}
+	case dungeon.TrapTeleport:
+		if pos, ok := m.randomEmptyPosition(); ok {
+			m.vacate(m.player.Pos.X, m.player.Pos.Y)
+			m.player.Pos = pos
+			m.dungeon[pos.Y][pos.X] = Player
+			m.addMessage("A flash of light! You are teleported elsewhere.")
+		}
+	case dungeon.TrapAlarm:
+		m.alertMonsters(template)
+		m.addMessage("A loud alarm rings! You hear monsters stirring.")
+	case dungeon.TrapPoisonGas:
+		duration := template.Duration
+		if duration <= 0 {
+			duration = defaultPoisonDuration
+		}
+		m.status.Poisoned = max(m.status.Poisoned, duration)
+		m.status.PoisonDamage = max(template.Damage, 1)
+		m.addMessage("A cloud of poison gas bursts out! You are poisoned.")
+	case dungeon.TrapNet:
+		duration := template.Duration
+		if duration <= 0 {
+			duration = defaultNetDuration
+		}
+		m.status.Netted = duration
+		m.addMessage("A net drops on you! You are caught.")
+	default:
+		damage := trapDamage(m.rng, template)
+		m.hurtPlayer(damage, fmt.Sprintf("You triggered a %s! -%d HP", template.Name, damage), "killed by "+withArticle(strings.ToLower(template.Name)))
+	}
+}
+
+// triggerTrapOnMonster sets off the trap under the monster at index i
+func (m *model) triggerTrapOnMonster(i int) {
+	monster := &m.monsters[i]
+	t := m.trapAt(monster.Pos.X, monster.Pos.Y)
+	if t < 0 {
+		return
+	}
+
+	template := m.traps[t].Template
+	visible := m.isVisible(monster.Pos.X, monster.Pos.Y)
+	if visible {
+		m.revealTrap(monster.Pos.X, monster.Pos.Y)
+		m.addMessage(fmt.Sprintf("The %s triggers a %s!", monster.Name, template.Name))
+	}
+
+	switch template.Effect {
+	case dungeon.TrapPit:
+		// The monster falls to a level the player isn't on
+		monster.Health = 0
+		m.vacate(monster.Pos.X, monster.Pos.Y)
+		if !visible {
+			m.addMessage("You hear something fall.")
+		}
+	case dungeon.TrapTeleport:
+		if pos, ok := m.randomEmptyPosition(); ok {
+			m.vacate(monster.Pos.X, monster.Pos.Y)
+			monster.Pos = pos
+			m.dungeon[pos.Y][pos.X] = Monster
+		}
+	case dungeon.TrapAlarm:
+		m.alertMonsters(template)
+		m.addMessage("An alarm rings somewhere in the dungeon!")
+	case dungeon.TrapNet:
+		duration := template.Duration
+		if duration <= 0 {
+			duration = defaultNetDuration
+		}
+		monster.Stuck = duration
+	case dungeon.TrapPoisonGas:
+		duration := template.Duration
+		if duration <= 0 {
+			duration = defaultPoisonDuration
+		}
+		monster.Health -= max(template.Damage, 1) * duration
+	default:
+		monster.Health -= trapDamage(m.rng, template)
+	}
+
+	if monster.Health <= 0 && template.Effect != dungeon.TrapPit {
+		m.vacate(monster.Pos.X, monster.Pos.Y)
+		if visible {
+			m.addMessage(fmt.Sprintf("The %s is killed by the trap!", monster.Name))
+		}
+	}
+	if monster.Health > 0 {
+		m.checkBossPhase(i)
+	} else if m.isBoss(*monster) {
+		m.defeatBoss(*monster)
+	}
+}
+
+// alertMonsters sends every monster on the level after the player
+func (m *model) alertMonsters(template dungeon.TrapTemplate) {
+	duration := template.Duration
+	if duration <= 0 {
+		duration = defaultAlarmDuration
+	}
+	for i := range m.monsters {
+		m.monsters[i].Alert = duration
+		m.monsters[i].Stuck = 0
+	}
+}
+
+// hurtPlayer deals damage to the player and ends the game if they die,
+// recording cause as what killed them
+func (m *model) hurtPlayer(damage int, msg, cause string) {
+	m.player.Health -= damage
+	m.hurtOnLevel = true
+	m.addMessage(msg)
+	if m.player.Health <= 0 {
+		m.die(cause)
+		m.addMessage("You died!")
+	}
+}
+
+// die ends the game, recording what killed the player
+func (m *model) die(cause string) {
+	m.gameOver = true
+	m.deathCause = cause
+}
+
+// randomEmptyPosition picks a random empty floor tile in the level
+func (m model) randomEmptyPosition() (Position, bool) {
+	var empty []Position
+	for y := range m.dungeon {
+		for x, tile := range m.dungeon[y] {
+			if tile == Empty {
+				empty = append(empty, Position{X: x, Y: y})
+			}
+		}
+	}
+	if len(empty) == 0 {
+		return Position{}, false
+	}
+	return empty[m.rng.Intn(len(empty))], true
+}
+
+// struggle spends a turn fighting free of a net
+func (m *model) struggle() {
+	m.status.Netted--
+	if m.status.Netted > 0 {
+		m.addMessage("You struggle against the net.")
+	} else {
+		m.addMessage("You break free of the net!")
+	}
+	m.moveMonsters()
+	m.endTurn()
+}
+
+// tickStatus applies lingering effects at the end of a turn
+func (m *model) tickStatus() {
+	if m.status.Poisoned <= 0 || m.gameOver {
+		return
+	}
+	m.status.Poisoned--
+	m.hurtPlayer(m.status.PoisonDamage, fmt.Sprintf("The poison burns in your veins. -%d HP", m.status.PoisonDamage), "succumbed to poison")
+	if m.status.Poisoned == 0 && !m.gameOver {
+		m.addMessage("You feel the poison wear off.")
+	}
+}
+
+// disarm tries to disarm a discovered trap next to or under the player
+func (m *model) disarm() {
+	t := -1
+	for i, trap := range m.traps {
+		if !trap.Hidden && abs(trap.Pos.X-m.player.Pos.X) <= 1 && abs(trap.Pos.Y-m.player.Pos.Y) <= 1 {
+			t = i
+			break
+		}
+	}
+	if t < 0 {
+		m.addMessage("There is no trap nearby to disarm.")
+		return
+	}
+
+	trap := m.traps[t]
+	chance := trap.Template.DisarmChance
+	if chance <= 0 {
+		chance = defaultDisarmChance
+	}
+
+	switch {
+	case m.rng.Float64() < chance:
+		m.removeTrap(trap.Pos.X, trap.Pos.Y)
+		m.addMessage(fmt.Sprintf("You disarm the %s.", trap.Template.Name))
+	case m.rng.Intn(3) == 0:
+		m.addMessage(fmt.Sprintf("You set off the %s!", trap.Template.Name))
+		m.springTrap(trap.Template)
+	default:
+		m.addMessage(fmt.Sprintf("You fail to disarm the %s.", trap.Template.Name))
+	}
+
+	if !m.gameOver {
+		m.moveMonsters()
+		m.endTurn()
+	}
+}
+
+// renderTrapAt renders a discovered trap with its template's symbol and color
+func (m model) renderTrapAt(x, y int) string {
+	i := m.trapAt(x, y)
+	if i < 0 || m.traps[i].Template.Symbol == "" {
+		return RenderTile(Trap)
+	}
+	style := TileMap[Trap].Style
+	if m.traps[i].Template.Color != "" {
+		style = lipgloss.NewStyle().Foreground(lipgloss.Color(m.traps[i].Template.Color))
+	}
+	return style.Render(string([]rune(m.traps[i].Template.Symbol)[0]))
+}
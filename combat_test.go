@@ -0,0 +1,102 @@
+package main
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestResolveAttackOutcomes(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	attacker := Entity{Damage: 4}
+	seen := make(map[attackResult]bool)
+
+	for i := 0; i < 2000; i++ {
+		result, damage := resolveAttack(rng, attacker, Entity{})
+		seen[result] = true
+
+		switch result {
+		case attackMiss:
+			if damage != 0 {
+				t.Errorf("Expected a miss to deal no damage, got %d", damage)
+			}
+		case attackGlancing:
+			if damage != 2 {
+				t.Errorf("Expected a glancing blow to deal half damage, got %d", damage)
+			}
+		case attackHit:
+			if damage != 4 {
+				t.Errorf("Expected a hit to deal full damage, got %d", damage)
+			}
+		case attackCritical:
+			if damage != 8 {
+				t.Errorf("Expected a critical hit to deal double damage, got %d", damage)
+			}
+		}
+	}
+
+	for _, result := range []attackResult{attackMiss, attackGlancing, attackHit, attackCritical} {
+		if !seen[result] {
+			t.Errorf("Expected attack result %d to occur", result)
+		}
+	}
+}
+
+func TestResolveAttackAccuracyAndEvasion(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		// Only a natural 1 misses a hopelessly outmatched defender
+		if result, _ := resolveAttack(rng, Entity{Damage: 1, Accuracy: 50}, Entity{}); result < attackHit && result != attackMiss {
+			t.Fatalf("Expected an accurate attack to hit, got result %d", result)
+		}
+		// Only a natural 20 lands on a hopelessly evasive defender
+		if result, _ := resolveAttack(rng, Entity{Damage: 1}, Entity{Evasion: 50}); result != attackMiss && result != attackCritical {
+			t.Fatalf("Expected an evasive defender to be missed, got result %d", result)
+		}
+	}
+}
+
+func TestRollDamageUsesDice(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	e := Entity{Damage: 100, DamageDice: "1d4+1"}
+	for i := 0; i < 200; i++ {
+		if damage := rollDamage(rng, e); damage < 2 || damage > 5 {
+			t.Fatalf("Expected 1d4+1 damage between 2 and 5, got %d", damage)
+		}
+	}
+
+	if damage := rollDamage(rng, Entity{Damage: 3}); damage != 3 {
+		t.Errorf("Expected flat damage without dice, got %d", damage)
+	}
+}
+
+func TestPlayerAttackKillsMonster(t *testing.T) {
+	m := initialModel()
+	m.monsters = []Entity{{Pos: Position{X: 1, Y: 1}, Health: 1, MaxHealth: 1, Name: "Skeleton"}}
+	m.dungeon[1][1] = Monster
+	m.player.Accuracy = 50
+
+	killed := false
+	for i := 0; i < 20 && !killed; i++ {
+		killed = m.playerAttack(0)
+	}
+	if !killed {
+		t.Fatal("Expected the player to kill the monster")
+	}
+	if len(m.monsters) != 0 {
+		t.Errorf("Expected the dead monster to be removed, got %d monsters", len(m.monsters))
+	}
+	if m.dungeon[1][1] == Monster {
+		t.Error("Expected the monster tile to be cleared")
+	}
+
+	found := false
+	for _, msg := range m.messages {
+		if strings.Contains(msg, "You killed the skeleton!") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected the log to name the monster, got %v", m.messages)
+	}
+}
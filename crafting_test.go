@@ -0,0 +1,90 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"cryptcrawl/internal/dungeon"
+)
+
+// newCraftingTestModel builds a game on the example dungeon's first level with
+// an empty inventory
+func newCraftingTestModel(t *testing.T) model {
+	m := newDefinitionModel(t, dungeon.CreateExampleDungeon())
+	m.inventory = nil
+	return m
+}
+
+// standNextToAltar moves the player next to the bone altar of the first level
+func standNextToAltar(t *testing.T, m *model) {
+	altar := m.def.Levels[0].Stations[0].Position
+	if m.dungeon[altar.Y][altar.X] != Station {
+		t.Fatalf("Expected a station tile at %v", altar)
+	}
+	m.vacate(m.player.Pos.X, m.player.Pos.Y)
+	m.player.Pos = Position{X: altar.X - 1, Y: altar.Y}
+	m.dungeon[m.player.Pos.Y][m.player.Pos.X] = Player
+}
+
+func TestCraft(t *testing.T) {
+	m := newCraftingTestModel(t)
+	m.addToInventory(ItemInstance{Template: *m.itemTemplate("bone_shard"), Count: 3})
+	m.addToInventory(ItemInstance{Template: *m.itemTemplate("rotten_flesh"), Count: 1})
+
+	// The broth needs the altar
+	m.craft(0)
+	if m.inventoryCount("bone_broth") != 0 {
+		t.Fatal("Expected crafting away from the altar to fail")
+	}
+
+	standNextToAltar(t, &m)
+	turns := m.turns
+	m.monsters = []Entity{{Pos: Position{X: 1, Y: 1}, Health: 5, MaxHealth: 5, Stuck: 2, Name: "Zombie"}}
+	m.craft(0)
+	if m.inventoryCount("bone_broth") != 1 {
+		t.Fatalf("Expected to craft bone broth, got %v", m.inventory)
+	}
+	if m.inventoryCount("bone_shard") != 1 || m.inventoryCount("rotten_flesh") != 0 {
+		t.Errorf("Expected the ingredients to be used up, got %v", m.inventory)
+	}
+	if m.turns != turns+1 {
+		t.Error("Expected crafting to take a turn")
+	}
+	if m.monsters[0].Stuck != 1 {
+		t.Errorf("Expected the monsters to act while the player crafts, got %d turns stuck", m.monsters[0].Stuck)
+	}
+
+	// Not enough ingredients left for another
+	m.craft(0)
+	if m.inventoryCount("bone_broth") != 1 {
+		t.Error("Expected crafting without ingredients to fail")
+	}
+}
+
+func TestCraftingScreen(t *testing.T) {
+	m := newCraftingTestModel(t)
+	standNextToAltar(t, &m)
+	m.addToInventory(ItemInstance{Template: *m.itemTemplate("bone_shard"), Count: 2})
+	m.addToInventory(ItemInstance{Template: *m.itemTemplate("rotten_flesh"), Count: 1})
+
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'c'}})
+	screen, ok := next.(craftingModel)
+	if !ok {
+		t.Fatalf("Expected C to open the crafting screen, got %T", next)
+	}
+	next, _ = screen.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	next, _ = next.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	game, ok := next.(model)
+	if !ok {
+		t.Fatalf("Expected esc to go back to the game, got %T", next)
+	}
+
+	if game.inventoryCount("bone_broth") != 1 {
+		t.Errorf("Expected the screen to craft bone broth, got %v", game.inventory)
+	}
+	if want := []action{craftAction(0)}; !reflect.DeepEqual(game.actions, want) {
+		t.Errorf("Expected crafting to be recorded as %v, got %v", want, game.actions)
+	}
+}
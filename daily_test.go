@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDailySeed(t *testing.T) {
+	if dailySeed("2024-05-01") != dailySeed("2024-05-01") {
+		t.Error("Expected the same date to give the same seed")
+	}
+	if dailySeed("2024-05-01") == dailySeed("2024-05-02") {
+		t.Error("Expected different dates to give different seeds")
+	}
+	if seed := dailySeed("2024-05-01"); seed < 0 {
+		t.Errorf("Expected a non-negative seed, got %d", seed)
+	}
+
+	date := dailyDate(time.Date(2024, 5, 1, 23, 30, 0, 0, time.FixedZone("UTC-2", -2*60*60)))
+	if date != "2024-05-02" {
+		t.Errorf("Expected daily dates in UTC, got %s", date)
+	}
+}
+
+func TestDailyModelIsShared(t *testing.T) {
+	a := newDailyModel("2024-05-01")
+	b := newDailyModel("2024-05-01")
+
+	if !reflect.DeepEqual(a.dungeon, b.dungeon) {
+		t.Error("Expected every player to get the same daily dungeon")
+	}
+	if a.def != nil {
+		t.Error("Expected the daily challenge to use a generated dungeon")
+	}
+	if a.dailyDate != "2024-05-01" {
+		t.Errorf("Expected the daily date to be set, got %q", a.dailyDate)
+	}
+
+	// Deeper levels are the same however the players got there
+	a.search()
+	a.search()
+	b.movePlayer(1, 0)
+	a.travel(2)
+	b.travel(2)
+	if !reflect.DeepEqual(a.dungeon, b.dungeon) || !reflect.DeepEqual(a.monsters, b.monsters) {
+		t.Error("Expected every player to get the same second level")
+	}
+}
+
+func TestDailyStoreOneAttempt(t *testing.T) {
+	store, err := newDailyStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create daily store: %v", err)
+	}
+
+	if err := store.Start("2024-05-01", "key1", "alice"); err != nil {
+		t.Fatalf("Failed to start attempt: %v", err)
+	}
+	if err := store.Start("2024-05-01", "key1", "alice"); !errors.Is(err, errAlreadyPlayed) {
+		t.Errorf("Expected a second attempt to be refused, got %v", err)
+	}
+	if err := store.Start("2024-05-02", "key1", "alice"); err != nil {
+		t.Errorf("Expected an attempt on another day to be allowed, got %v", err)
+	}
+}
+
+func TestDailyStoreEntries(t *testing.T) {
+	store, err := newDailyStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create daily store: %v", err)
+	}
+
+	results := map[string]dailyEntry{
+		"deep":    {Level: 3, Gold: 10, Turns: 300},
+		"rich":    {Level: 2, Gold: 90, Turns: 200},
+		"escaped": {Won: true, Level: 3, Gold: 5, Turns: 400},
+	}
+	for key, result := range results {
+		if err := store.Start("2024-05-01", key, key); err != nil {
+			t.Fatalf("Failed to start attempt: %v", err)
+		}
+		if err := store.Finish("2024-05-01", key, result); err != nil {
+			t.Fatalf("Failed to finish attempt: %v", err)
+		}
+	}
+	if err := store.Start("2024-05-01", "quitter", "quitter"); err != nil {
+		t.Fatalf("Failed to start attempt: %v", err)
+	}
+
+	entries, err := store.Entries("2024-05-01")
+	if err != nil {
+		t.Fatalf("Failed to read entries: %v", err)
+	}
+
+	var order []string
+	for _, entry := range entries {
+		order = append(order, entry.Key)
+	}
+	if want := []string{"escaped", "deep", "rich"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("Expected ranking %v, got %v", want, order)
+	}
+
+	if err := store.Finish("2024-05-01", "deep", dailyEntry{Level: 9}); err == nil {
+		t.Error("Expected a finished attempt not to be overwritten")
+	}
+}
+
+func TestDailyBoardReadOnce(t *testing.T) {
+	store, err := newDailyStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create daily store: %v", err)
+	}
+	old := dailyBoard
+	dailyBoard = store
+	defer func() { dailyBoard = old }()
+
+	m := newDailyModel("2024-05-01")
+	m.playerKey = "key1"
+	m.playerName = "alice"
+	if err := store.Start(m.dailyDate, m.playerKey, m.playerName); err != nil {
+		t.Fatalf("Failed to start attempt: %v", err)
+	}
+	m.die("fell into a pit")
+	m.finishRun()
+	if len(m.dailyEntries) != 1 || m.dailyEntries[0].Name != "alice" {
+		t.Fatalf("Expected the daily board to be read when the run ends, got %+v", m.dailyEntries)
+	}
+
+	// Rendering the game over screen doesn't read the board again
+	if err := os.Remove(store.path(m.dailyDate)); err != nil {
+		t.Fatalf("Failed to remove the daily board: %v", err)
+	}
+	if view := m.View(); !strings.Contains(view, "alice") {
+		t.Errorf("Expected the game over screen to show the daily board, got:\n%s", view)
+	}
+}
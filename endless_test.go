@@ -0,0 +1,91 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"cryptcrawl/internal/dungeon"
+)
+
+func TestDifficultyCurve(t *testing.T) {
+	curve := dungeon.DifficultyCurve{Linear: 0.5, Growth: 2, Max: 10}
+	for depth, want := range map[int]float64{0: 1, 1: 1, 2: 3, 3: 8, 4: 10} {
+		if got := curve.At(depth); math.Abs(got-want) > 1e-9 {
+			t.Errorf("At(%d) = %v, want %v", depth, got, want)
+		}
+	}
+	if got := (dungeon.DifficultyCurve{}).At(20); got != 1 {
+		t.Errorf("Expected a flat curve by default, got %v", got)
+	}
+}
+
+func TestEndlessDescent(t *testing.T) {
+	m := newEndlessModel(1)
+	for m.level < defaultTreasureEvery {
+		m.descend()
+	}
+	if m.gameWon {
+		t.Fatal("Expected an endless descent to go past the last level")
+	}
+	if m.levelKind() != treasureLevel || m.monsterScale() <= 1 || m.lootScale() <= 1 {
+		t.Errorf("Expected level 5 to be a treasure vault, got %v with scales %v, %v", m.levelKind(), m.monsterScale(), m.lootScale())
+	}
+	for _, monster := range m.monsters {
+		if !m.isBoss(monster) {
+			t.Errorf("Expected a treasure vault to be unguarded, got %s", monster.Name)
+		}
+	}
+
+	// The deepest level is kept on the leaderboard even after climbing back up
+	m.travel(2)
+	if e := m.scoreEntry(); e.Depth != 5 || e.Dungeon != randomDungeonName+endlessSuffix {
+		t.Errorf("Expected the endless board to record depth 5, got %d on %s", e.Depth, e.Dungeon)
+	}
+	if !strings.Contains(m.morgue(), "deepest 5 (endless)") {
+		t.Error("Expected the morgue file to record the deepest level")
+	}
+
+	// Ordinary runs don't scale
+	if plain := newModel(1); plain.monsterScale() != 1 || plain.levelKind() != normalLevel {
+		t.Error("Expected ordinary runs to be left alone")
+	}
+}
+
+func TestEndlessBossFloor(t *testing.T) {
+	m := newEndlessModel(2)
+	for m.level < defaultBossEvery {
+		m.descend()
+	}
+	if m.levelKind() != bossLevel {
+		t.Fatalf("Expected level %d to be a boss floor", m.level)
+	}
+	var boss *Entity
+	for i := range m.monsters {
+		if m.isBoss(m.monsters[i]) {
+			boss = &m.monsters[i]
+		}
+	}
+	if boss == nil || boss.TemplateID != wardenTemplate.ID {
+		t.Fatal("Expected the Warden of the Deep to guard a random dungeon's boss floor")
+	}
+	if boss.MaxHealth <= wardenTemplate.Health {
+		t.Errorf("Expected the boss to be scaled to the depth, got %d health", boss.MaxHealth)
+	}
+	if exit := m.stairs[len(m.stairs)-1].Pos; abs(boss.Pos.X-exit.X) > 1 || abs(boss.Pos.Y-exit.Y) > 1 {
+		t.Errorf("Expected the boss next to the exit at %v, got %v", exit, boss.Pos)
+	}
+
+	// A dungeon's own settings pick the boss
+	def := dungeon.CreateExampleDungeon()
+	def.Monsters = append(def.Monsters, dungeon.MonsterTemplate{ID: "lich", Name: "Lich", Symbol: "L", Health: 30, Damage: "1d6", Boss: true})
+	def.Endless = &dungeon.EndlessConfig{BossEvery: 3, Boss: "lich"}
+	m = newGame(2, def)
+	m.endless = true
+	if got := m.floorBoss(); got.ID != "lich" {
+		t.Errorf("Expected the dungeon's boss on its boss floors, got %s", got.ID)
+	}
+	if c := m.endlessConfig(); c.BossEvery != 3 || c.TreasureEvery != defaultTreasureEvery {
+		t.Errorf("Expected the dungeon's settings over the defaults, got %+v", c)
+	}
+}
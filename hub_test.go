@@ -0,0 +1,173 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"cryptcrawl/internal/dungeon"
+)
+
+// testHub returns a hub town with a portal to the given dungeon, a shop and an inn
+func testHub(def *dungeon.DungeonDefinition) *dungeon.HubDefinition {
+	return &dungeon.HubDefinition{
+		Name:     "Ashford",
+		Layout:   []string{"#######", "#.....#", "#.....#", "#######"},
+		StartPos: dungeon.Position{X: 2, Y: 1},
+		Portals:  []dungeon.PortalDefinition{{Dungeon: def.Name, Position: dungeon.Position{X: 1, Y: 1}}},
+		Shops: []dungeon.ShopDefinition{{
+			Name:     "Provisions",
+			Position: dungeon.Position{X: 3, Y: 1},
+			Wares:    []dungeon.WareDefinition{{ItemID: "bread", Price: 5}},
+		}},
+		Inns:  []dungeon.InnDefinition{{Name: "The Sleeping Gryphon", Position: dungeon.Position{X: 2, Y: 2}, Price: 10}},
+		Items: []dungeon.ItemTemplate{{ID: "bread", Name: "Bread", Type: "food", Nutrition: 400}},
+	}
+}
+
+func TestCampaignHub(t *testing.T) {
+	def := dungeon.CreateExampleDungeon()
+	dungeonLoader = &dungeon.DungeonLoader{Dungeons: []*dungeon.DungeonDefinition{def}}
+	defer func() { dungeonLoader = nil }()
+
+	m := newCampaignModel(3, testHub(def))
+	if !m.inHub || m.level != 0 || m.dungeon[1][1] != Portal || m.locationName() != "Ashford" {
+		t.Fatalf("Expected the campaign to start in the hub, got level %d in %s", m.level, m.locationName())
+	}
+
+	// Shopping and resting cost gold
+	m.gold = 20
+	m.player.Health = 1
+	m.act(actionRight)
+	if m.shop == nil {
+		t.Fatal("Expected bumping the shop to open it")
+	}
+	m.act(buyAction(0))
+	if m.gold != 15 || len(m.inventory) != 1 || m.inventory[0].Template.ID != "bread" {
+		t.Errorf("Expected to buy bread for 5 gold, got %d gold and %v", m.gold, m.inventory)
+	}
+	m.shop = nil
+	m.act(actionDown)
+	if m.gold != 5 || m.player.Health != m.player.MaxHealth {
+		t.Errorf("Expected a night at the inn to heal for 10 gold, got %d gold and %d health", m.gold, m.player.Health)
+	}
+
+	// The portal leads into the dungeon, and the stairs up lead back out
+	m.act(actionLeft)
+	if m.inHub || m.def != def || m.level != 1 {
+		t.Fatalf("Expected to enter %s, got level %d", def.Name, m.level)
+	}
+	fled := false
+	for _, stairs := range m.stairs {
+		if stairs.Target < 1 {
+			m.useStairs(stairs.Pos.X, stairs.Pos.Y)
+			fled = true
+			break
+		}
+	}
+	if !fled || !m.inHub || m.conquered[def.Name] {
+		t.Fatal("Expected to flee back to the hub")
+	}
+
+	// Conquering the only dungeon wins the campaign
+	m.enterPortal(1, 1)
+	m.level = m.maxDepth()
+	m.descend()
+	if !m.gameWon || !m.conquered[def.Name] {
+		t.Error("Expected conquering every dungeon to win the campaign")
+	}
+	if m.dungeonName() != "Ashford" {
+		t.Errorf("Expected the campaign to be scored on the hub's board, got %s", m.dungeonName())
+	}
+}
+
+func TestHubPausesHunger(t *testing.T) {
+	def := dungeon.CreateExampleDungeon()
+	dungeonLoader = &dungeon.DungeonLoader{Dungeons: []*dungeon.DungeonDefinition{def}}
+	defer func() { dungeonLoader = nil }()
+
+	m := newCampaignModel(3, testHub(def))
+	m.enterPortal(1, 1)
+	m.hunger.Satiation = 1
+	m.leaveDungeon(false)
+
+	for i := 0; i < 100; i++ {
+		m.endTurn()
+	}
+	if m.gameOver || m.hunger.Satiation != 1 {
+		t.Errorf("Expected the hunger clock to stop in the hub, got satiation %d", m.hunger.Satiation)
+	}
+}
+
+func TestShopIdentifiesWares(t *testing.T) {
+	def := dungeon.CreateExampleDungeon()
+	hub := testHub(def)
+	hub.Items = append(hub.Items, dungeon.ItemTemplate{ID: "elixir", Name: "Elixir of Life", Type: dungeon.ItemPotion, Unidentified: true})
+	hub.Shops[0].Wares = append(hub.Shops[0].Wares, dungeon.WareDefinition{ItemID: "elixir", Price: 5})
+
+	m := newCampaignModel(3, hub)
+	appearance := m.appearances["elixir"]
+	if appearance == "" {
+		t.Fatal("Expected the hub's unidentified wares to be disguised")
+	}
+	if other := newCampaignModel(3, hub).appearances["elixir"]; other != appearance {
+		t.Errorf("Expected the same seed to disguise the ware the same way, got %q and %q", appearance, other)
+	}
+
+	m.gold = 20
+	m.act(actionRight)
+	if menu := m.shopMenuView(); strings.Contains(menu, "Elixir") || !strings.Contains(menu, appearance) {
+		t.Errorf("Expected the shop to list the ware by its appearance, got:\n%s", menu)
+	}
+	m.act(buyAction(1))
+	if !m.identified["elixir"] || m.itemName(m.inventory[0]) != "Elixir of Life" {
+		t.Errorf("Expected buying the ware to identify it, got %q", m.itemName(m.inventory[0]))
+	}
+
+	// The ware keeps its appearance in the dungeon
+	dungeonLoader = &dungeon.DungeonLoader{Dungeons: []*dungeon.DungeonDefinition{def}}
+	defer func() { dungeonLoader = nil }()
+	m.enterPortal(1, 1)
+	if m.appearances["elixir"] != appearance {
+		t.Errorf("Expected the ware to look the same in the dungeon, got %q", m.appearances["elixir"])
+	}
+}
+
+func TestCampaignReplayAndSave(t *testing.T) {
+	def := dungeon.CreateExampleDungeon()
+	hub := testHub(def)
+	dungeonLoader = &dungeon.DungeonLoader{Dungeons: []*dungeon.DungeonDefinition{def}, Hub: hub}
+	defer func() { dungeonLoader = nil }()
+
+	m := newModel(8)
+	m.gold = 10
+	for _, a := range []action{actionRight, buyAction(0), actionLeft, actionLeft, actionDown} {
+		m.act(a)
+	}
+	if m.inHub {
+		t.Fatal("Expected to have entered the dungeon")
+	}
+
+	r := m.replay()
+	if !r.Campaign || r.Dungeon != "" {
+		t.Errorf("Expected the replay to start in the hub, got %+v", r)
+	}
+	replayed, err := r.start()
+	if err != nil {
+		t.Fatalf("Failed to start replay: %v", err)
+	}
+	replayed.gold = 10
+	for _, a := range []action(r.Actions) {
+		replayed.apply(a)
+	}
+	if replayed.player.Pos != m.player.Pos || replayed.gold != m.gold || replayed.def != m.def {
+		t.Error("Expected the replay to play out the same way")
+	}
+
+	restored, err := restoreGame(m.snapshot())
+	if err != nil {
+		t.Fatalf("Failed to restore game: %v", err)
+	}
+	if restored.hub != hub || restored.inHub || restored.def != def {
+		t.Error("Expected the campaign to be restored inside the dungeon")
+	}
+}
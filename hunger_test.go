@@ -0,0 +1,135 @@
+package main
+
+import (
+	"testing"
+
+	"cryptcrawl/internal/dungeon"
+)
+
+func TestNewHungerClock(t *testing.T) {
+	if newHungerClock(nil).Enabled() {
+		t.Error("Expected hunger to be disabled without a config")
+	}
+
+	if newHungerClock(&dungeon.HungerConfig{Enabled: false}).Enabled() {
+		t.Error("Expected hunger to be disabled when the config is disabled")
+	}
+
+	clock := newHungerClock(&dungeon.HungerConfig{Enabled: true})
+	if !clock.Enabled() {
+		t.Fatal("Expected hunger to be enabled")
+	}
+	if clock.Satiation != defaultStartSatiation {
+		t.Errorf("Expected default start satiation %d, got %d", defaultStartSatiation, clock.Satiation)
+	}
+	if clock.Config.MaxSatiation != defaultMaxSatiation {
+		t.Errorf("Expected default max satiation %d, got %d", defaultMaxSatiation, clock.Config.MaxSatiation)
+	}
+}
+
+func TestHungerState(t *testing.T) {
+	clock := newHungerClock(&dungeon.HungerConfig{Enabled: true})
+
+	tests := []struct {
+		satiation int
+		expected  HungerState
+	}{
+		{500, NotHungry},
+		{defaultHungryAt, Hungry},
+		{defaultWeakAt, Weak},
+		{defaultFaintingAt, Fainting},
+		{0, Starving},
+	}
+
+	for _, tt := range tests {
+		clock.Satiation = tt.satiation
+		if state := clock.State(); state != tt.expected {
+			t.Errorf("State() at satiation %d = %v, want %v", tt.satiation, state, tt.expected)
+		}
+	}
+}
+
+func TestTickHunger(t *testing.T) {
+	m := newDefinitionModel(t, dungeon.CreateExampleDungeon())
+
+	m.hunger.Satiation = m.hunger.Config.HungryAt + 1
+	m.tickHunger()
+	if m.hunger.State() != Hungry {
+		t.Errorf("Expected to be hungry, got %v", m.hunger.State())
+	}
+	if m.messages[len(m.messages)-1] != "You are getting hungry." {
+		t.Errorf("Expected a hunger message, got %q", m.messages[len(m.messages)-1])
+	}
+
+	// Starvation hurts every turn
+	m.hunger.Satiation = 0
+	health := m.player.Health
+	m.tickHunger()
+	if m.player.Health != health-m.hunger.Config.StarvationDamage {
+		t.Errorf("Expected starvation damage, health went from %d to %d", health, m.player.Health)
+	}
+
+	m.player.Health = 1
+	m.tickHunger()
+	if !m.gameOver {
+		t.Error("Expected the player to starve to death")
+	}
+}
+
+func TestTickHungerDisabled(t *testing.T) {
+	m := initialModel()
+	m.hunger = newHungerClock(nil)
+	health := m.player.Health
+
+	for i := 0; i < 10; i++ {
+		m.tickHunger()
+	}
+
+	if m.player.Health != health {
+		t.Errorf("Expected no starvation damage with hunger disabled, got health %d", m.player.Health)
+	}
+}
+
+func TestEat(t *testing.T) {
+	def := dungeon.CreateExampleDungeon()
+	m := newDefinitionModel(t, def)
+	m.inventory = nil
+
+	m.eat()
+	if m.messages[len(m.messages)-1] != "You have nothing to eat." {
+		t.Errorf("Expected nothing to eat, got %q", m.messages[len(m.messages)-1])
+	}
+
+	bread := m.itemTemplate("stale_bread")
+	if bread == nil {
+		t.Fatal("Example dungeon has no stale_bread item")
+	}
+	m.addToInventory(ItemInstance{Template: *bread, Count: 2})
+	m.hunger.Satiation = 100
+
+	m.eat()
+	if m.hunger.Satiation != 100+bread.Nutrition-m.hunger.Config.DrainPerTurn {
+		t.Errorf("Expected satiation %d, got %d", 100+bread.Nutrition-m.hunger.Config.DrainPerTurn, m.hunger.Satiation)
+	}
+	if len(m.inventory) != 1 || m.inventory[0].Count != 1 {
+		t.Errorf("Expected one bread left, got %v", m.inventory)
+	}
+
+	// Monsters get their turn while the player eats
+	m.monsters = []Entity{{Pos: Position{X: 1, Y: 1}, Health: 5, MaxHealth: 5, Stuck: 2, Name: "Zombie"}}
+	m.addToInventory(ItemInstance{Template: *bread, Count: 1})
+	m.eat()
+	if m.monsters[0].Stuck != 1 {
+		t.Errorf("Expected the monsters to act while the player eats, got %d turns stuck", m.monsters[0].Stuck)
+	}
+
+	// Satiation never exceeds the maximum
+	m.hunger.Satiation = m.hunger.Config.MaxSatiation
+	m.eat()
+	if m.hunger.Satiation > m.hunger.Config.MaxSatiation {
+		t.Errorf("Satiation %d exceeds maximum %d", m.hunger.Satiation, m.hunger.Config.MaxSatiation)
+	}
+	if len(m.inventory) != 0 {
+		t.Errorf("Expected the inventory to be empty, got %v", m.inventory)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"cryptcrawl/internal/dungeon"
+)
+
+func TestAssignAppearances(t *testing.T) {
+	def := dungeon.CreateExampleDungeon()
+	a := assignAppearances(1, def.Items)
+	for i := 0; i < 20; i++ {
+		if !reflect.DeepEqual(a, assignAppearances(1, def.Items)) {
+			t.Fatal("Expected the same seed to give the same appearances")
+		}
+	}
+
+	potion := a["health_potion"]
+	if !strings.HasSuffix(potion, " potion") {
+		t.Errorf("Expected the health potion to look like a potion, got %q", potion)
+	}
+	if !strings.HasPrefix(a["scroll_of_identify"], "scroll labelled ") {
+		t.Errorf("Expected the identify scroll to look like a scroll, got %q", a["scroll_of_identify"])
+	}
+	if _, ok := a["gold"]; ok {
+		t.Error("Expected identified items to have no appearance")
+	}
+
+	// Some seed must disguise the potion differently
+	for seed := int64(2); seed < 20; seed++ {
+		if assignAppearances(seed, def.Items)["health_potion"] != potion {
+			return
+		}
+	}
+	t.Error("Expected appearances to change between runs")
+}
+
+func newIdentifyModel(t *testing.T) model {
+	m := newDefinitionModel(t, dungeon.CreateExampleDungeon())
+	m.appearances = assignAppearances(m.seed, m.def.Items)
+	m.inventory = nil
+	return m
+}
+
+func TestUseIdentifiesItem(t *testing.T) {
+	m := newIdentifyModel(t)
+	potion := *m.itemTemplate("health_potion")
+	m.addToInventory(ItemInstance{Template: potion, Count: 2})
+
+	if name := m.itemName(m.inventory[0]); name != "2 x "+m.appearances["health_potion"] {
+		t.Errorf("Expected the potion to be disguised, got %q", name)
+	}
+
+	m.player.Health = 1
+	m.useItem(0)
+	if m.player.Health != 6 {
+		t.Errorf("Expected the potion to heal 5, got health %d", m.player.Health)
+	}
+	if !m.isIdentified(potion) {
+		t.Error("Expected drinking the potion to identify it")
+	}
+	if name := m.itemName(m.inventory[0]); name != "Health Potion" {
+		t.Errorf("Expected the true name once identified, got %q", name)
+	}
+}
+
+func TestUseItemTakesATurn(t *testing.T) {
+	m := newIdentifyModel(t)
+	m.addToInventory(ItemInstance{Template: *m.itemTemplate("health_potion"), Count: 1})
+	pos := Position{X: m.player.Pos.X + 1, Y: m.player.Pos.Y}
+	m.monsters = []Entity{{Pos: pos, Health: 5, MaxHealth: 5, Damage: 1, Alert: 5, Name: "Zombie"}}
+	m.dungeon[pos.Y][pos.X] = Monster
+
+	m.useItem(0)
+	attacked := false
+	for _, msg := range m.messages {
+		attacked = attacked || strings.HasPrefix(msg, "The zombie")
+	}
+	if !attacked {
+		t.Errorf("Expected the zombie next to the player to attack, got %v", m.messages)
+	}
+}
+
+func TestScrollOfIdentify(t *testing.T) {
+	m := newIdentifyModel(t)
+	m.addToInventory(ItemInstance{Template: *m.itemTemplate("scroll_of_identify"), Count: 1})
+	m.addToInventory(ItemInstance{Template: *m.itemTemplate("health_potion"), Count: 1})
+
+	m.useItem(0)
+	if !m.identified["health_potion"] || !m.identified["scroll_of_identify"] {
+		t.Errorf("Expected both the scroll and the potion to be identified, got %v", m.identified)
+	}
+
+	list := m.discoveries()
+	if len(list) != 2 || list[0].name == "" || list[1].name == "" {
+		t.Errorf("Expected two discoveries, got %+v", list)
+	}
+}
+
+func TestUseMenu(t *testing.T) {
+	m := newIdentifyModel(t)
+	m.addToInventory(ItemInstance{Template: *m.itemTemplate("stale_bread"), Count: 1})
+	m.addToInventory(ItemInstance{Template: *m.itemTemplate("health_potion"), Count: 1})
+
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'u'}})
+	m = next.(model)
+	if !m.choosing {
+		t.Fatal("Expected U to open the use menu")
+	}
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'a'}})
+	m = next.(model)
+
+	if m.choosing {
+		t.Error("Expected the menu to close after choosing")
+	}
+	if len(m.inventory) != 1 || m.inventory[0].Template.ID != "stale_bread" {
+		t.Errorf("Expected the potion, the only usable item, to be used, got %v", m.inventory)
+	}
+	if want := []action{useAction(1)}; !reflect.DeepEqual(m.actions, want) {
+		t.Errorf("Expected the use to be recorded as %v, got %v", want, m.actions)
+	}
+}
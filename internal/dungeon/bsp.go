@@ -0,0 +1,166 @@
+package dungeon
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// BSP tuning: a leaf must fit the smallest room plus a wall on each side
+const (
+	bspMinRoomSize = 3
+	bspMinLeafSize = bspMinRoomSize + 4
+	bspMinRooms    = 5
+	bspMaxRooms    = 10
+)
+
+func init() {
+	RegisterGenerator("bsp", bspGenerator{})
+}
+
+// bspGenerator splits the level into a binary tree of areas, puts a room in
+// every leaf and joins sibling areas with corridors
+type bspGenerator struct{}
+
+// bspNode is an area of the level, split in two unless it's a leaf
+type bspNode struct {
+	x, y, w, h  int
+	left, right *bspNode
+	room        *RoomDefinition
+}
+
+// splittable reports whether the area can be cut into two leaves
+func (n *bspNode) splittable() bool {
+	return n.w >= 2*bspMinLeafSize || n.h >= 2*bspMinLeafSize
+}
+
+// split cuts a leaf in two across its longer side
+func (n *bspNode) split(rng *rand.Rand) {
+	vertical := n.w >= n.h
+	if n.w < 2*bspMinLeafSize {
+		vertical = false
+	} else if n.h < 2*bspMinLeafSize {
+		vertical = true
+	}
+
+	if vertical {
+		cut := bspMinLeafSize + rng.Intn(n.w-2*bspMinLeafSize+1)
+		n.left = &bspNode{x: n.x, y: n.y, w: cut, h: n.h}
+		n.right = &bspNode{x: n.x + cut, y: n.y, w: n.w - cut, h: n.h}
+	} else {
+		cut := bspMinLeafSize + rng.Intn(n.h-2*bspMinLeafSize+1)
+		n.left = &bspNode{x: n.x, y: n.y, w: n.w, h: cut}
+		n.right = &bspNode{x: n.x, y: n.y + cut, w: n.w, h: n.h - cut}
+	}
+}
+
+// rooms returns the rooms of every leaf under the node
+func (n *bspNode) rooms() []*RoomDefinition {
+	if n.left == nil {
+		return []*RoomDefinition{n.room}
+	}
+	return append(n.left.rooms(), n.right.rooms()...)
+}
+
+// Generate builds a level with between MinRooms and MaxRooms rooms, or
+// returns an error if the level is too small to fit MinRooms
+func (bspGenerator) Generate(rng *rand.Rand, params GeneratorParams) (*GeneratedLevel, error) {
+	minRooms, maxRooms := params.MinRooms, params.MaxRooms
+	if minRooms <= 0 {
+		minRooms = bspMinRooms
+	}
+	if maxRooms < minRooms {
+		maxRooms = max(minRooms, bspMaxRooms)
+	}
+	if params.Width < bspMinLeafSize || params.Height < bspMinLeafSize {
+		return nil, fmt.Errorf("bsp: a %dx%d level is too small for a room", params.Width, params.Height)
+	}
+	target := minRooms + rng.Intn(maxRooms-minRooms+1)
+
+	// Keep splitting the biggest leaf until there are enough of them
+	root := &bspNode{w: params.Width, h: params.Height}
+	leaves := []*bspNode{root}
+	for len(leaves) < target {
+		best := -1
+		for i, leaf := range leaves {
+			if leaf.splittable() && (best < 0 || leaf.w*leaf.h > leaves[best].w*leaves[best].h) {
+				best = i
+			}
+		}
+		if best < 0 {
+			break
+		}
+		leaf := leaves[best]
+		leaf.split(rng)
+		leaves = append(leaves[:best], append([]*bspNode{leaf.left, leaf.right}, leaves[best+1:]...)...)
+	}
+	if len(leaves) < minRooms {
+		return nil, fmt.Errorf("bsp: a %dx%d level only fits %d of %d rooms", params.Width, params.Height, len(leaves), minRooms)
+	}
+
+	// Put a room of random size somewhere in every leaf, leaving walls around it
+	grid := newWallGrid(params.Width, params.Height)
+	level := &GeneratedLevel{Grid: grid}
+	for _, leaf := range leaves {
+		w := bspMinRoomSize + rng.Intn(leaf.w-bspMinLeafSize+1)
+		h := bspMinRoomSize + rng.Intn(leaf.h-bspMinLeafSize+1)
+		x := leaf.x + 2 + rng.Intn(leaf.w-w-3)
+		y := leaf.y + 2 + rng.Intn(leaf.h-h-3)
+		for ry := y; ry < y+h; ry++ {
+			for rx := x; rx < x+w; rx++ {
+				grid[ry][rx] = '.'
+			}
+		}
+		leaf.room = &RoomDefinition{
+			ID:     fmt.Sprintf("room%d", len(level.Rooms)+1),
+			X:      x - 1,
+			Y:      y - 1,
+			Width:  w + 2,
+			Height: h + 2,
+		}
+		level.Rooms = append(level.Rooms, *leaf.room)
+	}
+
+	// Join the two halves of every split through their closest rooms
+	connect(root, grid)
+
+	// Start in the first room and leave from the room farthest away
+	level.StartPos = roomCenter(level.Rooms[0])
+	exit, farthest := 0, -1
+	for i, room := range level.Rooms {
+		c := roomCenter(room)
+		if d := abs(c.X-level.StartPos.X) + abs(c.Y-level.StartPos.Y); d > farthest {
+			exit, farthest = i, d
+		}
+	}
+	level.ExitPos = roomCenter(level.Rooms[exit])
+	return level, nil
+}
+
+// connect digs a corridor between the two halves of every split under a node
+func connect(n *bspNode, grid [][]rune) {
+	if n.left == nil {
+		return
+	}
+	connect(n.left, grid)
+	connect(n.right, grid)
+
+	var from, to Position
+	best := -1
+	for _, a := range n.left.rooms() {
+		for _, b := range n.right.rooms() {
+			ca, cb := roomCenter(*a), roomCenter(*b)
+			if d := abs(ca.X-cb.X) + abs(ca.Y-cb.Y); best < 0 || d < best {
+				from, to, best = ca, cb, d
+			}
+		}
+	}
+	carveCorridor(grid, from, to)
+}
+
+// abs returns the absolute value of an integer
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,192 @@
+package dungeon
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Cave tuning defaults, each can be overridden through the generator options
+const (
+	caveFill         = 0.45 // Share of tiles that start out as rock
+	caveSmoothing    = 5    // Smoothing passes
+	cavePools        = 3    // Pools of water or lava
+	caveLavaChance   = 0.3  // Chance a pool is lava rather than water
+	caveMinOpen      = 0.3  // Share of the level the cave must open up
+	caveAttempts     = 10   // Fresh starts before giving up
+	cavePoolAttempts = 20   // Tries to fit each pool without cutting the cave in two
+)
+
+func init() {
+	RegisterGenerator("cave", caveGenerator{})
+}
+
+// caveGenerator grows natural caverns with a cellular automaton. It takes the
+// options "fill", "smoothing", "pools", "lava" and "minOpen".
+type caveGenerator struct{}
+
+// Generate builds a single connected cave with pools of water and lava
+func (caveGenerator) Generate(rng *rand.Rand, params GeneratorParams) (*GeneratedLevel, error) {
+	if params.Width < 5 || params.Height < 5 {
+		return nil, fmt.Errorf("cave: a %dx%d level is too small for a cave", params.Width, params.Height)
+	}
+	fill := params.Option("fill", caveFill)
+	smoothing := int(params.Option("smoothing", caveSmoothing))
+	minOpen := params.Option("minOpen", caveMinOpen)
+
+	for attempt := 0; attempt < caveAttempts; attempt++ {
+		grid := newWallGrid(params.Width, params.Height)
+		for y := 1; y < params.Height-1; y++ {
+			for x := 1; x < params.Width-1; x++ {
+				if rng.Float64() >= fill {
+					grid[y][x] = '.'
+				}
+			}
+		}
+		for i := 0; i < smoothing; i++ {
+			grid = smoothCave(grid)
+		}
+
+		// Keep only the biggest cavern
+		start, size := largestCavern(grid)
+		if size == 0 || float64(size) < minOpen*float64((params.Width-2)*(params.Height-2)) {
+			continue
+		}
+		dist := distances(grid, start)
+		for y := range grid {
+			for x := range grid[y] {
+				if dist[y][x] < 0 {
+					grid[y][x] = '#'
+				}
+			}
+		}
+
+		addPools(rng, grid, start, int(params.Option("pools", cavePools)), params.Option("lava", caveLavaChance))
+
+		// Start in a random spot and leave from the one farthest away
+		floor := floorTiles(grid)
+		level := &GeneratedLevel{Grid: grid, StartPos: floor[rng.Intn(len(floor))]}
+		level.ExitPos = farthest(distances(grid, level.StartPos))
+		return level, nil
+	}
+	return nil, fmt.Errorf("cave: no cave opened up %.0f%% of a %dx%d level in %d attempts", minOpen*100, params.Width, params.Height, caveAttempts)
+}
+
+// smoothCave runs one step of the automaton: tiles surrounded by rock turn to
+// rock and tiles with open space around them open up
+func smoothCave(grid [][]rune) [][]rune {
+	next := newWallGrid(len(grid[0]), len(grid))
+	for y := 1; y < len(grid)-1; y++ {
+		for x := 1; x < len(grid[y])-1; x++ {
+			walls := 0
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					if (dx != 0 || dy != 0) && grid[y+dy][x+dx] == '#' {
+						walls++
+					}
+				}
+			}
+			switch {
+			case walls >= 5:
+				next[y][x] = '#'
+			case walls <= 3:
+				next[y][x] = '.'
+			default:
+				next[y][x] = grid[y][x]
+			}
+		}
+	}
+	return next
+}
+
+// largestCavern finds the biggest connected open area, returning a tile in it
+// and its size
+func largestCavern(grid [][]rune) (Position, int) {
+	seen := make([][]bool, len(grid))
+	for y := range seen {
+		seen[y] = make([]bool, len(grid[y]))
+	}
+
+	var best Position
+	bestSize := 0
+	for y := range grid {
+		for x := range grid[y] {
+			if seen[y][x] || grid[y][x] != '.' {
+				continue
+			}
+			size := 0
+			dist := distances(grid, Position{X: x, Y: y})
+			for cy := range dist {
+				for cx, d := range dist[cy] {
+					if d >= 0 {
+						seen[cy][cx] = true
+						size++
+					}
+				}
+			}
+			if size > bestSize {
+				best, bestSize = Position{X: x, Y: y}, size
+			}
+		}
+	}
+	return best, bestSize
+}
+
+// addPools floods small areas of the cave with water or lava, skipping any
+// pool that would cut part of the cave off
+func addPools(rng *rand.Rand, grid [][]rune, start Position, pools int, lavaChance float64) {
+	open := len(floorTiles(grid))
+	for i := 0; i < pools; i++ {
+		liquid := rune(RuneWater)
+		if rng.Float64() < lavaChance {
+			liquid = RuneLava
+		}
+		for attempt := 0; attempt < cavePoolAttempts; attempt++ {
+			floor := floorTiles(grid)
+			center := floor[rng.Intn(len(floor))]
+			radius := 1 + rng.Intn(2)
+
+			var flooded []Position
+			for y := center.Y - radius; y <= center.Y+radius; y++ {
+				for x := center.X - radius; x <= center.X+radius; x++ {
+					if y < 0 || y >= len(grid) || x < 0 || x >= len(grid[y]) {
+						continue
+					}
+					if abs(x-center.X)+abs(y-center.Y) <= radius && grid[y][x] == '.' && (x != start.X || y != start.Y) {
+						grid[y][x] = liquid
+						flooded = append(flooded, Position{X: x, Y: y})
+					}
+				}
+			}
+
+			// The rest of the cave must still hang together
+			reached := 0
+			for _, row := range distances(grid, start) {
+				for _, d := range row {
+					if d >= 0 {
+						reached++
+					}
+				}
+			}
+			if reached == open-len(flooded) {
+				open = reached
+				break
+			}
+			for _, p := range flooded {
+				grid[p.Y][p.X] = '.'
+			}
+		}
+	}
+}
+
+// floorTiles lists the open floor of a grid
+func floorTiles(grid [][]rune) []Position {
+	var floor []Position
+	for y := range grid {
+		for x, r := range grid[y] {
+			if r == '.' {
+				floor = append(floor, Position{X: x, Y: y})
+			}
+		}
+	}
+	return floor
+}
@@ -0,0 +1,224 @@
+package dungeon
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Unreachable is something on a level the player can't get to from the start
+type Unreachable struct {
+	Kind     string // start, exit, stairs, station, monster, item or trap
+	ID       string // The monster, item, trap or station ID, or the level stairs lead to
+	Position Position
+}
+
+func (u Unreachable) String() string {
+	if u.ID == "" {
+		return fmt.Sprintf("%s at %d,%d can't be reached", u.Kind, u.Position.X, u.Position.Y)
+	}
+	return fmt.Sprintf("%s %q at %d,%d can't be reached", u.Kind, u.ID, u.Position.X, u.Position.Y)
+}
+
+// blocksPath reports whether the player can't walk onto a layout character.
+// Stations block, but are used from the tile next to them.
+func blocksPath(r rune) bool {
+	return r == '#' || r == RuneWater || r == RuneLava || r == '&'
+}
+
+// reachableTiles flood-fills a layout from a position, through everything
+// the player can walk onto or fight their way through
+func reachableTiles(grid [][]rune, from Position) [][]bool {
+	seen := make([][]bool, len(grid))
+	for y := range grid {
+		seen[y] = make([]bool, len(grid[y]))
+	}
+	if from.Y < 0 || from.Y >= len(grid) || from.X < 0 || from.X >= len(grid[from.Y]) {
+		return seen
+	}
+	seen[from.Y][from.X] = true
+	queue := []Position{from}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, d := range []Position{{X: 0, Y: -1}, {X: 1, Y: 0}, {X: 0, Y: 1}, {X: -1, Y: 0}} {
+			n := Position{X: p.X + d.X, Y: p.Y + d.Y}
+			if n.Y < 0 || n.Y >= len(grid) || n.X < 0 || n.X >= len(grid[n.Y]) || seen[n.Y][n.X] || blocksPath(grid[n.Y][n.X]) {
+				continue
+			}
+			seen[n.Y][n.X] = true
+			queue = append(queue, n)
+		}
+	}
+	return seen
+}
+
+// canReach reports whether the player can get onto a tile, or next to it
+// for stations
+func canReach(grid [][]rune, seen [][]bool, p Position) bool {
+	if p.Y < 0 || p.Y >= len(grid) || p.X < 0 || p.X >= len(grid[p.Y]) {
+		return false
+	}
+	if grid[p.Y][p.X] != '&' {
+		return seen[p.Y][p.X]
+	}
+	for _, d := range []Position{{X: 0, Y: -1}, {X: 1, Y: 0}, {X: 0, Y: 1}, {X: -1, Y: 0}} {
+		n := Position{X: p.X + d.X, Y: p.Y + d.Y}
+		if n.Y >= 0 && n.Y < len(grid) && n.X >= 0 && n.X < len(grid[n.Y]) && seen[n.Y][n.X] {
+			return true
+		}
+	}
+	return false
+}
+
+// ConnectLevel digs the shortest tunnels it can, through rock and liquid,
+// from the part of a layout the start is in to every open area cut off from
+// it, and returns how many tunnels it dug. The level's border is left alone.
+func ConnectLevel(grid [][]rune, start Position) int {
+	tunnels := 0
+	for {
+		seen := reachableTiles(grid, start)
+		prev := map[Position]Position{}
+		var queue []Position
+		for y := range seen {
+			for x := range seen[y] {
+				if seen[y][x] {
+					p := Position{X: x, Y: y}
+					prev[p] = p
+					queue = append(queue, p)
+				}
+			}
+		}
+		if len(queue) == 0 {
+			return tunnels
+		}
+
+		dug := false
+		for len(queue) > 0 && !dug {
+			p := queue[0]
+			queue = queue[1:]
+			for _, d := range []Position{{X: 0, Y: -1}, {X: 1, Y: 0}, {X: 0, Y: 1}, {X: -1, Y: 0}} {
+				n := Position{X: p.X + d.X, Y: p.Y + d.Y}
+				if n.X < 1 || n.Y < 1 || n.Y >= len(grid)-1 || n.X >= len(grid[n.Y])-1 || grid[n.Y][n.X] == '&' {
+					continue
+				}
+				if _, ok := prev[n]; ok {
+					continue
+				}
+				prev[n] = p
+				if !blocksPath(grid[n.Y][n.X]) {
+					// Found a cut-off area, dig back to the start's
+					for c := p; !seen[c.Y][c.X]; c = prev[c] {
+						grid[c.Y][c.X] = '.'
+					}
+					dug = true
+					break
+				}
+				queue = append(queue, n)
+			}
+		}
+		if !dug {
+			return tunnels
+		}
+		tunnels++
+	}
+}
+
+// FindUnreachable checks a level built by GenerateDungeonFromDefinition and
+// returns its exit, staircases, stations, monsters, items and traps the
+// player can't get to from the level's start. A start inside a wall or a
+// pool is reported too.
+func FindUnreachable(grid [][]rune, levelDef *LevelDefinition, metadata map[string]interface{}) []Unreachable {
+	seen := reachableTiles(grid, levelDef.StartPos)
+	var unreachable []Unreachable
+	if start := levelDef.StartPos; blocksPath(grid[start.Y][start.X]) {
+		unreachable = append(unreachable, Unreachable{Kind: "start", Position: start})
+	}
+	check := func(kind, id string, p Position) {
+		if !canReach(grid, seen, p) {
+			unreachable = append(unreachable, Unreachable{Kind: kind, ID: id, Position: p})
+		}
+	}
+
+	check("exit", "", levelDef.ExitPos)
+	for _, exit := range levelDef.Exits {
+		check("exit", exit.TargetLevel, exit.Position)
+	}
+	for _, stair := range levelDef.Stairs {
+		check("stairs", stair.TargetLevel, stair.Position)
+	}
+	for _, station := range levelDef.Stations {
+		check("station", station.StationID, station.Position)
+	}
+	for _, kind := range []string{"monster", "item", "trap"} {
+		spawns, _ := metadata[kind+"s"].([]map[string]interface{})
+		for _, data := range spawns {
+			id, _ := data["id"].(string)
+			pos, _ := data["position"].(map[string]int)
+			check(kind, id, Position{X: pos["x"], Y: pos["y"]})
+		}
+	}
+	return unreachable
+}
+
+// containsPosition reports whether a position is in a list
+func containsPosition(positions []Position, p Position) bool {
+	for _, q := range positions {
+		if q == p {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateReachability checks that the start of every hand-drawn level is
+// open, and that its exits, staircases, stations, and the monsters, items
+// and traps it places at fixed positions can all be reached from there.
+// Generated levels are dug through when they are built instead, and spawns
+// placed at random aren't checked.
+func (def *DungeonDefinition) ValidateReachability() error {
+	for i, level := range def.Levels {
+		if level.Generator != nil {
+			continue
+		}
+		_, metadata, err := GenerateDungeonFromDefinition(def, i, rand.New(rand.NewSource(1)))
+		if err != nil {
+			return err
+		}
+
+		// The level as built, with the spawns its legend adds
+		built, _ := metadata["level"].(LevelDefinition)
+		fixed := []Position{built.ExitPos}
+		for _, exit := range built.Exits {
+			fixed = append(fixed, exit.Position)
+		}
+		for _, stair := range built.Stairs {
+			fixed = append(fixed, stair.Position)
+		}
+		for _, station := range built.Stations {
+			fixed = append(fixed, station.Position)
+		}
+		for _, encounter := range built.Encounters {
+			if encounter.Position != nil {
+				fixed = append(fixed, *encounter.Position)
+			}
+		}
+		for _, item := range built.Items {
+			if item.Position != nil {
+				fixed = append(fixed, *item.Position)
+			}
+		}
+		for _, trap := range built.Traps {
+			if trap.Position != nil {
+				fixed = append(fixed, *trap.Position)
+			}
+		}
+
+		unreachable, _ := metadata["unreachable"].([]Unreachable)
+		for _, u := range unreachable {
+			if u.Kind == "start" || containsPosition(fixed, u.Position) {
+				return fmt.Errorf("level %q: %s", level.ID, u)
+			}
+		}
+	}
+	return nil
+}
@@ -19,6 +19,27 @@ type DungeonDefinition struct {
 	Monsters    []MonsterTemplate  `json:"monsters"`
 	Items       []ItemTemplate     `json:"items"`
 	Events      []EventDefinition  `json:"events"`
+	Traps       []TrapTemplate     `json:"traps,omitempty"`
+	Hunger      *HungerConfig      `json:"hunger,omitempty"`
+	Endless     *EndlessConfig     `json:"endless,omitempty"`
+	Recipes     []RecipeDefinition `json:"recipes,omitempty"`
+	Stations    []StationTemplate  `json:"stations,omitempty"`
+	Vaults      []VaultDefinition  `json:"vaults,omitempty"` // Prefab rooms stamped into generated levels
+
+	Achievements []AchievementDefinition `json:"achievements,omitempty"`
+	Legend       map[string]LegendEntry  `json:"legend,omitempty"` // What layout characters stand for in every level
+}
+
+// HungerConfig enables and tunes the hunger clock for a dungeon
+type HungerConfig struct {
+	Enabled          bool `json:"enabled"`
+	StartSatiation   int  `json:"startSatiation"`
+	MaxSatiation     int  `json:"maxSatiation"`
+	DrainPerTurn     int  `json:"drainPerTurn"`
+	HungryAt         int  `json:"hungryAt"`
+	WeakAt           int  `json:"weakAt"`
+	FaintingAt       int  `json:"faintingAt"`
+	StarvationDamage int  `json:"starvationDamage"`
 }
 
 // LevelDefinition represents a single level in a dungeon
@@ -32,8 +53,34 @@ type LevelDefinition struct {
 	Rooms       []RoomDefinition   `json:"rooms"`
 	Encounters  []EncounterSpawn   `json:"encounters"`
 	Items       []ItemSpawn        `json:"items"`
+	Traps       []TrapSpawn        `json:"traps,omitempty"`
+	Stairs      []StairLink        `json:"stairs,omitempty"`
+	Exits       []ExitDefinition   `json:"exits,omitempty"` // Exits besides exitPos, which one at exitPos replaces
+	Stations    []StationSpawn     `json:"stations,omitempty"`
 	StartPos    Position           `json:"startPos"`
 	ExitPos     Position           `json:"exitPos"`
+
+	Generator *GeneratorDefinition   `json:"generator,omitempty"` // Generates the layout instead of drawing it
+	Legend    map[string]LegendEntry `json:"legend,omitempty"`    // What layout characters stand for, over the dungeon's legend
+}
+
+// Stair directions
+const (
+	StairsUp   = "up"
+	StairsDown = "down"
+)
+
+// StairLink places a staircase in a level and links it to a target level
+type StairLink struct {
+	Position    Position           `json:"position"`
+	Direction   string             `json:"direction"`
+	TargetLevel string             `json:"targetLevel"`
+}
+
+// StationSpawn places a crafting station in a level
+type StationSpawn struct {
+	StationID   string             `json:"stationId"`
+	Position    Position           `json:"position"`
 }
 
 // RoomDefinition represents a room in a level
@@ -66,6 +113,14 @@ type ItemSpawn struct {
 	Chance      float64            `json:"chance"`
 }
 
+// TrapSpawn defines where traps are set
+type TrapSpawn struct {
+	TrapID      string             `json:"trapId"`
+	Position    *Position          `json:"position"`
+	RoomID      string             `json:"roomId"`
+	Chance      float64            `json:"chance"`
+}
+
 // MonsterTemplate defines a monster type
 type MonsterTemplate struct {
 	ID          string             `json:"id"`
@@ -74,10 +129,39 @@ type MonsterTemplate struct {
 	Symbol      string             `json:"symbol"`
 	Color       string             `json:"color"`
 	Health      int                `json:"health"`
-	Damage      int                `json:"damage"`
+	Damage      Dice               `json:"damage"`
+	Accuracy    int                `json:"accuracy,omitempty"`
+	Evasion     int                `json:"evasion,omitempty"`
 	LevelScale  float64            `json:"levelScale"`
 	Abilities   []string           `json:"abilities"`
 	LootTable   []LootEntry        `json:"lootTable"`
+	Boss        bool               `json:"boss,omitempty"`
+	Phases      []BossPhase        `json:"phases,omitempty"` // Boss phases, in order of falling health
+}
+
+// Monster abilities
+const (
+	AbilityRegenerate   = "regenerate"    // Heals 1 HP every turn
+	AbilityDoubleStrike = "double_strike" // Attacks twice
+)
+
+// BossPhase changes a boss once its health falls to a threshold. Zero
+// fields keep the boss's current stats.
+type BossPhase struct {
+	Threshold   float64            `json:"threshold"` // Fraction of max health at which the phase starts
+	Name        string             `json:"name,omitempty"`
+	Message     string             `json:"message,omitempty"`
+	Damage      Dice               `json:"damage,omitempty"`
+	Accuracy    int                `json:"accuracy,omitempty"`
+	Evasion     int                `json:"evasion,omitempty"`
+	Abilities   []string           `json:"abilities,omitempty"` // Replace the template's abilities when set
+	Adds        []BossAdd          `json:"adds,omitempty"`
+}
+
+// BossAdd is a group of monsters a boss summons when a phase starts
+type BossAdd struct {
+	MonsterID   string             `json:"monsterId"`
+	Count       int                `json:"count"`
 }
 
 // ItemTemplate defines an item type
@@ -89,16 +173,81 @@ type ItemTemplate struct {
 	Color       string             `json:"color"`
 	Type        string             `json:"type"`
 	Value       int                `json:"value"`
+	Nutrition   int                `json:"nutrition,omitempty"`
 	Effects     []ItemEffect       `json:"effects"`
+
+	Unidentified bool `json:"unidentified,omitempty"` // Shown by a random appearance until identified
 }
 
+// Item classes that get random appearances while unidentified
+const (
+	ItemPotion = "potion"
+	ItemScroll = "scroll"
+)
+
+// Item effects applied when an item is used
+const (
+	EffectHeal     = "heal"
+	EffectIdentify = "identify"
+	EffectMapping  = "magic_mapping"
+	EffectPoison   = "poison"
+)
+
 // ItemEffect defines an effect that an item can have
 type ItemEffect struct {
 	Type        string             `json:"type"`
-	Value       int                `json:"value"`
+	Value       Dice               `json:"value"`
 	Duration    int                `json:"duration"`
 }
 
+// Trap effects
+const (
+	TrapDart      = "dart"
+	TrapPit       = "pit"
+	TrapTeleport  = "teleport"
+	TrapAlarm     = "alarm"
+	TrapPoisonGas = "poison_gas"
+	TrapNet       = "net"
+)
+
+// TrapTemplate defines a trap type
+type TrapTemplate struct {
+	ID           string  `json:"id"`
+	Name         string  `json:"name"`
+	Description  string  `json:"description"`
+	Symbol       string  `json:"symbol"`
+	Color        string  `json:"color"`
+	Effect       string  `json:"effect"`
+	Damage       int     `json:"damage"`
+	Duration     int     `json:"duration"`
+	Hidden       bool    `json:"hidden"`
+	DisarmChance float64 `json:"disarmChance"`
+}
+
+// RecipeDefinition defines an item the player can craft from other items
+type RecipeDefinition struct {
+	ID          string             `json:"id"`
+	Inputs      []RecipeInput      `json:"inputs"`
+	Output      string             `json:"output"`            // Item template ID of the crafted item
+	Count       int                `json:"count,omitempty"`   // Items crafted at once, 1 if unset
+	Station     string             `json:"station,omitempty"` // Station template needed next to the player, if any
+}
+
+// RecipeInput is an ingredient of a recipe
+type RecipeInput struct {
+	ItemID      string             `json:"itemId"`
+	Count       int                `json:"count"`
+}
+
+// StationTemplate defines a crafting station that can be placed in levels
+type StationTemplate struct {
+	ID          string             `json:"id"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Symbol      string             `json:"symbol"`
+	Color       string             `json:"color"`
+}
+
 // LootEntry defines an item that can be dropped by a monster
 type LootEntry struct {
 	ItemID      string             `json:"itemId"`
@@ -113,9 +262,22 @@ type EventDefinition struct {
 	Name        string             `json:"name"`
 	Description string             `json:"description"`
 	Trigger     string             `json:"trigger"`
+	Target      string             `json:"target,omitempty"` // Monster ID for boss events, any when empty
 	Actions     []EventAction      `json:"actions"`
 }
 
+// Event triggers
+const (
+	EventBossDefeated = "boss_defeated"
+)
+
+// Event actions
+const (
+	ActionMessage  = "message"
+	ActionGiveItem = "give_item"
+	ActionSetFlag  = "set_flag" // Sets the quest flag named by the target
+)
+
 // EventAction defines an action that happens during an event
 type EventAction struct {
 	Type        string             `json:"type"`
@@ -123,12 +285,42 @@ type EventAction struct {
 	Value       interface{}        `json:"value"`
 }
 
+// Achievement triggers
+const (
+	AchievementKills    = "kills"          // Kill Count monsters, or Count of the Target monster
+	AchievementGold     = "gold"           // Hold Count gold
+	AchievementDepth    = "depth"          // Reach level Count
+	AchievementWin      = "win"            // Escape the dungeon
+	AchievementFlawless = "flawless_level" // Leave Count levels without taking damage on them
+	AchievementBoss     = "boss"           // Defeat Count bosses, or the Target boss
+)
+
+// AchievementDefinition defines an achievement players can unlock
+type AchievementDefinition struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Trigger     string `json:"trigger"`
+	Count       int    `json:"count,omitempty"`
+	Target      string `json:"target,omitempty"` // Monster ID for kill and boss achievements
+}
+
 // Position represents a 2D position
 type Position struct {
 	X int `json:"x"`
 	Y int `json:"y"`
 }
 
+// LevelIndex returns the index of the level with the given ID, or -1
+func (def *DungeonDefinition) LevelIndex(id string) int {
+	for i, level := range def.Levels {
+		if level.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
 // LoadDungeonDefinition loads a dungeon definition from a file
 func LoadDungeonDefinition(path string) (*DungeonDefinition, error) {
 	data, err := ioutil.ReadFile(path)
@@ -140,6 +332,15 @@ func LoadDungeonDefinition(path string) (*DungeonDefinition, error) {
 	if err := json.Unmarshal(data, &def); err != nil {
 		return nil, fmt.Errorf("failed to parse dungeon definition: %w", err)
 	}
+	if err := def.ValidateLevelGraph(); err != nil {
+		return nil, fmt.Errorf("invalid level graph: %w", err)
+	}
+	if err := def.ValidateLegends(); err != nil {
+		return nil, fmt.Errorf("invalid legend: %w", err)
+	}
+	if err := def.ValidateReachability(); err != nil {
+		return nil, fmt.Errorf("unreachable level content: %w", err)
+	}
 
 	return &def, nil
 }
@@ -153,7 +354,7 @@ func LoadDungeonDefinitionFromDir(dir string) ([]*DungeonDefinition, error) {
 
 	var defs []*DungeonDefinition
 	for _, file := range files {
-		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" || file.Name() == HubFileName {
 			continue
 		}
 
@@ -195,6 +396,16 @@ func CreateExampleDungeon() *DungeonDefinition {
 		Description: "A dark and dangerous crypt filled with undead monsters and ancient treasures.",
 		Author:      "CryptCrawl",
 		Version:     "1.0.0",
+		Hunger: &HungerConfig{
+			Enabled:          true,
+			StartSatiation:   600,
+			MaxSatiation:     1000,
+			DrainPerTurn:     1,
+			HungryAt:         150,
+			WeakAt:           50,
+			FaintingAt:       10,
+			StarvationDamage: 1,
+		},
 		Levels: []LevelDefinition{
 			{
 				ID:          "level1",
@@ -272,6 +483,34 @@ func CreateExampleDungeon() *DungeonDefinition {
 						RoomID:   "main_hall",
 						Chance:   0.5,
 					},
+					{
+						ItemID:   "stale_bread",
+						RoomID:   "entrance",
+						Chance:   0.6,
+					},
+					{
+						ItemID:   "scroll_of_identify",
+						RoomID:   "main_hall",
+						Chance:   0.5,
+					},
+				},
+				Stations: []StationSpawn{
+					{
+						StationID: "bone_altar",
+						Position:  Position{X: 18, Y: 1},
+					},
+				},
+				Traps: []TrapSpawn{
+					{
+						TrapID: "dart_trap",
+						RoomID: "main_hall",
+						Chance: 0.7,
+					},
+					{
+						TrapID: "crypt_gas",
+						RoomID: "entrance",
+						Chance: 0.5,
+					},
 				},
 				StartPos: Position{X: 2, Y: 8},
 				ExitPos:  Position{X: 17, Y: 7},
@@ -285,7 +524,8 @@ func CreateExampleDungeon() *DungeonDefinition {
 				Symbol:      "S",
 				Color:       "#ffffff",
 				Health:      5,
-				Damage:      2,
+				Damage:      "1d3+1",
+				Accuracy:    1,
 				LevelScale:  1.5,
 				LootTable: []LootEntry{
 					{
@@ -309,7 +549,7 @@ func CreateExampleDungeon() *DungeonDefinition {
 				Symbol:      "Z",
 				Color:       "#00ff00",
 				Health:      8,
-				Damage:      1,
+				Damage:      "1",
 				LevelScale:  1.2,
 				LootTable: []LootEntry{
 					{
@@ -343,14 +583,30 @@ func CreateExampleDungeon() *DungeonDefinition {
 				Description: "A potion that restores health.",
 				Symbol:      "!",
 				Color:       "#ff0000",
-				Type:        "consumable",
+				Type:        ItemPotion,
 				Value:       10,
 				Effects: []ItemEffect{
 					{
-						Type:  "heal",
-						Value: 5,
+						Type:  EffectHeal,
+						Value: "5",
+					},
+				},
+				Unidentified: true,
+			},
+			{
+				ID:          "scroll_of_identify",
+				Name:        "Scroll of Identify",
+				Description: "Reveals the true nature of an item in your pack.",
+				Symbol:      "?",
+				Color:       "#ffffff",
+				Type:        ItemScroll,
+				Value:       20,
+				Effects: []ItemEffect{
+					{
+						Type: EffectIdentify,
 					},
 				},
+				Unidentified: true,
 			},
 			{
 				ID:          "rusty_sword",
@@ -363,7 +619,7 @@ func CreateExampleDungeon() *DungeonDefinition {
 				Effects: []ItemEffect{
 					{
 						Type:  "damage",
-						Value: 2,
+						Value: "1d4",
 					},
 				},
 			},
@@ -384,6 +640,66 @@ func CreateExampleDungeon() *DungeonDefinition {
 				Color:       "#00aa00",
 				Type:        "material",
 				Value:       1,
+				Nutrition:   150,
+			},
+			{
+				ID:          "stale_bread",
+				Name:        "Stale Bread",
+				Description: "A hard crust of bread left by some unlucky adventurer.",
+				Symbol:      "%",
+				Color:       "#ccaa66",
+				Type:        "food",
+				Value:       3,
+				Nutrition:   400,
+			},
+			{
+				ID:          "bone_broth",
+				Name:        "Bone Broth",
+				Description: "A thin, bitter broth boiled from bones. Restores a little health.",
+				Symbol:      "!",
+				Color:       "#ddddaa",
+				Type:        "consumable",
+				Value:       5,
+				Effects: []ItemEffect{
+					{
+						Type:  EffectHeal,
+						Value: "1d4+2",
+					},
+				},
+			},
+		},
+		Recipes: []RecipeDefinition{
+			{
+				ID:     "bone_broth",
+				Output: "bone_broth",
+				Inputs: []RecipeInput{
+					{ItemID: "bone_shard", Count: 2},
+					{ItemID: "rotten_flesh", Count: 1},
+				},
+				Station: "bone_altar",
+			},
+		},
+		Stations: []StationTemplate{
+			{
+				ID:          "bone_altar",
+				Name:        "Bone Altar",
+				Description: "An altar of fused bones, still warm to the touch.",
+				Symbol:      "&",
+				Color:       "#ddddaa",
+			},
+		},
+		Traps: []TrapTemplate{
+			{
+				ID:           "crypt_gas",
+				Name:         "Crypt Gas",
+				Description:  "A cracked tomb leaking foul vapours.",
+				Symbol:       "^",
+				Color:        "#66aa00",
+				Effect:       TrapPoisonGas,
+				Damage:       1,
+				Duration:     4,
+				Hidden:       true,
+				DisarmChance: 0.5,
 			},
 		},
 		Events: []EventDefinition{
@@ -420,17 +736,116 @@ func CreateExampleDungeon() *DungeonDefinition {
 				},
 			},
 		},
+		Achievements: []AchievementDefinition{
+			{
+				ID:          "bone_collector",
+				Name:        "Bone Collector",
+				Description: "Destroy 10 skeletons in the Forgotten Crypt.",
+				Trigger:     AchievementKills,
+				Count:       10,
+				Target:      "skeleton",
+			},
+		},
 	}
 }
 
-// GenerateDungeonFromDefinition generates a dungeon from a definition
-func GenerateDungeonFromDefinition(def *DungeonDefinition, level int) ([][]rune, map[string]interface{}, error) {
+// DefaultTrapTemplates returns the built-in trap types, available to every dungeon
+func DefaultTrapTemplates() []TrapTemplate {
+	return []TrapTemplate{
+		{
+			ID:           "dart_trap",
+			Name:         "Dart Trap",
+			Description:  "A hidden launcher that fires a poisoned dart.",
+			Symbol:       "^",
+			Color:        "#ff00ff",
+			Effect:       TrapDart,
+			Damage:       2,
+			Hidden:       true,
+			DisarmChance: 0.6,
+		},
+		{
+			ID:           "pit",
+			Name:         "Pit",
+			Description:  "A deep shaft that drops to the level below.",
+			Symbol:       "^",
+			Color:        "#885500",
+			Effect:       TrapPit,
+			Damage:       1,
+			Hidden:       true,
+			DisarmChance: 0.3,
+		},
+		{
+			ID:           "teleport_trap",
+			Name:         "Teleport Trap",
+			Description:  "A shimmering rune that whisks away whoever steps on it.",
+			Symbol:       "^",
+			Color:        "#aa00ff",
+			Effect:       TrapTeleport,
+			Hidden:       true,
+			DisarmChance: 0.4,
+		},
+		{
+			ID:           "alarm_trap",
+			Name:         "Alarm Trap",
+			Description:  "A tripwire tied to a rusty bell.",
+			Symbol:       "^",
+			Color:        "#ffff00",
+			Effect:       TrapAlarm,
+			Duration:     20,
+			Hidden:       true,
+			DisarmChance: 0.8,
+		},
+		{
+			ID:           "gas_trap",
+			Name:         "Poison Gas Trap",
+			Description:  "A vent that fills the air with choking fumes.",
+			Symbol:       "^",
+			Color:        "#00ff00",
+			Effect:       TrapPoisonGas,
+			Damage:       1,
+			Duration:     5,
+			Hidden:       true,
+			DisarmChance: 0.5,
+		},
+		{
+			ID:           "net_trap",
+			Name:         "Net Trap",
+			Description:  "A weighted net waiting to drop from the ceiling.",
+			Symbol:       "^",
+			Color:        "#cccccc",
+			Effect:       TrapNet,
+			Duration:     3,
+			Hidden:       false,
+			DisarmChance: 0.7,
+		},
+	}
+}
+
+// GenerateDungeonFromDefinition generates a dungeon from a definition, drawing
+// every random roll from rng so a seeded generator always yields the same level.
+// A nil rng uses a randomly seeded generator.
+func GenerateDungeonFromDefinition(def *DungeonDefinition, level int, rng *rand.Rand) ([][]rune, map[string]interface{}, error) {
 	if level < 0 || level >= len(def.Levels) {
 		return nil, nil, fmt.Errorf("invalid level index: %d", level)
 	}
+	if rng == nil {
+		rng = rand.New(rand.NewSource(rand.Int63()))
+	}
 
 	levelDef := def.Levels[level]
 	
+	// Procedural levels get their layout from a generator
+	if levelDef.Generator != nil {
+		if err := generateLevel(rng, def, &levelDef, level+1); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, p := range []Position{levelDef.StartPos, levelDef.ExitPos} {
+		if p.X < 0 || p.X >= levelDef.Width || p.Y < 0 || p.Y >= levelDef.Height {
+			return nil, nil, fmt.Errorf("level %q: position %d,%d is outside the level", levelDef.ID, p.X, p.Y)
+		}
+	}
+	
 	// Create the dungeon grid
 	dungeon := make([][]rune, levelDef.Height)
 	for i := range dungeon {
@@ -453,6 +868,52 @@ func GenerateDungeonFromDefinition(def *DungeonDefinition, level int) ([][]rune,
 		}
 	}
 	
+	// Swap in what the legend's characters stand for
+	water, lava := applyLegend(dungeon, legendFor(def, &levelDef), &levelDef, level+1)
+	
+	// Draw linked staircases
+	for _, stair := range levelDef.Stairs {
+		if stair.Position.X < 0 || stair.Position.X >= levelDef.Width || stair.Position.Y < 0 || stair.Position.Y >= levelDef.Height {
+			continue
+		}
+		if stair.Direction == StairsUp {
+			dungeon[stair.Position.Y][stair.Position.X] = '<'
+		} else {
+			dungeon[stair.Position.Y][stair.Position.X] = '>'
+		}
+	}
+	
+	// Draw the extra exits
+	for _, exit := range levelDef.Exits {
+		if exit.Position.X < 0 || exit.Position.X >= levelDef.Width || exit.Position.Y < 0 || exit.Position.Y >= levelDef.Height {
+			continue
+		}
+		dungeon[exit.Position.Y][exit.Position.X] = 'E'
+	}
+	
+	// Draw crafting stations, so nothing spawns on top of them
+	for _, station := range levelDef.Stations {
+		if station.Position.X < 0 || station.Position.X >= levelDef.Width || station.Position.Y < 0 || station.Position.Y >= levelDef.Height {
+			continue
+		}
+		dungeon[station.Position.Y][station.Position.X] = '&'
+	}
+	
+	// Keep the bare map to check what can be reached, as the monster and
+	// item symbols drawn over it could be read as walls or lava. Hand-drawn
+	// layouts only have lava where the legend puts it.
+	terrain := make([][]rune, len(dungeon))
+	for y := range dungeon {
+		terrain[y] = append([]rune(nil), dungeon[y]...)
+		if levelDef.Generator == nil {
+			for x, r := range terrain[y] {
+				if r == RuneLava && !containsPosition(lava, Position{X: x, Y: y}) {
+					terrain[y][x] = '.'
+				}
+			}
+		}
+	}
+	
 	// Create metadata for the dungeon
 	metadata := map[string]interface{}{
 		"name":        levelDef.Name,
@@ -460,8 +921,14 @@ func GenerateDungeonFromDefinition(def *DungeonDefinition, level int) ([][]rune,
 		"rooms":       levelDef.Rooms,
 		"startPos":    levelDef.StartPos,
 		"exitPos":     levelDef.ExitPos,
+		"stairs":      levelDef.Stairs,
+		"level":       levelDef,
+		"generated":   levelDef.Generator != nil,
+		"water":       water,
+		"lava":        lava,
 		"monsters":    make([]map[string]interface{}, 0),
 		"items":       make([]map[string]interface{}, 0),
+		"traps":       make([]map[string]interface{}, 0),
 	}
 	
 	// Place monsters
@@ -478,65 +945,9 @@ func GenerateDungeonFromDefinition(def *DungeonDefinition, level int) ([][]rune,
 		
 		count := encounter.Count
 		for i := 0; i < count; i++ {
-			var x, y int
-			
-			if encounter.Position != nil {
-				// Fixed position
-				x, y = encounter.Position.X, encounter.Position.Y
-			} else if encounter.RoomID != "" {
-				// Random position in a room
-				var room *RoomDefinition
-				for _, r := range levelDef.Rooms {
-					if r.ID == encounter.RoomID {
-						room = &r
-						break
-					}
-				}
-				
-				if room == nil {
-					continue
-				}
-				
-				// Find a random empty position in the room
-				attempts := 0
-				for attempts < 100 {
-					rx := rand.Intn(room.Width-2) + room.X + 1
-					ry := rand.Intn(room.Height-2) + room.Y + 1
-					
-					if rx < 0 || rx >= levelDef.Width || ry < 0 || ry >= levelDef.Height {
-						attempts++
-						continue
-					}
-					
-					if dungeon[ry][rx] == '.' {
-						x, y = rx, ry
-						break
-					}
-					
-					attempts++
-				}
-				
-				if attempts >= 100 {
-					continue
-				}
-			} else {
-				// Random position anywhere in the dungeon
-				attempts := 0
-				for attempts < 100 {
-					rx := rand.Intn(levelDef.Width)
-					ry := rand.Intn(levelDef.Height)
-					
-					if dungeon[ry][rx] == '.' {
-						x, y = rx, ry
-						break
-					}
-					
-					attempts++
-				}
-				
-				if attempts >= 100 {
-					continue
-				}
+			x, y, found := findSpawnPosition(rng, &levelDef, dungeon, encounter.Position, encounter.RoomID)
+			if !found {
+				continue
 			}
 			
 			// Place the monster
@@ -546,7 +957,7 @@ func GenerateDungeonFromDefinition(def *DungeonDefinition, level int) ([][]rune,
 				// Add monster to metadata
 				monsterLevel := encounter.MinLevel
 				if encounter.MaxLevel > encounter.MinLevel {
-					monsterLevel = encounter.MinLevel + rand.Intn(encounter.MaxLevel-encounter.MinLevel+1)
+					monsterLevel = encounter.MinLevel + rng.Intn(encounter.MaxLevel-encounter.MinLevel+1)
 				}
 				
 				monsterData := map[string]interface{}{
@@ -556,7 +967,10 @@ func GenerateDungeonFromDefinition(def *DungeonDefinition, level int) ([][]rune,
 					"symbol":      monster.Symbol,
 					"color":       monster.Color,
 					"health":      int(float64(monster.Health) * (1.0 + float64(monsterLevel-1)*monster.LevelScale)),
-					"damage":      int(float64(monster.Damage) * (1.0 + float64(monsterLevel-1)*monster.LevelScale*0.5)),
+					"damage":      int(monster.Damage.Average() * (1.0 + float64(monsterLevel-1)*monster.LevelScale*0.5)),
+					"damageDice":  string(monster.Damage.Scale(1.0 + float64(monsterLevel-1)*monster.LevelScale*0.5)),
+					"accuracy":    monster.Accuracy,
+					"evasion":     monster.Evasion,
 					"level":       monsterLevel,
 					"position":    map[string]int{"x": x, "y": y},
 				}
@@ -575,7 +989,7 @@ func GenerateDungeonFromDefinition(def *DungeonDefinition, level int) ([][]rune,
 	
 	for _, itemSpawn := range levelDef.Items {
 		// Check if the item should spawn based on chance
-		if rand.Float64() > itemSpawn.Chance {
+		if rng.Float64() > itemSpawn.Chance {
 			continue
 		}
 		
@@ -584,65 +998,9 @@ func GenerateDungeonFromDefinition(def *DungeonDefinition, level int) ([][]rune,
 			continue
 		}
 		
-		var x, y int
-		
-		if itemSpawn.Position != nil {
-			// Fixed position
-			x, y = itemSpawn.Position.X, itemSpawn.Position.Y
-		} else if itemSpawn.RoomID != "" {
-			// Random position in a room
-			var room *RoomDefinition
-			for _, r := range levelDef.Rooms {
-				if r.ID == itemSpawn.RoomID {
-					room = &r
-					break
-				}
-			}
-			
-			if room == nil {
-				continue
-			}
-			
-			// Find a random empty position in the room
-			attempts := 0
-			for attempts < 100 {
-				rx := rand.Intn(room.Width-2) + room.X + 1
-				ry := rand.Intn(room.Height-2) + room.Y + 1
-				
-				if rx < 0 || rx >= levelDef.Width || ry < 0 || ry >= levelDef.Height {
-					attempts++
-					continue
-				}
-				
-				if dungeon[ry][rx] == '.' {
-					x, y = rx, ry
-					break
-				}
-				
-				attempts++
-			}
-			
-			if attempts >= 100 {
-				continue
-			}
-		} else {
-			// Random position anywhere in the dungeon
-			attempts := 0
-			for attempts < 100 {
-				rx := rand.Intn(levelDef.Width)
-				ry := rand.Intn(levelDef.Height)
-				
-				if dungeon[ry][rx] == '.' {
-					x, y = rx, ry
-					break
-				}
-				
-				attempts++
-			}
-			
-			if attempts >= 100 {
-				continue
-			}
+		x, y, found := findSpawnPosition(rng, &levelDef, dungeon, itemSpawn.Position, itemSpawn.RoomID)
+		if !found {
+			continue
 		}
 		
 		// Place the item
@@ -666,9 +1024,102 @@ func GenerateDungeonFromDefinition(def *DungeonDefinition, level int) ([][]rune,
 		}
 	}
 	
+	// Place traps
+	trapMap := make(map[string]TrapTemplate)
+	for _, trap := range DefaultTrapTemplates() {
+		trapMap[trap.ID] = trap
+	}
+	for _, trap := range def.Traps {
+		trapMap[trap.ID] = trap
+	}
+	
+	for _, trapSpawn := range levelDef.Traps {
+		if trapSpawn.Chance > 0 && rng.Float64() > trapSpawn.Chance {
+			continue
+		}
+		
+		trap, ok := trapMap[trapSpawn.TrapID]
+		if !ok {
+			continue
+		}
+		
+		x, y, found := findSpawnPosition(rng, &levelDef, dungeon, trapSpawn.Position, trapSpawn.RoomID)
+		if !found || x < 0 || x >= levelDef.Width || y < 0 || y >= levelDef.Height {
+			continue
+		}
+		
+		if trap.Hidden {
+			dungeon[y][x] = ';'
+		} else {
+			dungeon[y][x] = '^'
+		}
+		
+		trapData := map[string]interface{}{
+			"id":       trap.ID,
+			"hidden":   trap.Hidden,
+			"position": map[string]int{"x": x, "y": y},
+		}
+		
+		traps := metadata["traps"].([]map[string]interface{})
+		metadata["traps"] = append(traps, trapData)
+	}
+	
+	// Report whatever the player can't get to
+	metadata["unreachable"] = FindUnreachable(terrain, &levelDef, metadata)
+	
 	// Place player and exit
 	dungeon[levelDef.StartPos.Y][levelDef.StartPos.X] = '@'
 	dungeon[levelDef.ExitPos.Y][levelDef.ExitPos.X] = 'E'
 	
 	return dungeon, metadata, nil
 }
+
+// findSpawnPosition picks a spawn position: the fixed position if given,
+// otherwise a random floor tile in the room or anywhere in the level
+func findSpawnPosition(rng *rand.Rand, levelDef *LevelDefinition, dungeon [][]rune, pos *Position, roomID string) (int, int, bool) {
+	if pos != nil {
+		// Fixed position
+		return pos.X, pos.Y, true
+	}
+
+	if roomID != "" {
+		// Random position in a room
+		var room *RoomDefinition
+		for i := range levelDef.Rooms {
+			if levelDef.Rooms[i].ID == roomID {
+				room = &levelDef.Rooms[i]
+				break
+			}
+		}
+
+		if room == nil || room.Width <= 2 || room.Height <= 2 {
+			return 0, 0, false
+		}
+
+		// Find a random empty position in the room
+		for attempts := 0; attempts < 100; attempts++ {
+			rx := rng.Intn(room.Width-2) + room.X + 1
+			ry := rng.Intn(room.Height-2) + room.Y + 1
+
+			if rx < 0 || rx >= levelDef.Width || ry < 0 || ry >= levelDef.Height {
+				continue
+			}
+
+			if dungeon[ry][rx] == '.' {
+				return rx, ry, true
+			}
+		}
+		return 0, 0, false
+	}
+
+	// Random position anywhere in the dungeon
+	for attempts := 0; attempts < 100; attempts++ {
+		rx := rng.Intn(levelDef.Width)
+		ry := rng.Intn(levelDef.Height)
+
+		if dungeon[ry][rx] == '.' {
+			return rx, ry, true
+		}
+	}
+	return 0, 0, false
+}
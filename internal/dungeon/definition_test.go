@@ -1,8 +1,11 @@
 package dungeon
 
 import (
+	"math/rand"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -28,6 +31,22 @@ func TestCreateExampleDungeon(t *testing.T) {
 	if len(def.Items) == 0 {
 		t.Error("Dungeon has no items")
 	}
+
+	if def.Hunger == nil || !def.Hunger.Enabled {
+		t.Error("Dungeon does not enable hunger")
+	}
+
+	hasFood := false
+	for _, item := range def.Items {
+		if item.Nutrition > 0 {
+			hasFood = true
+			break
+		}
+	}
+
+	if !hasFood {
+		t.Error("Dungeon has no food items")
+	}
 }
 
 func TestSaveAndLoadDungeonDefinition(t *testing.T) {
@@ -76,7 +95,7 @@ func TestGenerateDungeonFromDefinition(t *testing.T) {
 	def := CreateExampleDungeon()
 
 	// Generate a dungeon from the definition
-	dungeon, metadata, err := GenerateDungeonFromDefinition(def, 0)
+	dungeon, metadata, err := GenerateDungeonFromDefinition(def, 0, nil)
 	if err != nil {
 		t.Fatalf("Failed to generate dungeon: %v", err)
 	}
@@ -174,3 +193,324 @@ func TestLoadDungeonDefinitionFromDir(t *testing.T) {
 		t.Error("Dungeon 2 was not loaded")
 	}
 }
+
+func TestGenerateDungeonFromDefinitionTraps(t *testing.T) {
+	def := CreateExampleDungeon()
+	def.Levels[0].Traps = []TrapSpawn{
+		{TrapID: "crypt_gas", Position: &Position{X: 3, Y: 3}, Chance: 1},
+		{TrapID: "net_trap", Position: &Position{X: 4, Y: 4}, Chance: 1},
+		{TrapID: "unknown_trap", Position: &Position{X: 5, Y: 5}, Chance: 1},
+	}
+
+	dungeon, metadata, err := GenerateDungeonFromDefinition(def, 0, nil)
+	if err != nil {
+		t.Fatalf("Failed to generate dungeon: %v", err)
+	}
+
+	traps, ok := metadata["traps"].([]map[string]interface{})
+	if !ok {
+		t.Fatal("Metadata does not contain traps")
+	}
+
+	if len(traps) != 2 {
+		t.Fatalf("Expected 2 traps, got %d", len(traps))
+	}
+
+	// Hidden traps are drawn as ';', visible ones as '^'
+	if dungeon[3][3] != ';' {
+		t.Errorf("Expected a hidden trap at (3,3), got %q", dungeon[3][3])
+	}
+
+	if dungeon[4][4] != '^' {
+		t.Errorf("Expected a visible trap at (4,4), got %q", dungeon[4][4])
+	}
+
+	if hidden, _ := traps[0]["hidden"].(bool); !hidden {
+		t.Error("Expected the crypt gas trap to be hidden")
+	}
+}
+
+func TestGenerateDungeonFromDefinitionStairs(t *testing.T) {
+	def := CreateExampleDungeon()
+	def.Levels[0].Stairs = []StairLink{
+		{Position: Position{X: 1, Y: 1}, Direction: StairsUp, TargetLevel: "level1"},
+		{Position: Position{X: 2, Y: 1}, Direction: StairsDown, TargetLevel: "level1"},
+	}
+
+	dungeon, _, err := GenerateDungeonFromDefinition(def, 0, nil)
+	if err != nil {
+		t.Fatalf("Failed to generate dungeon: %v", err)
+	}
+
+	if dungeon[1][1] != '<' {
+		t.Errorf("Expected stairs up at (1,1), got %q", dungeon[1][1])
+	}
+
+	if dungeon[1][2] != '>' {
+		t.Errorf("Expected stairs down at (2,1), got %q", dungeon[1][2])
+	}
+}
+
+func TestGenerateDungeonFromDefinitionSeeded(t *testing.T) {
+	def := CreateExampleDungeon()
+
+	dungeon1, metadata1, err := GenerateDungeonFromDefinition(def, 0, rand.New(rand.NewSource(1234)))
+	if err != nil {
+		t.Fatalf("Failed to generate dungeon: %v", err)
+	}
+	dungeon2, metadata2, err := GenerateDungeonFromDefinition(def, 0, rand.New(rand.NewSource(1234)))
+	if err != nil {
+		t.Fatalf("Failed to generate dungeon: %v", err)
+	}
+
+	if !reflect.DeepEqual(dungeon1, dungeon2) {
+		t.Error("Expected the same seed to generate the same layout")
+	}
+	if !reflect.DeepEqual(metadata1, metadata2) {
+		t.Error("Expected the same seed to generate the same spawns")
+	}
+}
+
+func TestLevelIndex(t *testing.T) {
+	def := CreateExampleDungeon()
+
+	if i := def.LevelIndex("level1"); i != 0 {
+		t.Errorf("LevelIndex(%q) = %d, want 0", "level1", i)
+	}
+
+	if i := def.LevelIndex("missing"); i != -1 {
+		t.Errorf("LevelIndex(%q) = %d, want -1", "missing", i)
+	}
+}
+
+func TestValidateLevelGraph(t *testing.T) {
+	def := CreateExampleDungeon()
+	if err := def.ValidateLevelGraph(); err != nil {
+		t.Fatalf("Expected the example dungeon to be valid, got %v", err)
+	}
+
+	// A secret level behind a locked exit, after the last level leads out
+	def.Levels[0].Exits = []ExitDefinition{{Position: def.Levels[0].ExitPos, TargetLevel: ExitOut}}
+	secret := def.Levels[0]
+	secret.ID = "secret"
+	secret.Exits = []ExitDefinition{{Position: secret.ExitPos, TargetLevel: ExitOut}}
+	def.Levels = append(def.Levels, secret)
+	if err := def.ValidateLevelGraph(); err == nil {
+		t.Error("Expected an unreachable level to be rejected")
+	}
+	def.Levels[0].Exits = append(def.Levels[0].Exits, ExitDefinition{Position: Position{X: 1, Y: 1}, TargetLevel: "secret", RequiresFlag: "lever_pulled"})
+	if err := def.ValidateLevelGraph(); err == nil {
+		t.Error("Expected a flag no event sets to be rejected")
+	}
+	def.Events = append(def.Events, EventDefinition{ID: "lever", Trigger: EventBossDefeated, Actions: []EventAction{{Type: ActionSetFlag, Target: "lever_pulled"}}})
+	if err := def.ValidateLevelGraph(); err != nil {
+		t.Errorf("Expected the secret level to be valid, got %v", err)
+	}
+
+	def.Levels[0].Exits[1].TargetLevel = "missing"
+	if err := def.ValidateLevelGraph(); err == nil {
+		t.Error("Expected an exit to an unknown level to be rejected")
+	}
+	def.Levels[0].Exits[1].TargetLevel = "secret"
+	def.Levels[0].ID = "secret"
+	if err := def.ValidateLevelGraph(); err == nil {
+		t.Error("Expected duplicate level IDs to be rejected")
+	}
+}
+
+func TestLegend(t *testing.T) {
+	def := CreateExampleDungeon()
+	level := &def.Levels[0]
+	level.Encounters, level.Items, level.Traps = nil, nil, nil
+	level.Layout[1] = "#zp~L....#.........#"
+	def.Legend = map[string]LegendEntry{
+		"z": {Monster: "zombie"},
+		"L": {Tile: "wall"},
+	}
+	level.Legend = map[string]LegendEntry{
+		"p": {Item: "health_potion"},
+		"L": {Tile: "lava"},
+		"~": {Tile: "water"},
+	}
+	if err := def.ValidateLegends(); err != nil {
+		t.Fatalf("Expected the legend to be valid, got %v", err)
+	}
+
+	grid, metadata, err := GenerateDungeonFromDefinition(def, 0, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatalf("Failed to generate level: %v", err)
+	}
+	if grid[1][4] != RuneLava {
+		t.Errorf("Expected the level's legend to win over the dungeon's, got %q", grid[1][4])
+	}
+	if water, _ := metadata["water"].([]Position); len(water) != 1 || water[0] != (Position{X: 3, Y: 1}) {
+		t.Errorf("Expected the legend's water to be reported, got %v", metadata["water"])
+	}
+	if lava, _ := metadata["lava"].([]Position); len(lava) != 1 || lava[0] != (Position{X: 4, Y: 1}) {
+		t.Errorf("Expected the legend's lava to be reported, got %v", metadata["lava"])
+	}
+	monsters := metadata["monsters"].([]map[string]interface{})
+	if len(monsters) != 1 || monsters[0]["id"] != "zombie" || monsters[0]["position"].(map[string]int)["x"] != 1 {
+		t.Errorf("Expected a zombie where the layout puts it, got %v", monsters)
+	}
+	items := metadata["items"].([]map[string]interface{})
+	if len(items) != 1 || items[0]["id"] != "health_potion" || items[0]["position"].(map[string]int)["x"] != 2 {
+		t.Errorf("Expected a health potion where the layout puts it, got %v", items)
+	}
+	if len(def.Levels[0].Encounters) != 0 || len(def.Levels[0].Items) != 0 {
+		t.Error("Expected the legend to leave the definition alone")
+	}
+
+	// Monsters drawn deeper down are stronger
+	deeper := def.Levels[0]
+	deeper.ID = "deeper"
+	def.Levels = append(def.Levels, deeper)
+	_, metadata, err = GenerateDungeonFromDefinition(def, 1, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatalf("Failed to generate level: %v", err)
+	}
+	if monsters := metadata["monsters"].([]map[string]interface{}); len(monsters) != 1 || monsters[0]["level"] != 2 {
+		t.Errorf("Expected the zombie on the second level to be level 2, got %v", monsters)
+	}
+
+	def.Legend["z"] = LegendEntry{Monster: "dragon"}
+	if err := def.ValidateLegends(); err == nil {
+		t.Error("Expected a legend naming an unknown monster to be rejected")
+	}
+	def.Legend["z"] = LegendEntry{Tile: "quicksand"}
+	if err := def.ValidateLegends(); err == nil {
+		t.Error("Expected a legend naming an unknown tile to be rejected")
+	}
+}
+
+func TestGenerateDungeonFromDefinitionGenerator(t *testing.T) {
+	def := CreateExampleDungeon()
+	def.Levels = append(def.Levels, LevelDefinition{
+		ID:   "caves",
+		Name: "Caves",
+		Generator: &GeneratorDefinition{
+			Algorithm:      "cave",
+			Width:          40,
+			Height:         20,
+			MonsterDensity: 3,
+			Monsters:       []PoolEntry{{ID: "zombie", MinLevel: 2, MaxLevel: 2}},
+			Items:          []PoolEntry{{ID: "bone_shard"}},
+		},
+		Stairs:   []StairLink{{Direction: StairsUp, TargetLevel: "level1"}},
+		Stations: []StationSpawn{{StationID: "bone_altar"}},
+	})
+
+	grid, metadata, err := GenerateDungeonFromDefinition(def, 1, rand.New(rand.NewSource(5)))
+	if err != nil {
+		t.Fatalf("Failed to generate dungeon: %v", err)
+	}
+	if len(grid) != 20 || len(grid[0]) != 40 {
+		t.Fatalf("Expected a 40x20 level, got %dx%d", len(grid[0]), len(grid))
+	}
+	if generated, _ := metadata["generated"].(bool); !generated {
+		t.Error("Expected the level to be marked as generated")
+	}
+
+	level := metadata["level"].(LevelDefinition)
+	if grid[level.StartPos.Y][level.StartPos.X] != '@' || grid[level.ExitPos.Y][level.ExitPos.X] != 'E' {
+		t.Errorf("Expected the start and exit on the generated map")
+	}
+	if len(level.Stairs) != 1 || grid[level.Stairs[0].Position.Y][level.Stairs[0].Position.X] != '<' {
+		t.Errorf("Expected the staircase on the generated map, got %v", level.Stairs)
+	}
+	if len(level.Stations) != 1 || grid[level.Stations[0].Position.Y][level.Stations[0].Position.X] != '&' {
+		t.Errorf("Expected the station on the generated map, got %v", level.Stations)
+	}
+	if def.Levels[1].Layout != nil || def.Levels[1].Stairs[0].Position != (Position{}) {
+		t.Error("Expected generating a level to leave the definition alone")
+	}
+
+	monsters := metadata["monsters"].([]map[string]interface{})
+	if len(monsters) == 0 {
+		t.Fatal("Expected the monster pool to populate the level")
+	}
+	for _, monster := range monsters {
+		if monster["id"] != "zombie" || monster["level"] != 2 {
+			t.Errorf("Expected only level 2 zombies from the pool, got %v", monster)
+		}
+	}
+	for _, item := range metadata["items"].([]map[string]interface{}) {
+		if item["id"] != "bone_shard" {
+			t.Errorf("Expected only bone shards from the pool, got %v", item["id"])
+		}
+	}
+
+	// The same seed generates the same level
+	grid2, _, _ := GenerateDungeonFromDefinition(def, 1, rand.New(rand.NewSource(5)))
+	if !reflect.DeepEqual(grid, grid2) {
+		t.Error("Expected the same seed to generate the same level")
+	}
+
+	def.Levels[1].Generator.Algorithm = "nope"
+	if _, _, err := GenerateDungeonFromDefinition(def, 1, nil); err == nil {
+		t.Error("Expected an error for an unknown generator")
+	}
+}
+
+func TestGenerateDungeonFromDefinitionUnreachable(t *testing.T) {
+	def := CreateExampleDungeon()
+	_, metadata, err := GenerateDungeonFromDefinition(def, 0, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unreachable := metadata["unreachable"].([]Unreachable); len(unreachable) != 0 {
+		t.Errorf("Expected everything on the example level to be reachable, got %v", unreachable)
+	}
+
+	// Wall off the main hall, with the exit, the station, a potion and a
+	// zombie in it
+	level := &def.Levels[0]
+	level.Layout[3] = "#........#.........#"
+	level.Layout[7] = "#........#.......E.#"
+	level.Layout[8] = "#.S......#.........#"
+	_, metadata, _ = GenerateDungeonFromDefinition(def, 0, rand.New(rand.NewSource(1)))
+	kinds := map[string]bool{}
+	for _, u := range metadata["unreachable"].([]Unreachable) {
+		kinds[u.Kind+":"+u.ID] = true
+	}
+	for _, want := range []string{"exit:", "station:bone_altar", "item:health_potion", "monster:zombie"} {
+		if !kinds[want] {
+			t.Errorf("Expected %s to be reported unreachable, got %v", want, kinds)
+		}
+	}
+
+	level.StartPos = Position{X: 0, Y: 0}
+	_, metadata, _ = GenerateDungeonFromDefinition(def, 0, rand.New(rand.NewSource(1)))
+	if unreachable := metadata["unreachable"].([]Unreachable); len(unreachable) == 0 || unreachable[0].Kind != "start" {
+		t.Errorf("Expected a start inside a wall to be reported, got %v", unreachable)
+	}
+
+	level.ExitPos = Position{X: 40, Y: 3}
+	if _, _, err := GenerateDungeonFromDefinition(def, 0, nil); err == nil {
+		t.Error("Expected an error for an exit outside the level")
+	}
+}
+
+func TestValidateReachability(t *testing.T) {
+	def := CreateExampleDungeon()
+	if err := def.ValidateReachability(); err != nil {
+		t.Fatalf("Expected the example dungeon to be reachable, got %v", err)
+	}
+
+	// Hand-drawn levels aren't dug through, so a walled-off exit fails the dungeon
+	level := &def.Levels[0]
+	level.Layout[3] = "#........#.........#"
+	level.Layout[7] = "#........#.......E.#"
+	level.Layout[8] = "#.S......#.........#"
+	if err := def.ValidateReachability(); err == nil || !strings.Contains(err.Error(), "exit") {
+		t.Errorf("Expected the walled-off exit to be reported, got %v", err)
+	}
+
+	// % is floor in hand-drawn layouts
+	level.Layout[3] = "#........%.........#"
+	level.Layout[7] = "#........%.......E.#"
+	level.Layout[8] = "#.S......%.........#"
+	if err := def.ValidateReachability(); err != nil {
+		t.Errorf("Expected %% not to block the way, got %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package dungeon
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// Dice is a dice expression such as "2d4+1". A plain number like "3" is a
+// fixed amount, so integer values in dungeon files keep working.
+type Dice string
+
+// ParseDice parses a dice expression into its count, sides and bonus
+func ParseDice(expr string) (count, sides, bonus int, err error) {
+	s := strings.ReplaceAll(strings.ToLower(expr), " ", "")
+	if s == "" {
+		return 0, 0, 0, nil
+	}
+
+	d := strings.IndexByte(s, 'd')
+	if d < 0 {
+		bonus, err = strconv.Atoi(s)
+		if err != nil {
+			return 0, 0, 0, fmt.Errorf("invalid dice expression %q", expr)
+		}
+		return 0, 0, bonus, nil
+	}
+
+	count = 1
+	if d > 0 {
+		count, err = strconv.Atoi(s[:d])
+		if err != nil || count < 0 {
+			return 0, 0, 0, fmt.Errorf("invalid dice count in %q", expr)
+		}
+	}
+
+	rest := s[d+1:]
+	if i := strings.IndexAny(rest, "+-"); i >= 0 {
+		bonus, err = strconv.Atoi(rest[i:])
+		if err != nil {
+			return 0, 0, 0, fmt.Errorf("invalid dice bonus in %q", expr)
+		}
+		rest = rest[:i]
+	}
+
+	sides, err = strconv.Atoi(rest)
+	if err != nil || sides <= 0 {
+		return 0, 0, 0, fmt.Errorf("invalid dice sides in %q", expr)
+	}
+
+	return count, sides, bonus, nil
+}
+
+// FlatDice returns dice that always roll the given amount
+func FlatDice(n int) Dice {
+	return Dice(strconv.Itoa(n))
+}
+
+// Valid reports whether the dice expression can be parsed
+func (d Dice) Valid() bool {
+	_, _, _, err := ParseDice(string(d))
+	return err == nil
+}
+
+// Roll rolls the dice, treating invalid expressions as zero
+func (d Dice) Roll(rng *rand.Rand) int {
+	count, sides, bonus, err := ParseDice(string(d))
+	if err != nil {
+		return 0
+	}
+
+	total := bonus
+	for i := 0; i < count; i++ {
+		if rng != nil {
+			total += rng.Intn(sides) + 1
+		} else {
+			total += rand.Intn(sides) + 1
+		}
+	}
+	return total
+}
+
+// Max returns the highest possible roll
+func (d Dice) Max() int {
+	count, sides, bonus, _ := ParseDice(string(d))
+	return count*sides + bonus
+}
+
+// Average returns the average roll
+func (d Dice) Average() float64 {
+	count, sides, bonus, _ := ParseDice(string(d))
+	return float64(count)*float64(sides+1)/2 + float64(bonus)
+}
+
+// Scale returns dice whose average is multiplied by factor, by raising the bonus
+func (d Dice) Scale(factor float64) Dice {
+	count, sides, bonus, err := ParseDice(string(d))
+	if err != nil {
+		return d
+	}
+
+	avg := d.Average()
+	bonus += int(avg*factor) - int(math.Floor(avg))
+	if count == 0 {
+		return FlatDice(bonus)
+	}
+
+	switch {
+	case bonus > 0:
+		return Dice(fmt.Sprintf("%dd%d+%d", count, sides, bonus))
+	case bonus < 0:
+		return Dice(fmt.Sprintf("%dd%d%d", count, sides, bonus))
+	}
+	return Dice(fmt.Sprintf("%dd%d", count, sides))
+}
+
+// UnmarshalJSON accepts either a dice string or a plain integer
+func (d *Dice) UnmarshalJSON(data []byte) error {
+	var n int
+	if err := json.Unmarshal(data, &n); err == nil {
+		*d = FlatDice(n)
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("dice must be a number or a string like \"2d4+1\": %w", err)
+	}
+	if _, _, _, err := ParseDice(s); err != nil {
+		return err
+	}
+	*d = Dice(s)
+	return nil
+}
+
+// MarshalJSON writes fixed amounts as plain integers and everything else as a string
+func (d Dice) MarshalJSON() ([]byte, error) {
+	count, _, bonus, err := ParseDice(string(d))
+	if err == nil && count == 0 && d != "" {
+		return json.Marshal(bonus)
+	}
+	return json.Marshal(string(d))
+}
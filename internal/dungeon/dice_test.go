@@ -0,0 +1,126 @@
+package dungeon
+
+import (
+	"encoding/json"
+	"math/rand"
+	"testing"
+)
+
+func TestParseDice(t *testing.T) {
+	tests := []struct {
+		expr  string
+		count int
+		sides int
+		bonus int
+		valid bool
+	}{
+		{"2d4+1", 2, 4, 1, true},
+		{"1d6", 1, 6, 0, true},
+		{"d8", 1, 8, 0, true},
+		{"3d6-2", 3, 6, -2, true},
+		{"5", 0, 0, 5, true},
+		{"", 0, 0, 0, true},
+		{"2d", 0, 0, 0, false},
+		{"xd6", 0, 0, 0, false},
+		{"2d0", 0, 0, 0, false},
+		{"banana", 0, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			count, sides, bonus, err := ParseDice(tt.expr)
+			if (err == nil) != tt.valid {
+				t.Fatalf("ParseDice(%q) error = %v, want valid %v", tt.expr, err, tt.valid)
+			}
+			if !tt.valid {
+				return
+			}
+			if count != tt.count || sides != tt.sides || bonus != tt.bonus {
+				t.Errorf("ParseDice(%q) = %d, %d, %d, want %d, %d, %d", tt.expr, count, sides, bonus, tt.count, tt.sides, tt.bonus)
+			}
+		})
+	}
+}
+
+func TestDiceRoll(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	dice := Dice("2d4+1")
+
+	for i := 0; i < 100; i++ {
+		roll := dice.Roll(rng)
+		if roll < 3 || roll > 9 {
+			t.Fatalf("Roll() = %d, want between 3 and 9", roll)
+		}
+	}
+
+	if roll := FlatDice(4).Roll(rng); roll != 4 {
+		t.Errorf("FlatDice(4).Roll() = %d, want 4", roll)
+	}
+
+	if dice.Max() != 9 {
+		t.Errorf("Max() = %d, want 9", dice.Max())
+	}
+
+	if dice.Average() != 6 {
+		t.Errorf("Average() = %v, want 6", dice.Average())
+	}
+}
+
+func TestDiceScale(t *testing.T) {
+	tests := []struct {
+		dice     Dice
+		factor   float64
+		expected Dice
+	}{
+		{"2", 1.75, "3"},
+		{"2", 1, "2"},
+		{"1d4", 2, "1d4+3"},
+		{"2d6+1", 1, "2d6+1"},
+	}
+
+	for _, tt := range tests {
+		if scaled := tt.dice.Scale(tt.factor); scaled != tt.expected {
+			t.Errorf("Dice(%q).Scale(%v) = %q, want %q", tt.dice, tt.factor, scaled, tt.expected)
+		}
+	}
+}
+
+func TestDiceJSON(t *testing.T) {
+	var monster MonsterTemplate
+	if err := json.Unmarshal([]byte(`{"damage": 3}`), &monster); err != nil {
+		t.Fatalf("Failed to parse integer damage: %v", err)
+	}
+	if monster.Damage != "3" {
+		t.Errorf("Expected integer damage to parse as %q, got %q", "3", monster.Damage)
+	}
+
+	if err := json.Unmarshal([]byte(`{"damage": "2d4+1"}`), &monster); err != nil {
+		t.Fatalf("Failed to parse dice damage: %v", err)
+	}
+	if monster.Damage != "2d4+1" {
+		t.Errorf("Expected dice damage %q, got %q", "2d4+1", monster.Damage)
+	}
+
+	if err := json.Unmarshal([]byte(`{"damage": "lots"}`), &monster); err == nil {
+		t.Error("Expected invalid dice to fail to parse")
+	}
+
+	// Fixed amounts are written back as integers
+	data, err := json.Marshal(ItemEffect{Type: "heal", Value: FlatDice(5)})
+	if err != nil {
+		t.Fatalf("Failed to marshal item effect: %v", err)
+	}
+	if string(data) != `{"type":"heal","value":5,"duration":0}` {
+		t.Errorf("Unexpected JSON for a fixed amount: %s", data)
+	}
+
+	// Unset dice stay unset
+	var empty Dice
+	data, err = json.Marshal(empty)
+	if err != nil {
+		t.Fatalf("Failed to marshal empty dice: %v", err)
+	}
+	if err := json.Unmarshal(data, &empty); err != nil || empty != "" {
+		t.Errorf("Expected empty dice to round-trip, got %q (%v)", empty, err)
+	}
+}
@@ -0,0 +1,35 @@
+package dungeon
+
+import "math"
+
+// EndlessConfig tunes endless descent through a dungeon. Unset fields keep
+// the game's defaults.
+type EndlessConfig struct {
+	MonsterCurve  *DifficultyCurve `json:"monsterCurve,omitempty"`  // Scales monster health and damage by depth
+	LootCurve     *DifficultyCurve `json:"lootCurve,omitempty"`     // Scales the gold found by depth
+	TreasureEvery int              `json:"treasureEvery,omitempty"` // Every this many levels is a treasure vault
+	BossEvery     int              `json:"bossEvery,omitempty"`     // Every this many levels is a boss floor
+	Boss          string           `json:"boss,omitempty"`          // Monster template guarding boss floors
+}
+
+// DifficultyCurve turns a depth into a multiplier. It is 1 on the first
+// level, and for every level below that grows by Linear and is multiplied
+// by Growth, up to Max.
+type DifficultyCurve struct {
+	Linear float64 `json:"linear,omitempty"`
+	Growth float64 `json:"growth,omitempty"` // 1 if unset
+	Max    float64 `json:"max,omitempty"`    // No limit if unset
+}
+
+// At returns the curve's multiplier at a depth
+func (c DifficultyCurve) At(depth int) float64 {
+	n := float64(max(depth-1, 0))
+	scale := 1 + c.Linear*n
+	if c.Growth > 0 {
+		scale *= math.Pow(c.Growth, n)
+	}
+	if c.Max > 0 {
+		scale = min(scale, c.Max)
+	}
+	return max(scale, 0)
+}
@@ -0,0 +1,161 @@
+package dungeon
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// Generator builds procedural levels. Implementations draw every random roll
+// from the given rng, so a seeded generator always yields the same level.
+type Generator interface {
+	Generate(rng *rand.Rand, params GeneratorParams) (*GeneratedLevel, error)
+}
+
+// GeneratorParams tunes a generator
+type GeneratorParams struct {
+	Width    int
+	Height   int
+	MinRooms int                // Fewest rooms the level must have, for generators with rooms
+	MaxRooms int                // Most rooms the level may have, for generators with rooms
+	Options  map[string]float64 // Generator-specific settings
+}
+
+// Option returns a generator-specific setting, or def when it isn't set
+func (p GeneratorParams) Option(name string, def float64) float64 {
+	if value, ok := p.Options[name]; ok {
+		return value
+	}
+	return def
+}
+
+// Runes generators use for pools. Hand-drawn layouts use '~' for either.
+const (
+	RuneWater = '~'
+	RuneLava  = '%'
+)
+
+// GeneratedLevel is a procedural level. The grid uses the same runes as
+// hand-drawn layouts: '#' for walls and '.' for floor, plus RuneWater and
+// RuneLava for pools.
+type GeneratedLevel struct {
+	Grid     [][]rune
+	Rooms    []RoomDefinition // Rooms including their walls, like hand-written rooms
+	StartPos Position
+	ExitPos  Position
+}
+
+// generators holds the registered generators by name
+var generators = make(map[string]Generator)
+
+// DefaultGenerator is the generator used when none is named
+const DefaultGenerator = "bsp"
+
+// RegisterGenerator makes a generator available by name. It panics if the
+// name is taken, as that is a programming error.
+func RegisterGenerator(name string, g Generator) {
+	if _, ok := generators[name]; ok {
+		panic(fmt.Sprintf("dungeon: generator %q registered twice", name))
+	}
+	generators[name] = g
+}
+
+// GetGenerator returns the generator registered under a name. An empty name
+// returns the default generator.
+func GetGenerator(name string) (Generator, error) {
+	if name == "" {
+		name = DefaultGenerator
+	}
+	g, ok := generators[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown level generator %q", name)
+	}
+	return g, nil
+}
+
+// GeneratorNames lists the registered generators in alphabetical order
+func GeneratorNames() []string {
+	names := make([]string, 0, len(generators))
+	for name := range generators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newWallGrid creates a grid filled with walls
+func newWallGrid(width, height int) [][]rune {
+	grid := make([][]rune, height)
+	for y := range grid {
+		grid[y] = make([]rune, width)
+		for x := range grid[y] {
+			grid[y][x] = '#'
+		}
+	}
+	return grid
+}
+
+// carveCorridor digs an L-shaped corridor between two points, horizontal first
+func carveCorridor(grid [][]rune, from, to Position) {
+	for x := min(from.X, to.X); x <= max(from.X, to.X); x++ {
+		grid[from.Y][x] = '.'
+	}
+	for y := min(from.Y, to.Y); y <= max(from.Y, to.Y); y++ {
+		grid[y][to.X] = '.'
+	}
+}
+
+// roomCenter returns the middle of a room's floor
+func roomCenter(r RoomDefinition) Position {
+	return Position{X: r.X + r.Width/2, Y: r.Y + r.Height/2}
+}
+
+// passable reports whether a generated tile can be walked on
+func passable(r rune) bool {
+	return r != '#' && r != RuneWater && r != RuneLava
+}
+
+// distances flood-fills the grid from a position and returns how many steps
+// away every tile is, or -1 for tiles that can't be reached
+func distances(grid [][]rune, from Position) [][]int {
+	dist := make([][]int, len(grid))
+	for y := range grid {
+		dist[y] = make([]int, len(grid[y]))
+		for x := range dist[y] {
+			dist[y][x] = -1
+		}
+	}
+	if from.Y < 0 || from.Y >= len(grid) || from.X < 0 || from.X >= len(grid[from.Y]) || !passable(grid[from.Y][from.X]) {
+		return dist
+	}
+
+	dist[from.Y][from.X] = 0
+	queue := []Position{from}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, d := range []Position{{X: 0, Y: -1}, {X: 1, Y: 0}, {X: 0, Y: 1}, {X: -1, Y: 0}} {
+			x, y := p.X+d.X, p.Y+d.Y
+			if y < 0 || y >= len(grid) || x < 0 || x >= len(grid[y]) || dist[y][x] >= 0 || !passable(grid[y][x]) {
+				continue
+			}
+			dist[y][x] = dist[p.Y][p.X] + 1
+			queue = append(queue, Position{X: x, Y: y})
+		}
+	}
+	return dist
+}
+
+// farthest returns the reachable tile farthest from the start of a flood fill
+func farthest(dist [][]int) Position {
+	var best Position
+	most := -1
+	for y := range dist {
+		for x, d := range dist[y] {
+			if d > most {
+				best, most = Position{X: x, Y: y}, d
+			}
+		}
+	}
+	return best
+}
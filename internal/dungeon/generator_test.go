@@ -0,0 +1,335 @@
+package dungeon
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// reachable counts the floor tiles reachable from a position
+func reachable(grid [][]rune, from Position) map[Position]bool {
+	seen := map[Position]bool{from: true}
+	queue := []Position{from}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, d := range []Position{{X: 1}, {X: -1}, {Y: 1}, {Y: -1}} {
+			n := Position{X: p.X + d.X, Y: p.Y + d.Y}
+			if n.Y < 0 || n.Y >= len(grid) || n.X < 0 || n.X >= len(grid[n.Y]) || grid[n.Y][n.X] == '#' || seen[n] {
+				continue
+			}
+			seen[n] = true
+			queue = append(queue, n)
+		}
+	}
+	return seen
+}
+
+func TestGeneratorRegistry(t *testing.T) {
+	if _, err := GetGenerator("bsp"); err != nil {
+		t.Fatalf("Expected the bsp generator to be registered: %v", err)
+	}
+	if g, err := GetGenerator(""); err != nil || !reflect.DeepEqual(g, generators[DefaultGenerator]) {
+		t.Errorf("Expected an empty name to pick the default generator, got %v, %v", g, err)
+	}
+	if _, err := GetGenerator("nope"); err == nil {
+		t.Error("Expected an error for an unknown generator")
+	}
+
+	found := false
+	for _, name := range GeneratorNames() {
+		if name == "bsp" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected bsp in %v", GeneratorNames())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected registering a name twice to panic")
+		}
+	}()
+	RegisterGenerator("bsp", bspGenerator{})
+}
+
+func TestBSPGenerator(t *testing.T) {
+	g, _ := GetGenerator("bsp")
+	params := GeneratorParams{Width: 97, Height: 30, MinRooms: 6, MaxRooms: 9}
+	for seed := int64(0); seed < 50; seed++ {
+		level, err := g.Generate(rand.New(rand.NewSource(seed)), params)
+		if err != nil {
+			t.Fatalf("Seed %d: %v", seed, err)
+		}
+		if len(level.Rooms) < params.MinRooms || len(level.Rooms) > params.MaxRooms {
+			t.Errorf("Seed %d: expected 6-9 rooms, got %d", seed, len(level.Rooms))
+		}
+		if len(level.Grid) != params.Height || len(level.Grid[0]) != params.Width {
+			t.Fatalf("Seed %d: expected a 97x30 grid", seed)
+		}
+		for x := 0; x < params.Width; x++ {
+			if level.Grid[0][x] != '#' || level.Grid[params.Height-1][x] != '#' {
+				t.Fatalf("Seed %d: expected the border to stay solid", seed)
+			}
+		}
+
+		// Every room, and the exit, can be reached from the start
+		seen := reachable(level.Grid, level.StartPos)
+		if !seen[level.ExitPos] {
+			t.Errorf("Seed %d: the exit %v can't be reached from %v", seed, level.ExitPos, level.StartPos)
+		}
+		for _, room := range level.Rooms {
+			if c := roomCenter(room); !seen[c] {
+				t.Errorf("Seed %d: room %s can't be reached", seed, room.ID)
+			}
+		}
+	}
+}
+
+func TestBSPGeneratorSeeded(t *testing.T) {
+	g, _ := GetGenerator("bsp")
+	params := GeneratorParams{Width: 60, Height: 25}
+	a, _ := g.Generate(rand.New(rand.NewSource(7)), params)
+	b, _ := g.Generate(rand.New(rand.NewSource(7)), params)
+	if !reflect.DeepEqual(a, b) {
+		t.Error("Expected the same seed to generate the same level")
+	}
+}
+
+func TestBSPGeneratorTooSmall(t *testing.T) {
+	g, _ := GetGenerator("bsp")
+	if _, err := g.Generate(rand.New(rand.NewSource(1)), GeneratorParams{Width: 20, Height: 10, MinRooms: 5}); err == nil {
+		t.Error("Expected an error when the minimum room count doesn't fit")
+	}
+	level, err := g.Generate(rand.New(rand.NewSource(1)), GeneratorParams{Width: 20, Height: 10, MinRooms: 1, MaxRooms: 2})
+	if err != nil || len(level.Rooms) == 0 {
+		t.Errorf("Expected a small level to still fit a room, got %v", err)
+	}
+}
+
+func TestCaveGenerator(t *testing.T) {
+	g, err := GetGenerator("cave")
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := GeneratorParams{Width: 60, Height: 25, Options: map[string]float64{"pools": 4, "lava": 0.5}}
+	liquids := 0
+	for seed := int64(0); seed < 20; seed++ {
+		level, err := g.Generate(rand.New(rand.NewSource(seed)), params)
+		if err != nil {
+			t.Fatalf("Seed %d: %v", seed, err)
+		}
+
+		// Every open tile belongs to one cave, reachable from the start
+		seen := reachable(level.Grid, level.StartPos)
+		for y, row := range level.Grid {
+			for x, r := range row {
+				switch r {
+				case '.':
+					if !seen[Position{X: x, Y: y}] {
+						t.Fatalf("Seed %d: floor at %d,%d is cut off", seed, x, y)
+					}
+				case RuneWater, RuneLava:
+					liquids++
+				case '#':
+				default:
+					t.Fatalf("Seed %d: unexpected rune %q", seed, r)
+				}
+			}
+		}
+		if !seen[level.ExitPos] || level.ExitPos == level.StartPos {
+			t.Errorf("Seed %d: expected a reachable exit away from the start, got %v", seed, level.ExitPos)
+		}
+	}
+	if liquids == 0 {
+		t.Error("Expected some pools of water or lava")
+	}
+}
+
+func TestMazeGenerator(t *testing.T) {
+	g, err := GetGenerator("maze")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadEnds := func(grid [][]rune) int {
+		count := 0
+		for y := 1; y < len(grid)-1; y += 2 {
+			for x := 1; x < len(grid[y])-1; x += 2 {
+				if grid[y][x] == '.' && openNeighbours(grid, x, y) == 1 {
+					count++
+				}
+			}
+		}
+		return count
+	}
+
+	// A perfect maze is a tree: one path between any two cells
+	params := GeneratorParams{Width: 41, Height: 21}
+	level, err := g.Generate(rand.New(rand.NewSource(3)), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cells := 20 * 10
+	if open := len(floorTiles(level.Grid)); open != 2*cells-1 {
+		t.Errorf("Expected a perfect maze to open %d tiles, got %d", 2*cells-1, open)
+	}
+	if len(reachable(level.Grid, level.StartPos)) != 2*cells-1 {
+		t.Error("Expected every cell of the maze to be reachable")
+	}
+	if deadEnds(level.Grid) == 0 {
+		t.Error("Expected a perfect maze to have dead ends")
+	}
+
+	// The exit is as far from the start as the maze allows
+	dist := distances(level.Grid, level.StartPos)
+	for y := range dist {
+		for x := range dist[y] {
+			if dist[y][x] > dist[level.ExitPos.Y][level.ExitPos.X] {
+				t.Fatalf("Expected the exit at the farthest cell, but %d,%d is farther", x, y)
+			}
+		}
+	}
+
+	// A fully braided maze has no dead ends left
+	params.Options = map[string]float64{"braid": 1, "rooms": 3}
+	level, err = g.Generate(rand.New(rand.NewSource(3)), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := deadEnds(level.Grid); n != 0 {
+		t.Errorf("Expected no dead ends in a braided maze, got %d", n)
+	}
+	if len(level.Rooms) == 0 {
+		t.Error("Expected rooms to be carved into the maze")
+	}
+	for _, room := range level.Rooms {
+		for y := room.Y + 1; y < room.Y+room.Height-1; y++ {
+			for x := room.X + 1; x < room.X+room.Width-1; x++ {
+				if level.Grid[y][x] != '.' {
+					t.Fatalf("Expected room %s to be open at %d,%d", room.ID, x, y)
+				}
+			}
+		}
+	}
+}
+
+func TestOrientVault(t *testing.T) {
+	vault := VaultDefinition{Layout: []string{"ab", "c"}}
+	layout := orientVault(rand.New(rand.NewSource(1)), vault)
+	if got := []string{string(layout[0]), string(layout[1])}; !reflect.DeepEqual(got, []string{"ab", "c#"}) {
+		t.Errorf("Expected short rows padded with rock, got %q", got)
+	}
+
+	// Every orientation keeps the vault's characters, turned or flipped
+	vault = VaultDefinition{Layout: []string{"abc", "def"}, Rotate: true, Mirror: true}
+	seen := map[string]bool{}
+	for seed := int64(0); seed < 100; seed++ {
+		layout := orientVault(rand.New(rand.NewSource(seed)), vault)
+		key := ""
+		for _, row := range layout {
+			key += string(row) + "/"
+		}
+		seen[key] = true
+	}
+	for _, want := range []string{"abc/def/", "cba/fed/", "fed/cba/", "da/eb/fc/", "cf/be/ad/"} {
+		if !seen[want] {
+			t.Errorf("Expected the orientation %s among %v", want, seen)
+		}
+	}
+	if len(seen) != 8 {
+		t.Errorf("Expected all 8 orientations, got %d", len(seen))
+	}
+}
+
+func TestPlaceVaults(t *testing.T) {
+	g, _ := GetGenerator("bsp")
+	vault := VaultDefinition{
+		ID:     "treasury",
+		Layout: []string{"#####", "#$g.+", "#####"},
+		Legend: map[string]LegendEntry{
+			"$": {Item: "gold"},
+			"g": {Monster: "goblin"},
+		},
+		MinDepth: 2,
+		MaxDepth: 3,
+		Rotate:   true,
+		Mirror:   true,
+	}
+
+	for seed := int64(0); seed < 20; seed++ {
+		level, _ := g.Generate(rand.New(rand.NewSource(seed)), GeneratorParams{Width: 60, Height: 25})
+		rooms := len(level.Rooms)
+		spawns := placeVaults(rand.New(rand.NewSource(seed)), []VaultDefinition{vault}, 2, level)
+		if len(level.Rooms) != rooms+1 || level.Rooms[rooms].ID != "treasury" {
+			t.Fatalf("Seed %d: expected the vault to be added as a room", seed)
+		}
+		if len(spawns.encounters) != 1 || spawns.encounters[0].MonsterID != "goblin" {
+			t.Fatalf("Seed %d: expected the vault's goblin, got %v", seed, spawns.encounters)
+		}
+		if e := spawns.encounters[0]; e.MinLevel != 2 || e.MaxLevel != 2 {
+			t.Errorf("Seed %d: expected the goblin to be as strong as the depth, got levels %d-%d", seed, e.MinLevel, e.MaxLevel)
+		}
+		if len(spawns.items) != 1 || spawns.items[0].ItemID != "gold" {
+			t.Fatalf("Seed %d: expected the vault's gold, got %v", seed, spawns.items)
+		}
+		if room := level.Rooms[rooms]; len(room.Doors) != 1 || level.Grid[room.Doors[0].Y][room.Doors[0].X] != '+' {
+			t.Errorf("Seed %d: expected the vault's door, got %v", seed, room.Doors)
+		}
+
+		// The vault is tunnelled into the rest of the level
+		seen := reachable(level.Grid, level.StartPos)
+		if !seen[*spawns.items[0].Position] || !seen[*spawns.encounters[0].Position] {
+			t.Errorf("Seed %d: the vault can't be reached from the start", seed)
+		}
+	}
+
+	// Vaults stay within their depths and rarity
+	level, _ := g.Generate(rand.New(rand.NewSource(1)), GeneratorParams{Width: 60, Height: 25})
+	rooms := len(level.Rooms)
+	placeVaults(rand.New(rand.NewSource(1)), []VaultDefinition{vault}, 1, level)
+	placeVaults(rand.New(rand.NewSource(1)), []VaultDefinition{vault}, 4, level)
+	if len(level.Rooms) != rooms {
+		t.Error("Expected the vault to stay out of levels outside its depths")
+	}
+	vault.Rarity = 10
+	placed := 0
+	for seed := int64(0); seed < 100; seed++ {
+		level, _ := g.Generate(rand.New(rand.NewSource(seed)), GeneratorParams{Width: 60, Height: 25})
+		rooms := len(level.Rooms)
+		placeVaults(rand.New(rand.NewSource(seed)), []VaultDefinition{vault}, 2, level)
+		placed += len(level.Rooms) - rooms
+	}
+	if placed == 0 || placed > 30 {
+		t.Errorf("Expected a rare vault on about 1 level in 10, got %d in 100", placed)
+	}
+}
+
+func TestConnectLevel(t *testing.T) {
+	grid := [][]rune{
+		[]rune("##########"),
+		[]rune("#..#..~..#"),
+		[]rune("#..#..~..#"),
+		[]rune("####..~..#"),
+		[]rune("#..#######"),
+		[]rune("##########"),
+	}
+	start := Position{X: 1, Y: 1}
+	if n := ConnectLevel(grid, start); n != 3 {
+		t.Errorf("Expected 3 tunnels, one to each cut-off area, got %d", n)
+	}
+	seen := reachableTiles(grid, start)
+	for y, row := range grid {
+		for x, r := range row {
+			if !blocksPath(r) && !seen[y][x] {
+				t.Errorf("Expected %d,%d to be dug into", x, y)
+			}
+		}
+		if row[0] != '#' || row[len(row)-1] != '#' {
+			t.Error("Expected the border to stay solid")
+		}
+	}
+	if n := ConnectLevel(grid, start); n != 0 {
+		t.Errorf("Expected a connected level to be left alone, got %d tunnels", n)
+	}
+}
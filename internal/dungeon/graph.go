@@ -0,0 +1,148 @@
+package dungeon
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExitOut is the target of exits that lead out of the dungeon
+const ExitOut = "out"
+
+// ExitDefinition is one of a level's exits, leading to a level by ID or out
+// of the dungeon. Locked exits only open for players carrying an item or who
+// have set a quest flag.
+type ExitDefinition struct {
+	Position     Position `json:"position"`
+	TargetLevel  string   `json:"targetLevel"`            // Level ID, or ExitOut
+	RequiresItem string   `json:"requiresItem,omitempty"` // Item ID the player must carry, such as a key
+	RequiresFlag string   `json:"requiresFlag,omitempty"` // Quest flag set by an event
+	Message      string   `json:"message,omitempty"`      // Shown when the exit is locked
+}
+
+// Locked reports whether the exit needs a key or a quest flag
+func (e ExitDefinition) Locked() bool {
+	return e.RequiresItem != "" || e.RequiresFlag != ""
+}
+
+// levelEdges returns the indexes of the levels a level leads to, with
+// len(def.Levels) standing for the way out of the dungeon. The exit position
+// leads to the next level unless an exit replaces it, and staircases drawn
+// in the layout lead to the neighbouring levels.
+func (def *DungeonDefinition) levelEdges(i int) []int {
+	level := def.Levels[i]
+	out := len(def.Levels)
+	var edges []int
+
+	replaced := false
+	for _, exit := range level.Exits {
+		replaced = replaced || exit.Position == level.ExitPos
+		if exit.TargetLevel == ExitOut {
+			edges = append(edges, out)
+		} else if j := def.LevelIndex(exit.TargetLevel); j >= 0 {
+			edges = append(edges, j)
+		}
+	}
+	if !replaced {
+		edges = append(edges, i+1)
+	}
+
+	for _, link := range level.Stairs {
+		if j := def.LevelIndex(link.TargetLevel); j >= 0 {
+			edges = append(edges, j)
+		}
+	}
+	for _, row := range level.Layout {
+		if strings.ContainsRune(row, '<') && i > 0 {
+			edges = append(edges, i-1)
+		}
+		if strings.ContainsRune(row, '>') {
+			edges = append(edges, i+1)
+		}
+	}
+	return edges
+}
+
+// ValidateLevelGraph checks that the dungeon's levels fit together: level IDs
+// are unique, every exit and staircase leads to a level that exists, locks
+// name items and flags the dungeon can provide, and every level can be
+// reached from the first one, which has a way out of the dungeon.
+func (def *DungeonDefinition) ValidateLevelGraph() error {
+	if len(def.Levels) == 0 {
+		return nil
+	}
+
+	ids := make(map[string]bool)
+	for _, level := range def.Levels {
+		if level.ID == "" {
+			continue
+		}
+		if level.ID == ExitOut {
+			return fmt.Errorf("level ID %q is reserved for exits out of the dungeon", ExitOut)
+		}
+		if ids[level.ID] {
+			return fmt.Errorf("duplicate level ID %q", level.ID)
+		}
+		ids[level.ID] = true
+	}
+
+	items := make(map[string]bool)
+	for _, item := range def.Items {
+		items[item.ID] = true
+	}
+	flags := make(map[string]bool)
+	for _, event := range def.Events {
+		for _, action := range event.Actions {
+			if action.Type == ActionSetFlag {
+				flags[action.Target] = true
+			}
+		}
+	}
+
+	for _, level := range def.Levels {
+		for _, exit := range level.Exits {
+			if exit.TargetLevel != ExitOut && !ids[exit.TargetLevel] {
+				return fmt.Errorf("level %q: exit at %d,%d leads to unknown level %q", level.ID, exit.Position.X, exit.Position.Y, exit.TargetLevel)
+			}
+			if exit.RequiresItem != "" && !items[exit.RequiresItem] {
+				return fmt.Errorf("level %q: exit at %d,%d needs unknown item %q", level.ID, exit.Position.X, exit.Position.Y, exit.RequiresItem)
+			}
+			if exit.RequiresFlag != "" && !flags[exit.RequiresFlag] {
+				return fmt.Errorf("level %q: exit at %d,%d needs flag %q, which no event sets", level.ID, exit.Position.X, exit.Position.Y, exit.RequiresFlag)
+			}
+		}
+		for _, link := range level.Stairs {
+			if !ids[link.TargetLevel] {
+				return fmt.Errorf("level %q: stairs at %d,%d lead to unknown level %q", level.ID, link.Position.X, link.Position.Y, link.TargetLevel)
+			}
+		}
+	}
+
+	// Walk the level graph from the first level. Going past the last level
+	// leads out of the dungeon, through random levels if it has too few.
+	out := len(def.Levels)
+	seen := make([]bool, out+1)
+	queue := []int{0}
+	seen[0] = true
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		for _, j := range def.levelEdges(i) {
+			j = min(j, out)
+			if !seen[j] {
+				seen[j] = true
+				if j < out {
+					queue = append(queue, j)
+				}
+			}
+		}
+	}
+	for i, level := range def.Levels {
+		if !seen[i] {
+			return fmt.Errorf("level %q can't be reached from the first level", level.ID)
+		}
+	}
+	if !seen[out] {
+		return fmt.Errorf("no level leads out of the dungeon")
+	}
+	return nil
+}
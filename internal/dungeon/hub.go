@@ -0,0 +1,164 @@
+package dungeon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// HubFileName is the file in a dungeons directory that defines its hub town
+const HubFileName = "hub.json"
+
+// HubDefinition is an overworld town where a campaign starts. Each of its
+// portals leads to a dungeon, and the player comes back to the hub after
+// conquering or fleeing one.
+type HubDefinition struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Layout      []string           `json:"layout"`
+	StartPos    Position           `json:"startPos"`
+	Portals     []PortalDefinition `json:"portals"`
+	Shops       []ShopDefinition   `json:"shops,omitempty"`
+	Inns        []InnDefinition    `json:"inns,omitempty"`
+	Items       []ItemTemplate     `json:"items,omitempty"` // Wares sold by the shops
+}
+
+// PortalDefinition is a portal in the hub leading to a dungeon
+type PortalDefinition struct {
+	Dungeon  string   `json:"dungeon"` // Name of the dungeon definition
+	Position Position `json:"position"`
+}
+
+// ShopDefinition is a shop counter in the hub
+type ShopDefinition struct {
+	Name     string           `json:"name"`
+	Position Position         `json:"position"`
+	Wares    []WareDefinition `json:"wares"`
+}
+
+// WareDefinition is an item a shop sells
+type WareDefinition struct {
+	ItemID string `json:"itemId"`
+	Price  int    `json:"price"`
+}
+
+// InnDefinition is an inn in the hub where the player can rest
+type InnDefinition struct {
+	Name     string   `json:"name"`
+	Position Position `json:"position"`
+	Price    int      `json:"price"`
+}
+
+// Grid returns the hub's layout as a rectangular grid, padding short rows with walls
+func (hub *HubDefinition) Grid() [][]rune {
+	width := 0
+	for _, row := range hub.Layout {
+		width = max(width, len([]rune(row)))
+	}
+	grid := make([][]rune, len(hub.Layout))
+	for y, row := range hub.Layout {
+		grid[y] = make([]rune, width)
+		for x := range grid[y] {
+			grid[y][x] = '#'
+		}
+		copy(grid[y], []rune(row))
+	}
+	return grid
+}
+
+// Item looks up one of the hub's item templates
+func (hub *HubDefinition) Item(id string) *ItemTemplate {
+	for i := range hub.Items {
+		if hub.Items[i].ID == id {
+			return &hub.Items[i]
+		}
+	}
+	return nil
+}
+
+// Validate checks that every portal, shop and inn stands on the floor of the
+// layout, that every portal leads to one of the given dungeons and that
+// every ware is one of the hub's items
+func (hub *HubDefinition) Validate(dungeons []*DungeonDefinition) error {
+	grid := hub.Grid()
+	if len(grid) == 0 || len(grid[0]) == 0 {
+		return fmt.Errorf("hub %q has no layout", hub.Name)
+	}
+	onFloor := func(what string, p Position) error {
+		if p.Y < 0 || p.Y >= len(grid) || p.X < 0 || p.X >= len(grid[p.Y]) {
+			return fmt.Errorf("hub %q: %s at %d,%d is outside the layout", hub.Name, what, p.X, p.Y)
+		}
+		if grid[p.Y][p.X] == '#' {
+			return fmt.Errorf("hub %q: %s at %d,%d is inside a wall", hub.Name, what, p.X, p.Y)
+		}
+		return nil
+	}
+
+	if err := onFloor("start position", hub.StartPos); err != nil {
+		return err
+	}
+	if len(hub.Portals) == 0 {
+		return fmt.Errorf("hub %q has no portals", hub.Name)
+	}
+	for _, portal := range hub.Portals {
+		if err := onFloor("portal to "+portal.Dungeon, portal.Position); err != nil {
+			return err
+		}
+		found := false
+		for _, def := range dungeons {
+			if def.Name == portal.Dungeon {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("hub %q: portal leads to unknown dungeon %q", hub.Name, portal.Dungeon)
+		}
+	}
+	for _, shop := range hub.Shops {
+		if err := onFloor("shop "+shop.Name, shop.Position); err != nil {
+			return err
+		}
+		for _, ware := range shop.Wares {
+			if hub.Item(ware.ItemID) == nil {
+				return fmt.Errorf("hub %q: shop %s sells unknown item %q", hub.Name, shop.Name, ware.ItemID)
+			}
+			if ware.Price < 0 {
+				return fmt.Errorf("hub %q: shop %s sells %q at a negative price", hub.Name, shop.Name, ware.ItemID)
+			}
+		}
+	}
+	for _, inn := range hub.Inns {
+		if err := onFloor("inn "+inn.Name, inn.Position); err != nil {
+			return err
+		}
+		if inn.Price < 0 {
+			return fmt.Errorf("hub %q: inn %s has a negative price", hub.Name, inn.Name)
+		}
+	}
+	return nil
+}
+
+// LoadHubDefinition loads the hub town of a dungeons directory and checks it
+// against the dungeons loaded from there. It returns nil without an error
+// when the directory has no hub.
+func LoadHubDefinition(dir string, dungeons []*DungeonDefinition) (*HubDefinition, error) {
+	data, err := os.ReadFile(filepath.Join(dir, HubFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hub definition: %w", err)
+	}
+
+	var hub HubDefinition
+	if err := json.Unmarshal(data, &hub); err != nil {
+		return nil, fmt.Errorf("failed to parse hub definition: %w", err)
+	}
+	if err := hub.Validate(dungeons); err != nil {
+		return nil, err
+	}
+	return &hub, nil
+}
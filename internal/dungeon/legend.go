@@ -0,0 +1,171 @@
+package dungeon
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// LegendEntry says what a layout character stands for: a tile, or a
+// monster or item standing on the floor
+type LegendEntry struct {
+	Tile    string `json:"tile,omitempty"`    // Tile name such as "lava", or its layout character such as "#" or "+"
+	Monster string `json:"monster,omitempty"` // Monster template ID
+	Item    string `json:"item,omitempty"`    // Item template ID
+}
+
+// TileNames are the tile names a legend can use, with their layout characters
+var TileNames = map[string]rune{
+	"wall":        '#',
+	"floor":       '.',
+	"door":        '+',
+	"secret_door": '=',
+	"exit":        'E',
+	"stairs_up":   '<',
+	"stairs_down": '>',
+	"gold":        '$',
+	"chest":       '?',
+	"trap":        '^',
+	"hidden_trap": ';',
+	"water":       RuneWater,
+	"lava":        RuneLava,
+}
+
+// TileRune returns the layout character of the entry's tile
+func (e LegendEntry) TileRune() rune {
+	if r, ok := TileNames[e.Tile]; ok {
+		return r
+	}
+	r, _ := utf8.DecodeRuneInString(e.Tile)
+	return r
+}
+
+// place returns the tile the entry draws in place of r at a position, adding
+// the monster or item it stands for to the spawns. Monsters are as strong as
+// the depth they're found at.
+func (e LegendEntry) place(r rune, pos Position, depth int, encounters *[]EncounterSpawn, items *[]ItemSpawn) rune {
+	switch {
+	case e.Monster != "":
+		level := max(depth, 1)
+		*encounters = append(*encounters, EncounterSpawn{MonsterID: e.Monster, Count: 1, MinLevel: level, MaxLevel: level, Position: &pos})
+		return '.'
+	case e.Item != "":
+		*items = append(*items, ItemSpawn{ItemID: e.Item, Position: &pos, Chance: 1})
+		return '.'
+	case e.Tile != "":
+		return e.TileRune()
+	}
+	return r
+}
+
+// legendFor returns the legend of a level: the dungeon's legend, with the
+// level's own entries taking precedence
+func legendFor(def *DungeonDefinition, levelDef *LevelDefinition) map[string]LegendEntry {
+	if len(levelDef.Legend) == 0 {
+		return def.Legend
+	}
+	legend := make(map[string]LegendEntry, len(def.Legend)+len(levelDef.Legend))
+	for k, v := range def.Legend {
+		legend[k] = v
+	}
+	for k, v := range levelDef.Legend {
+		legend[k] = v
+	}
+	return legend
+}
+
+// applyLegend replaces the legend characters of a drawn grid with their
+// tiles, and turns those standing for monsters and items into spawns at
+// their positions, levelled for the depth. It returns the tiles the legend made water, which
+// would otherwise be taken as water or lava at random, and those it made
+// lava, which would otherwise be floor.
+func applyLegend(grid [][]rune, legend map[string]LegendEntry, levelDef *LevelDefinition, depth int) (water, lava []Position) {
+	if len(legend) == 0 {
+		return nil, nil
+	}
+
+	// Copy the spawn lists so the dungeon definition is left alone
+	levelDef.Encounters = levelDef.Encounters[:len(levelDef.Encounters):len(levelDef.Encounters)]
+	levelDef.Items = levelDef.Items[:len(levelDef.Items):len(levelDef.Items)]
+
+	for y := range grid {
+		for x, r := range grid[y] {
+			entry, ok := legend[string(r)]
+			if !ok {
+				continue
+			}
+			pos := Position{X: x, Y: y}
+			grid[y][x] = entry.place(r, pos, depth, &levelDef.Encounters, &levelDef.Items)
+			if entry.Tile == "" {
+				continue
+			}
+			switch grid[y][x] {
+			case RuneWater:
+				water = append(water, pos)
+			case RuneLava:
+				lava = append(lava, pos)
+			}
+		}
+	}
+	return water, lava
+}
+
+// validateLegend checks that every entry of a legend is keyed by a single
+// character and stands for exactly one known tile, monster or item
+func validateLegend(def *DungeonDefinition, legend map[string]LegendEntry) error {
+	for key, entry := range legend {
+		if utf8.RuneCountInString(key) != 1 {
+			return fmt.Errorf("legend key %q must be a single character", key)
+		}
+		set := 0
+		for _, field := range []string{entry.Tile, entry.Monster, entry.Item} {
+			if field != "" {
+				set++
+			}
+		}
+		if set != 1 {
+			return fmt.Errorf("legend entry %q must name exactly one tile, monster or item", key)
+		}
+
+		switch {
+		case entry.Tile != "":
+			if _, ok := TileNames[entry.Tile]; !ok && utf8.RuneCountInString(entry.Tile) != 1 {
+				return fmt.Errorf("legend entry %q: unknown tile %q", key, entry.Tile)
+			}
+		case entry.Monster != "":
+			found := false
+			for _, monster := range def.Monsters {
+				found = found || monster.ID == entry.Monster
+			}
+			if !found {
+				return fmt.Errorf("legend entry %q: unknown monster %q", key, entry.Monster)
+			}
+		case entry.Item != "":
+			found := false
+			for _, item := range def.Items {
+				found = found || item.ID == entry.Item
+			}
+			if !found {
+				return fmt.Errorf("legend entry %q: unknown item %q", key, entry.Item)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateLegends checks the dungeon's legend and those of its levels and vaults
+func (def *DungeonDefinition) ValidateLegends() error {
+	if err := validateLegend(def, def.Legend); err != nil {
+		return err
+	}
+	for _, level := range def.Levels {
+		if err := validateLegend(def, level.Legend); err != nil {
+			return fmt.Errorf("level %q: %w", level.ID, err)
+		}
+	}
+	for _, vault := range def.Vaults {
+		if err := validateLegend(def, vault.Legend); err != nil {
+			return fmt.Errorf("vault %q: %w", vault.ID, err)
+		}
+	}
+	return nil
+}
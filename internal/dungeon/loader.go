@@ -3,6 +3,7 @@ package dungeon
 import (
 	"fmt"
 	"log"
+	"math/rand"
 	"os"
 	"path/filepath"
 )
@@ -12,6 +13,7 @@ type DungeonLoader struct {
 	Dungeons     []*DungeonDefinition
 	CurrentIndex int
 	BasePath     string
+	Hub          *HubDefinition // Hub town of the dungeons, nil when they are played one at a time
 }
 
 // NewDungeonLoader creates a new dungeon loader
@@ -35,7 +37,7 @@ func NewDungeonLoader(basePath string) (*DungeonLoader, error) {
 
 	hasJsonFiles := false
 	for _, file := range files {
-		if !file.IsDir() && filepath.Ext(file.Name()) == ".json" {
+		if !file.IsDir() && filepath.Ext(file.Name()) == ".json" && file.Name() != HubFileName {
 			hasJsonFiles = true
 			break
 		}
@@ -74,7 +76,9 @@ func NewDungeonLoader(basePath string) (*DungeonLoader, error) {
 	}
 
 	// If no dungeons were found in the main directory, try loading from examples
+	dir := basePath
 	if len(dungeons) == 0 {
+		dir = examplesDir
 		dungeons, err = LoadDungeonDefinitionFromDir(examplesDir)
 		if err != nil {
 			return nil, fmt.Errorf("failed to load example dungeon definitions: %w", err)
@@ -89,9 +93,21 @@ func NewDungeonLoader(basePath string) (*DungeonLoader, error) {
 		Dungeons:     dungeons,
 		CurrentIndex: 0,
 		BasePath:     basePath,
+		Hub:          loadHub(dir, dungeons),
 	}, nil
 }
 
+// loadHub loads the hub town next to the dungeons, leaving it out with a
+// warning when it doesn't fit them
+func loadHub(dir string, dungeons []*DungeonDefinition) *HubDefinition {
+	hub, err := LoadHubDefinition(dir, dungeons)
+	if err != nil {
+		fmt.Printf("Warning: failed to load hub %s: %v\n", filepath.Join(dir, HubFileName), err)
+		return nil
+	}
+	return hub
+}
+
 // GetCurrentDungeon returns the current dungeon definition
 func (dl *DungeonLoader) GetCurrentDungeon() *DungeonDefinition {
 	if dl.CurrentIndex < 0 || dl.CurrentIndex >= len(dl.Dungeons) {
@@ -130,8 +146,10 @@ func (dl *DungeonLoader) ReloadDungeons() error {
 	}
 
 	// If no dungeons were found in the main directory, try loading from examples
+	dir := dl.BasePath
 	if len(dungeons) == 0 {
-		dungeons, err = LoadDungeonDefinitionFromDir(filepath.Join(dl.BasePath, "examples"))
+		dir = filepath.Join(dl.BasePath, "examples")
+		dungeons, err = LoadDungeonDefinitionFromDir(dir)
 		if err != nil {
 			return fmt.Errorf("failed to reload example dungeon definitions: %w", err)
 		}
@@ -143,15 +161,16 @@ func (dl *DungeonLoader) ReloadDungeons() error {
 
 	dl.Dungeons = dungeons
 	dl.CurrentIndex = 0
+	dl.Hub = loadHub(dir, dungeons)
 	return nil
 }
 
 // GenerateCurrentLevel generates a dungeon from the current definition and level
-func (dl *DungeonLoader) GenerateCurrentLevel(level int) ([][]rune, map[string]interface{}, error) {
+func (dl *DungeonLoader) GenerateCurrentLevel(level int, rng *rand.Rand) ([][]rune, map[string]interface{}, error) {
 	dungeon := dl.GetCurrentDungeon()
 	if dungeon == nil {
 		return nil, nil, fmt.Errorf("no current dungeon")
 	}
 
-	return GenerateDungeonFromDefinition(dungeon, level)
+	return GenerateDungeonFromDefinition(dungeon, level, rng)
 }
@@ -234,7 +234,7 @@ func TestGenerateCurrentLevel(t *testing.T) {
 	}
 	
 	// Generate a level
-	dungeon, metadata, err := loader.GenerateCurrentLevel(0)
+	dungeon, metadata, err := loader.GenerateCurrentLevel(0, nil)
 	if err != nil {
 		t.Fatalf("Failed to generate level: %v", err)
 	}
@@ -248,3 +248,46 @@ func TestGenerateCurrentLevel(t *testing.T) {
 		t.Error("Generated metadata is nil")
 	}
 }
+
+func TestLoadHub(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "dungeon-loader-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	def := CreateExampleDungeon()
+	if err := SaveDungeonDefinition(def, filepath.Join(tempDir, "dungeon.json")); err != nil {
+		t.Fatalf("Failed to save dungeon definition: %v", err)
+	}
+	hub := `{
+		"name": "Town",
+		"layout": ["#####", "#...#", "#...#", "#####"],
+		"startPos": {"x": 1, "y": 1},
+		"portals": [{"dungeon": "` + def.Name + `", "position": {"x": 3, "y": 2}}]
+	}`
+	if err := os.WriteFile(filepath.Join(tempDir, HubFileName), []byte(hub), 0644); err != nil {
+		t.Fatalf("Failed to write hub: %v", err)
+	}
+
+	loader, err := NewDungeonLoader(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to create dungeon loader: %v", err)
+	}
+	if len(loader.Dungeons) != 1 {
+		t.Errorf("Expected the hub not to be loaded as a dungeon, got %d dungeons", len(loader.Dungeons))
+	}
+	if loader.Hub == nil || loader.Hub.Name != "Town" {
+		t.Fatalf("Expected the hub to be loaded, got %+v", loader.Hub)
+	}
+
+	// Portals must lead somewhere
+	loader.Hub.Portals[0].Dungeon = "Nowhere"
+	if err := loader.Hub.Validate(loader.Dungeons); err == nil {
+		t.Error("Expected a portal to an unknown dungeon to be rejected")
+	}
+	loader.Hub.Portals[0] = PortalDefinition{Dungeon: def.Name, Position: Position{X: 0, Y: 0}}
+	if err := loader.Hub.Validate(loader.Dungeons); err == nil {
+		t.Error("Expected a portal inside a wall to be rejected")
+	}
+}
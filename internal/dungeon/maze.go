@@ -0,0 +1,136 @@
+package dungeon
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Maze tuning defaults, each can be overridden through the generator options
+const (
+	mazeBraid       = 0.0 // Share of dead ends knocked through into loops
+	mazeRooms       = 0   // Rooms carved into the maze
+	mazeRoomMinSize = 3
+	mazeRoomMaxSize = 7
+	mazeRoomTries   = 50 // Tries to fit each room before giving up on it
+)
+
+func init() {
+	RegisterGenerator("maze", mazeGenerator{})
+}
+
+// mazeGenerator digs a maze with a recursive backtracker. It takes the
+// options "braid", from 0 for a perfect maze to 1 for no dead ends at all,
+// and "rooms", the number of open rooms to carve into the maze.
+type mazeGenerator struct{}
+
+// mazeSteps are the moves between maze cells, which sit on odd coordinates
+var mazeSteps = []Position{{X: 0, Y: -2}, {X: 2, Y: 0}, {X: 0, Y: 2}, {X: -2, Y: 0}}
+
+// Generate builds a maze, with the exit on the cell farthest from the start
+func (mazeGenerator) Generate(rng *rand.Rand, params GeneratorParams) (*GeneratedLevel, error) {
+	if params.Width < 5 || params.Height < 5 {
+		return nil, fmt.Errorf("maze: a %dx%d level is too small for a maze", params.Width, params.Height)
+	}
+	grid := newWallGrid(params.Width, params.Height)
+	isCell := func(x, y int) bool {
+		return x > 0 && y > 0 && x < params.Width-1 && y < params.Height-1 && x%2 == 1 && y%2 == 1
+	}
+
+	// Recursive backtracker, with an explicit stack
+	start := Position{X: 1 + 2*rng.Intn((params.Width-1)/2), Y: 1 + 2*rng.Intn((params.Height-1)/2)}
+	grid[start.Y][start.X] = '.'
+	stack := []Position{start}
+	for len(stack) > 0 {
+		cell := stack[len(stack)-1]
+		var open []Position
+		for _, step := range mazeSteps {
+			x, y := cell.X+step.X, cell.Y+step.Y
+			if isCell(x, y) && grid[y][x] == '#' {
+				open = append(open, step)
+			}
+		}
+		if len(open) == 0 {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		step := open[rng.Intn(len(open))]
+		grid[cell.Y+step.Y/2][cell.X+step.X/2] = '.'
+		grid[cell.Y+step.Y][cell.X+step.X] = '.'
+		stack = append(stack, Position{X: cell.X + step.X, Y: cell.Y + step.Y})
+	}
+
+	// Knock dead ends through into a neighbouring passage
+	braid := params.Option("braid", mazeBraid)
+	for y := 1; y < params.Height-1; y += 2 {
+		for x := 1; x < params.Width-1; x += 2 {
+			if !isCell(x, y) || openNeighbours(grid, x, y) != 1 || rng.Float64() >= braid {
+				continue
+			}
+			var walls []Position
+			for _, step := range mazeSteps {
+				if isCell(x+step.X, y+step.Y) && grid[y+step.Y/2][x+step.X/2] == '#' {
+					walls = append(walls, step)
+				}
+			}
+			if len(walls) > 0 {
+				step := walls[rng.Intn(len(walls))]
+				grid[y+step.Y/2][x+step.X/2] = '.'
+			}
+		}
+	}
+
+	// Carve rooms on cell boundaries, so they line up with the passages
+	level := &GeneratedLevel{Grid: grid, StartPos: start}
+	rooms := int(params.Option("rooms", mazeRooms))
+	for i := 0; i < rooms; i++ {
+		for try := 0; try < mazeRoomTries; try++ {
+			w := mazeRoomMinSize + 2*rng.Intn((mazeRoomMaxSize-mazeRoomMinSize)/2+1)
+			h := mazeRoomMinSize + 2*rng.Intn((mazeRoomMaxSize-mazeRoomMinSize)/2+1)
+			if w > params.Width-2 || h > params.Height-2 {
+				break
+			}
+			x := 1 + 2*rng.Intn((params.Width-w)/2)
+			y := 1 + 2*rng.Intn((params.Height-h)/2)
+			if x+w > params.Width-1 || y+h > params.Height-1 || overlapsRoom(level.Rooms, x-1, y-1, w+2, h+2) {
+				continue
+			}
+			for ry := y; ry < y+h; ry++ {
+				for rx := x; rx < x+w; rx++ {
+					grid[ry][rx] = '.'
+				}
+			}
+			level.Rooms = append(level.Rooms, RoomDefinition{
+				ID:     fmt.Sprintf("room%d", len(level.Rooms)+1),
+				X:      x - 1,
+				Y:      y - 1,
+				Width:  w + 2,
+				Height: h + 2,
+			})
+			break
+		}
+	}
+
+	level.ExitPos = farthest(distances(grid, start))
+	return level, nil
+}
+
+// openNeighbours counts the open tiles next to a tile
+func openNeighbours(grid [][]rune, x, y int) int {
+	count := 0
+	for _, d := range []Position{{X: 0, Y: -1}, {X: 1, Y: 0}, {X: 0, Y: 1}, {X: -1, Y: 0}} {
+		if grid[y+d.Y][x+d.X] != '#' {
+			count++
+		}
+	}
+	return count
+}
+
+// overlapsRoom reports whether an area overlaps any of the rooms
+func overlapsRoom(rooms []RoomDefinition, x, y, w, h int) bool {
+	for _, r := range rooms {
+		if x < r.X+r.Width && x+w > r.X && y < r.Y+r.Height && y+h > r.Y {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+package dungeon
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Procedural level defaults
+const (
+	DefaultGeneratedWidth  = 60
+	DefaultGeneratedHeight = 25
+	defaultMonsterDensity  = 2.0 // Monsters per 100 floor tiles
+	defaultItemDensity     = 1.0 // Items per 100 floor tiles
+)
+
+// GeneratorDefinition asks for a level to be generated rather than drawn
+type GeneratorDefinition struct {
+	Algorithm      string             `json:"algorithm,omitempty"` // Registered generator name, bsp if empty
+	Width          int                `json:"width,omitempty"`     // The level's width if unset
+	Height         int                `json:"height,omitempty"`    // The level's height if unset
+	MinRooms       int                `json:"minRooms,omitempty"`
+	MaxRooms       int                `json:"maxRooms,omitempty"`
+	MonsterDensity float64            `json:"monsterDensity,omitempty"` // Monsters per 100 floor tiles
+	ItemDensity    float64            `json:"itemDensity,omitempty"`    // Items per 100 floor tiles
+	Monsters       []PoolEntry        `json:"monsters,omitempty"`       // Every monster but bosses if empty
+	Items          []PoolEntry        `json:"items,omitempty"`          // Every item if empty
+	Options        map[string]float64 `json:"options,omitempty"`        // Generator-specific settings
+}
+
+// PoolEntry is a monster or item that can be picked for a generated level
+type PoolEntry struct {
+	ID       string `json:"id"`                 // Monster or item template ID
+	Weight   int    `json:"weight,omitempty"`   // Relative chance of being picked, 1 if unset
+	MinLevel int    `json:"minLevel,omitempty"` // Monster level range, 1 if unset
+	MaxLevel int    `json:"maxLevel,omitempty"`
+}
+
+// generateLevel fills in a level definition's layout, rooms, start and exit
+// from its generator, stamps in the dungeon's vaults for the depth, and adds
+// the monsters and items its pools roll. Staircases and stations go on random
+// floor tiles, and anything that cuts the level off from its start is dug
+// through.
+func generateLevel(rng *rand.Rand, def *DungeonDefinition, levelDef *LevelDefinition, depth int) error {
+	spec := levelDef.Generator
+	g, err := GetGenerator(spec.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	params := GeneratorParams{
+		Width:    firstPositive(spec.Width, levelDef.Width, DefaultGeneratedWidth),
+		Height:   firstPositive(spec.Height, levelDef.Height, DefaultGeneratedHeight),
+		MinRooms: spec.MinRooms,
+		MaxRooms: spec.MaxRooms,
+		Options:  spec.Options,
+	}
+	generated, err := g.Generate(rng, params)
+	if err != nil {
+		return fmt.Errorf("failed to generate level %q: %w", levelDef.ID, err)
+	}
+
+	vaults := placeVaults(rng, def.Vaults, depth, generated)
+	grid := generated.Grid
+	grid[generated.StartPos.Y][generated.StartPos.X] = '@'
+	grid[generated.ExitPos.Y][generated.ExitPos.X] = 'E'
+
+	// Find spots for the staircases and stations the level asks for
+	floor := floorTiles(grid)
+	reserved := vaults.positions()
+	for i := 0; i < len(floor); i++ {
+		if reserved[floor[i]] {
+			floor = append(floor[:i], floor[i+1:]...)
+			i--
+		}
+	}
+	rng.Shuffle(len(floor), func(i, j int) { floor[i], floor[j] = floor[j], floor[i] })
+	take := func() (Position, bool) {
+		if len(floor) == 0 {
+			return Position{}, false
+		}
+		p := floor[0]
+		floor = floor[1:]
+		return p, true
+	}
+	stairs := levelDef.Stairs[:0:0]
+	for _, stair := range levelDef.Stairs {
+		if p, ok := take(); ok {
+			stair.Position = p
+			stairs = append(stairs, stair)
+		}
+	}
+	exits := levelDef.Exits[:0:0]
+	for _, exit := range levelDef.Exits {
+		if p, ok := take(); ok {
+			exit.Position = p
+			exits = append(exits, exit)
+		}
+	}
+	stations := levelDef.Stations[:0:0]
+	for _, station := range levelDef.Stations {
+		if p, ok := take(); ok {
+			station.Position = p
+			stations = append(stations, station)
+		}
+	}
+
+	// Dig through wherever pools, vaults or stations cut the level in two
+	for _, station := range stations {
+		grid[station.Position.Y][station.Position.X] = '&'
+	}
+	ConnectLevel(grid, generated.StartPos)
+	for _, station := range stations {
+		grid[station.Position.Y][station.Position.X] = '.'
+	}
+
+	layout := make([]string, len(grid))
+	for y := range grid {
+		layout[y] = string(grid[y])
+	}
+	levelDef.Width, levelDef.Height = params.Width, params.Height
+	levelDef.Layout = layout
+	levelDef.Rooms = generated.Rooms
+	levelDef.StartPos, levelDef.ExitPos = generated.StartPos, generated.ExitPos
+	levelDef.Stairs, levelDef.Stations = stairs, stations
+	levelDef.Exits = exits
+
+	// Roll the level's population from the pools
+	open := len(floorTiles(grid))
+	monsters := spec.Monsters
+	if len(monsters) == 0 {
+		for _, m := range def.Monsters {
+			if !m.Boss {
+				monsters = append(monsters, PoolEntry{ID: m.ID})
+			}
+		}
+	}
+	items := spec.Items
+	if len(items) == 0 {
+		for _, item := range def.Items {
+			items = append(items, PoolEntry{ID: item.ID})
+		}
+	}
+
+	encounters := append(append([]EncounterSpawn(nil), levelDef.Encounters...), vaults.encounters...)
+	for n := spawnCount(spec.MonsterDensity, defaultMonsterDensity, open); n > 0 && len(monsters) > 0; n-- {
+		entry := pickPoolEntry(rng, monsters)
+		minLevel := max(entry.MinLevel, 1)
+		encounters = append(encounters, EncounterSpawn{
+			MonsterID: entry.ID,
+			Count:     1,
+			MinLevel:  minLevel,
+			MaxLevel:  max(entry.MaxLevel, minLevel),
+		})
+	}
+	levelDef.Encounters = encounters
+
+	itemSpawns := append(append([]ItemSpawn(nil), levelDef.Items...), vaults.items...)
+	for n := spawnCount(spec.ItemDensity, defaultItemDensity, open); n > 0 && len(items) > 0; n-- {
+		itemSpawns = append(itemSpawns, ItemSpawn{ItemID: pickPoolEntry(rng, items).ID, Chance: 1})
+	}
+	levelDef.Items = itemSpawns
+	return nil
+}
+
+// spawnCount turns a density per 100 floor tiles into a number of spawns
+func spawnCount(density, def float64, floor int) int {
+	if density <= 0 {
+		density = def
+	}
+	return int(math.Round(density * float64(floor) / 100))
+}
+
+// pickPoolEntry picks a pool entry at random by weight
+func pickPoolEntry(rng *rand.Rand, pool []PoolEntry) PoolEntry {
+	total := 0
+	for _, entry := range pool {
+		total += max(entry.Weight, 1)
+	}
+	roll := rng.Intn(total)
+	for _, entry := range pool {
+		roll -= max(entry.Weight, 1)
+		if roll < 0 {
+			return entry
+		}
+	}
+	return pool[len(pool)-1]
+}
+
+// firstPositive returns the first of the values above zero
+func firstPositive(values ...int) int {
+	for _, v := range values {
+		if v > 0 {
+			return v
+		}
+	}
+	return 0
+}
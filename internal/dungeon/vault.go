@@ -0,0 +1,195 @@
+package dungeon
+
+import (
+	"math/rand"
+)
+
+// vaultAttempts is how many random spots are tried for each vault
+const vaultAttempts = 200
+
+// VaultDefinition is a small hand-made room that generators stamp into the
+// solid rock of generated levels and connect to the rest of the level
+type VaultDefinition struct {
+	ID       string                 `json:"id"`
+	Name     string                 `json:"name"`
+	Layout   []string               `json:"layout"`
+	Legend   map[string]LegendEntry `json:"legend,omitempty"`   // What layout characters stand for, beyond the usual tiles
+	MinDepth int                    `json:"minDepth,omitempty"` // Shallowest level the vault appears on, 1 if unset
+	MaxDepth int                    `json:"maxDepth,omitempty"` // Deepest level the vault appears on, any if unset
+	Rarity   int                    `json:"rarity,omitempty"`   // Appears on one eligible level in Rarity, every one if unset
+	Rotate   bool                   `json:"rotate,omitempty"`   // May be turned by quarter turns
+	Mirror   bool                   `json:"mirror,omitempty"`   // May be flipped
+}
+
+// vaultSpawns are the monsters and items a stamped vault asks for
+type vaultSpawns struct {
+	encounters []EncounterSpawn
+	items      []ItemSpawn
+}
+
+// positions returns the tiles the vault monsters and items stand on
+func (s vaultSpawns) positions() map[Position]bool {
+	taken := map[Position]bool{}
+	for _, e := range s.encounters {
+		taken[*e.Position] = true
+	}
+	for _, i := range s.items {
+		taken[*i.Position] = true
+	}
+	return taken
+}
+
+// placeVaults stamps the vaults that may appear at a depth into the solid
+// rock of a generated level, digs a tunnel from each to the rest of the
+// level, and returns the monsters and items they hold
+func placeVaults(rng *rand.Rand, vaults []VaultDefinition, depth int, level *GeneratedLevel) vaultSpawns {
+	var spawns vaultSpawns
+	for _, vault := range vaults {
+		if depth < max(vault.MinDepth, 1) || (vault.MaxDepth > 0 && depth > vault.MaxDepth) || len(vault.Layout) == 0 {
+			continue
+		}
+		if vault.Rarity > 1 && rng.Intn(vault.Rarity) != 0 {
+			continue
+		}
+
+		layout := orientVault(rng, vault)
+		h, w := len(layout), 0
+		for _, row := range layout {
+			w = max(w, len(row))
+		}
+		for attempt := 0; attempt < vaultAttempts; attempt++ {
+			x := 1 + rng.Intn(max(len(level.Grid[0])-w-1, 1))
+			y := 1 + rng.Intn(max(len(level.Grid)-h-1, 1))
+			if !solidRock(level.Grid, x-1, y-1, w+2, h+2) {
+				continue
+			}
+			stampVault(vault, layout, x, y, depth, level, &spawns)
+			break
+		}
+	}
+	return spawns
+}
+
+// orientVault turns and flips a vault's layout at random, as far as the
+// vault allows it, padding short rows with rock
+func orientVault(rng *rand.Rand, vault VaultDefinition) [][]rune {
+	w := 0
+	for _, row := range vault.Layout {
+		w = max(w, len([]rune(row)))
+	}
+	layout := make([][]rune, len(vault.Layout))
+	for y, row := range vault.Layout {
+		layout[y] = []rune(row)
+		for len(layout[y]) < w {
+			layout[y] = append(layout[y], '#')
+		}
+	}
+
+	if vault.Mirror && rng.Intn(2) == 0 {
+		for _, row := range layout {
+			for i, j := 0, len(row)-1; i < j; i, j = i+1, j-1 {
+				row[i], row[j] = row[j], row[i]
+			}
+		}
+	}
+	if vault.Rotate {
+		for turns := rng.Intn(4); turns > 0; turns-- {
+			turned := make([][]rune, len(layout[0]))
+			for x := range turned {
+				turned[x] = make([]rune, len(layout))
+				for y := range layout {
+					turned[x][len(layout)-1-y] = layout[y][x]
+				}
+			}
+			layout = turned
+		}
+	}
+	return layout
+}
+
+// solidRock reports whether an area lies inside the grid and is all wall
+func solidRock(grid [][]rune, x, y, w, h int) bool {
+	if x < 0 || y < 0 || y+h > len(grid) || x+w > len(grid[0]) {
+		return false
+	}
+	for ry := y; ry < y+h; ry++ {
+		for rx := x; rx < x+w; rx++ {
+			if grid[ry][rx] != '#' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// stampVault draws a vault into the level at x, y, adds it as a room and
+// connects it to the level
+func stampVault(vault VaultDefinition, layout [][]rune, x, y, depth int, level *GeneratedLevel, spawns *vaultSpawns) {
+	room := RoomDefinition{ID: vault.ID, Name: vault.Name, X: x - 1, Y: y - 1, Width: len(layout[0]) + 2, Height: len(layout) + 2}
+	var entrance, fallback *Position
+	for vy, row := range layout {
+		for vx, r := range row {
+			pos := Position{X: x + vx, Y: y + vy}
+			if entry, ok := vault.Legend[string(r)]; ok {
+				r = entry.place(r, pos, depth, &spawns.encounters, &spawns.items)
+			}
+			level.Grid[pos.Y][pos.X] = r
+			if r == '+' {
+				room.Doors = append(room.Doors, pos)
+			}
+
+			// Tunnel out from an opening on the vault's edge, or any floor if there is none
+			if !passable(r) {
+				continue
+			}
+			if vx == 0 || vy == 0 || vx == len(row)-1 || vy == len(layout)-1 {
+				if entrance == nil {
+					entrance = &pos
+				}
+			} else if fallback == nil {
+				fallback = &pos
+			}
+		}
+	}
+	if entrance == nil {
+		entrance = fallback
+	}
+	level.Rooms = append(level.Rooms, room)
+	if entrance != nil {
+		digTunnel(level.Grid, *entrance, room)
+	}
+}
+
+// digTunnel digs the shortest tunnel through rock from a vault's entrance to
+// the nearest open tile outside the vault
+func digTunnel(grid [][]rune, from Position, room RoomDefinition) {
+	inside := func(p Position) bool {
+		return p.X > room.X && p.X < room.X+room.Width-1 && p.Y > room.Y && p.Y < room.Y+room.Height-1
+	}
+	prev := map[Position]Position{from: from}
+	queue := []Position{from}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, d := range []Position{{X: 0, Y: -1}, {X: 1, Y: 0}, {X: 0, Y: 1}, {X: -1, Y: 0}} {
+			n := Position{X: p.X + d.X, Y: p.Y + d.Y}
+			if n.X < 1 || n.Y < 1 || n.Y >= len(grid)-1 || n.X >= len(grid[n.Y])-1 {
+				continue
+			}
+			if _, seen := prev[n]; seen {
+				continue
+			}
+			prev[n] = p
+			switch {
+			case grid[n.Y][n.X] == '#':
+				queue = append(queue, n)
+			case passable(grid[n.Y][n.X]) && !inside(n):
+				// Reached the level, dig back to the vault
+				for c := p; c != from; c = prev[c] {
+					grid[c.Y][c.X] = '.'
+				}
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"cryptcrawl/internal/dungeon"
+)
+
+// ItemInstance represents an item lying in the dungeon or carried by the player
+type ItemInstance struct {
+	Pos      Position
+	Template dungeon.ItemTemplate
+	Count    int
+}
+
+// Name returns the display name of the item, including the stack size
+func (it ItemInstance) Name() string {
+	if it.Count > 1 {
+		return fmt.Sprintf("%d x %s", it.Count, it.Template.Name)
+	}
+	return it.Template.Name
+}
+
+// itemTemplate looks up an item template in the loaded dungeon definition
+func (m model) itemTemplate(id string) *dungeon.ItemTemplate {
+	if m.def == nil {
+		return nil
+	}
+	for i := range m.def.Items {
+		if m.def.Items[i].ID == id {
+			return &m.def.Items[i]
+		}
+	}
+	return nil
+}
+
+// monsterTemplate looks up a monster template in the loaded dungeon
+// definition, falling back to the built-in Warden of the Deep
+func (m model) monsterTemplate(id string) *dungeon.MonsterTemplate {
+	if m.def != nil {
+		for i := range m.def.Monsters {
+			if m.def.Monsters[i].ID == id {
+				return &m.def.Monsters[i]
+			}
+		}
+	}
+	if id == wardenTemplate.ID {
+		warden := wardenTemplate
+		return &warden
+	}
+	return nil
+}
+
+// placeItem puts an item on the floor at its position
+func (m *model) placeItem(item ItemInstance) {
+	if !m.inBounds(item.Pos.X, item.Pos.Y) {
+		return
+	}
+	m.items = append(m.items, item)
+	m.dungeon[item.Pos.Y][item.Pos.X] = Item
+}
+
+// pickUpItems moves every item at the given position into the inventory
+func (m *model) pickUpItems(x, y int) {
+	remaining := m.items[:0]
+	for _, item := range m.items {
+		if item.Pos.X != x || item.Pos.Y != y {
+			remaining = append(remaining, item)
+			continue
+		}
+
+		if item.Template.Type == "currency" {
+			amount := item.Template.Value * item.Count
+			m.gold += amount
+			m.addMessage(fmt.Sprintf("You found %d gold!", amount))
+			continue
+		}
+
+		m.addToInventory(item)
+		m.addMessage(fmt.Sprintf("You pick up %s.", m.itemName(item)))
+	}
+	m.items = remaining
+}
+
+// addToInventory adds an item to the inventory, stacking it with identical items
+func (m *model) addToInventory(item ItemInstance) {
+	item.Pos = Position{}
+	for i := range m.inventory {
+		if m.inventory[i].Template.ID == item.Template.ID {
+			m.inventory[i].Count += item.Count
+			return
+		}
+	}
+	m.inventory = append(m.inventory, item)
+}
+
+// removeFromInventory takes one item out of the inventory slot at index
+func (m *model) removeFromInventory(index int) {
+	m.inventory[index].Count--
+	if m.inventory[index].Count <= 0 {
+		m.inventory = append(m.inventory[:index], m.inventory[index+1:]...)
+	}
+}
+
+// dropLoot rolls a dead monster's loot table and drops the results on its tile
+func (m *model) dropLoot(monster Entity) {
+	template := m.monsterTemplate(monster.TemplateID)
+	if template == nil {
+		return
+	}
+
+	for _, entry := range template.LootTable {
+		if m.rng.Float64() > entry.Chance {
+			continue
+		}
+		item := m.itemTemplate(entry.ItemID)
+		if item == nil {
+			continue
+		}
+
+		count := entry.MinCount
+		if entry.MaxCount > entry.MinCount {
+			count += m.rng.Intn(entry.MaxCount - entry.MinCount + 1)
+		}
+		if count <= 0 {
+			continue
+		}
+
+		m.placeItem(ItemInstance{Pos: monster.Pos, Template: *item, Count: count})
+	}
+}
+
+// renderItemAt renders the topmost item at the given position
+func (m model) renderItemAt(x, y int) string {
+	for i := len(m.items) - 1; i >= 0; i-- {
+		item := m.items[i]
+		if item.Pos.X != x || item.Pos.Y != y || item.Template.Symbol == "" {
+			continue
+		}
+		style := lipgloss.NewStyle()
+		if item.Template.Color != "" {
+			style = style.Foreground(lipgloss.Color(item.Template.Color))
+		}
+		return style.Render(string([]rune(item.Template.Symbol)[0]))
+	}
+	return RenderTile(Item)
+}
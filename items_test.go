@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+
+	"cryptcrawl/internal/dungeon"
+)
+
+func TestPickUpItems(t *testing.T) {
+	m := newDefinitionModel(t, dungeon.CreateExampleDungeon())
+	m.items = nil
+	m.inventory = nil
+
+	pos := Position{X: m.player.Pos.X + 1, Y: m.player.Pos.Y}
+	m.dungeon[pos.Y][pos.X] = Empty
+	m.placeItem(ItemInstance{Pos: pos, Template: *m.itemTemplate("rotten_flesh"), Count: 2})
+	m.placeItem(ItemInstance{Pos: pos, Template: *m.itemTemplate("gold"), Count: 5})
+
+	if m.dungeon[pos.Y][pos.X] != Item {
+		t.Fatalf("Expected an item tile at %v", pos)
+	}
+
+	gold := m.gold
+	m.pickUpItems(pos.X, pos.Y)
+
+	if len(m.items) != 0 {
+		t.Errorf("Expected no items left on the floor, got %d", len(m.items))
+	}
+	if m.gold != gold+5 {
+		t.Errorf("Expected gold to increase by 5, got %d", m.gold-gold)
+	}
+	if len(m.inventory) != 1 || m.inventory[0].Count != 2 {
+		t.Errorf("Expected 2 rotten flesh in the inventory, got %v", m.inventory)
+	}
+
+	// Identical items stack
+	m.addToInventory(ItemInstance{Template: *m.itemTemplate("rotten_flesh"), Count: 1})
+	if len(m.inventory) != 1 || m.inventory[0].Count != 3 {
+		t.Errorf("Expected items to stack, got %v", m.inventory)
+	}
+}
+
+func TestDropLoot(t *testing.T) {
+	def := dungeon.CreateExampleDungeon()
+	def.Monsters[0].LootTable = []dungeon.LootEntry{{ItemID: "bone_shard", Chance: 1, MinCount: 2, MaxCount: 2}}
+	m := newDefinitionModel(t, def)
+	m.items = nil
+
+	monster := Entity{Pos: Position{X: 3, Y: 3}, TemplateID: def.Monsters[0].ID}
+	m.dungeon[3][3] = Empty
+	m.dropLoot(monster)
+
+	if len(m.items) != 1 {
+		t.Fatalf("Expected 1 dropped item, got %d", len(m.items))
+	}
+	if m.items[0].Template.ID != "bone_shard" || m.items[0].Count != 2 {
+		t.Errorf("Expected 2 bone shards, got %s", m.items[0].Name())
+	}
+	if m.dungeon[3][3] != Item {
+		t.Error("Expected the loot to be visible on the map")
+	}
+}
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Scoring weights
+const (
+	scorePerGold   = 1
+	scorePerDepth  = 100
+	scorePerKill   = 25
+	scoreForEscape = 500
+	turnsPerPoint  = 10 // Every this many turns costs a point
+)
+
+// Leaderboard settings
+const (
+	leaderboardMaxEntries = 500 // Entries kept per dungeon
+	leaderboardSize       = 15  // Entries shown on the leaderboard screen
+	randomDungeonName     = "Random dungeon"
+)
+
+// Global high-score leaderboard
+var leaderboard *leaderboardStore
+
+// score rates a run from its gold, depth, kills and turns
+func score(gold, depth, kills, turns int, won bool) int {
+	s := gold*scorePerGold + depth*scorePerDepth + kills*scorePerKill - turns/turnsPerPoint
+	if won {
+		s += scoreForEscape
+	}
+	return max(s, 0)
+}
+
+// scoreEntry is one finished run on the leaderboard
+type scoreEntry struct {
+	Key     string    `json:"key,omitempty"`
+	Name    string    `json:"name"`
+	Dungeon string    `json:"dungeon"`
+	Score   int       `json:"score"`
+	Gold    int       `json:"gold"`
+	Depth   int       `json:"depth"`
+	Kills   int       `json:"kills"`
+	Turns   int       `json:"turns"`
+	Won     bool      `json:"won"`
+	Seed    int64     `json:"seed"`
+	Date    time.Time `json:"date"`
+}
+
+// dungeonName returns the leaderboard name of the dungeon being played.
+// Endless descents have boards of their own, and campaigns share the board
+// of their hub town.
+func (m model) dungeonName() string {
+	name := randomDungeonName
+	switch {
+	case m.hub != nil:
+		name = m.hub.Name
+	case m.def != nil:
+		name = m.def.Name
+	}
+	if m.endless {
+		name += endlessSuffix
+	}
+	return name
+}
+
+// totalKills returns how many monsters the player has killed
+func (m model) totalKills() int {
+	total := 0
+	for _, count := range m.kills {
+		total += count
+	}
+	return total
+}
+
+// scoreEntry returns the leaderboard entry for the run
+func (m model) scoreEntry() scoreEntry {
+	return scoreEntry{
+		Key:     m.playerKey,
+		Name:    m.playerName,
+		Dungeon: m.dungeonName(),
+		Score:   score(m.gold, m.deepestLevel(), m.totalKills(), m.turns, m.gameWon),
+		Gold:    m.gold,
+		Depth:   m.deepestLevel(),
+		Kills:   m.totalKills(),
+		Turns:   m.turns,
+		Won:     m.gameWon,
+		Seed:    m.seed,
+		Date:    time.Now().UTC(),
+	}
+}
+
+// submitScore puts a finished run on the leaderboard. Daily challenges have
+// their own leaderboard.
+func (m *model) submitScore() {
+	if leaderboard == nil || m.dailyDate != "" {
+		return
+	}
+	rank, err := leaderboard.Add(m.scoreEntry())
+	if err != nil {
+		m.addMessage(fmt.Sprintf("Could not record your score: %v", err))
+		return
+	}
+	m.scoreRank = rank
+}
+
+// scoreView shows the run's score on the game over screen
+func (m model) scoreView() string {
+	if m.dailyDate != "" {
+		return ""
+	}
+	e := m.scoreEntry()
+	if m.scoreRank > 0 {
+		return fmt.Sprintf("  Score: %d (#%d on the %s leaderboard)\n", e.Score, m.scoreRank, e.Dungeon)
+	}
+	return fmt.Sprintf("  Score: %d\n", e.Score)
+}
+
+// leaderboardStore keeps every dungeon's high scores in one file
+type leaderboardStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// newLeaderboardStore creates a leaderboard stored in the given file
+func newLeaderboardStore(path string) (*leaderboardStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create leaderboard directory: %w", err)
+	}
+	return &leaderboardStore{path: path}, nil
+}
+
+// load reads every entry; the caller must hold the lock
+func (s *leaderboardStore) load() ([]scoreEntry, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read leaderboard: %w", err)
+	}
+
+	var entries []scoreEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse leaderboard: %w", err)
+	}
+	return entries, nil
+}
+
+// save writes every entry; the caller must hold the lock
+func (s *leaderboardStore) save(entries []scoreEntry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode leaderboard: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a half-written board
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write leaderboard: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// scoreLess reports whether run a ranks above run b: higher scores first,
+// then earlier runs
+func scoreLess(a, b scoreEntry) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	return a.Date.Before(b.Date)
+}
+
+// sortScores orders entries from best to worst
+func sortScores(entries []scoreEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return scoreLess(entries[i], entries[j])
+	})
+}
+
+// Add records a run and returns its rank on its dungeon's leaderboard
+func (s *leaderboardStore) Add(entry scoreEntry) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return 0, err
+	}
+
+	// Insert the run after every run ranking at least as high
+	sortScores(entries)
+	index := sort.Search(len(entries), func(i int) bool {
+		return scoreLess(entry, entries[i])
+	})
+	entries = append(entries, scoreEntry{})
+	copy(entries[index+1:], entries[index:])
+	entries[index] = entry
+
+	// Keep only the best runs of each dungeon
+	rank := 0
+	kept := entries[:0]
+	perDungeon := make(map[string]int)
+	for i, e := range entries {
+		perDungeon[e.Dungeon]++
+		if perDungeon[e.Dungeon] > leaderboardMaxEntries {
+			continue
+		}
+		kept = append(kept, e)
+		if i == index {
+			rank = perDungeon[e.Dungeon]
+		}
+	}
+
+	if err := s.save(kept); err != nil {
+		return 0, err
+	}
+	return rank, nil
+}
+
+// Entries returns the best runs of a dungeon, or of every dungeon when the name is empty
+func (s *leaderboardStore) Entries(dungeonName string) ([]scoreEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	matching := entries[:0]
+	for _, e := range entries {
+		if dungeonName == "" || e.Dungeon == dungeonName {
+			matching = append(matching, e)
+		}
+	}
+	sortScores(matching)
+	return matching, nil
+}
+
+// leaderboardKeys are the leaderboard screen's key bindings
+var leaderboardKeys = struct {
+	Next key.Binding
+	Prev key.Binding
+	Back key.Binding
+	Quit key.Binding
+}{
+	Next: key.NewBinding(key.WithKeys("right", "l", "tab"), key.WithHelp("→", "next board")),
+	Prev: key.NewBinding(key.WithKeys("left", "h", "shift+tab"), key.WithHelp("←", "previous board")),
+	Back: key.NewBinding(key.WithKeys("esc", "b"), key.WithHelp("esc", "back")),
+	Quit: key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "quit")),
+}
+
+// leaderboardModel shows the global and per-dungeon leaderboards
+type leaderboardModel struct {
+	parent  tea.Model // Screen to go back to, if any
+	key     string    // Highlights this player's runs
+	boards  []string  // "" for the global board, then dungeon names
+	index   int
+	entries []scoreEntry // Every run, best first, read when the screen opens
+	err     error        // Why the leaderboard couldn't be read
+}
+
+// newLeaderboardModel creates a leaderboard screen that starts on the given
+// dungeon's board, or the global one when the name is empty
+func newLeaderboardModel(parent tea.Model, key, dungeonName string) leaderboardModel {
+	lm := leaderboardModel{parent: parent, key: key, boards: []string{""}}
+	if leaderboard != nil {
+		lm.entries, lm.err = leaderboard.Entries("")
+	}
+	seen := make(map[string]bool)
+	for _, e := range lm.entries {
+		if !seen[e.Dungeon] {
+			seen[e.Dungeon] = true
+			lm.boards = append(lm.boards, e.Dungeon)
+		}
+	}
+	sort.Strings(lm.boards[1:])
+	for i, name := range lm.boards {
+		if name == dungeonName {
+			lm.index = i
+		}
+	}
+	return lm
+}
+
+// Init initializes the leaderboard screen
+func (lm leaderboardModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update switches boards and leaves the screen
+func (lm leaderboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case key.Matches(msg, leaderboardKeys.Quit):
+			return lm, tea.Quit
+		case key.Matches(msg, leaderboardKeys.Back):
+			if lm.parent != nil {
+				return lm.parent, nil
+			}
+			return lm, tea.Quit
+		case key.Matches(msg, leaderboardKeys.Next):
+			lm.index = (lm.index + 1) % len(lm.boards)
+		case key.Matches(msg, leaderboardKeys.Prev):
+			lm.index = (lm.index + len(lm.boards) - 1) % len(lm.boards)
+		}
+	}
+	return lm, nil
+}
+
+// View renders the current board
+func (lm leaderboardModel) View() string {
+	title := lipgloss.NewStyle().Bold(true)
+	highlight := lipgloss.NewStyle().Foreground(lipgloss.Color("#ffff00"))
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("#888888"))
+
+	board := lm.boards[lm.index]
+	name := board
+	if name == "" {
+		name = "All dungeons"
+	}
+
+	var b strings.Builder
+	b.WriteString("\n  " + title.Render("HIGH SCORES: "+name) + "\n\n")
+
+	var entries []scoreEntry
+	for _, e := range lm.entries {
+		if board == "" || e.Dungeon == board {
+			entries = append(entries, e)
+		}
+	}
+	switch {
+	case lm.err != nil:
+		fmt.Fprintf(&b, "  Leaderboard unavailable: %v\n", lm.err)
+	case len(entries) == 0:
+		b.WriteString("  No scores yet. Be the first!\n")
+	default:
+		fmt.Fprintf(&b, "  %4s  %-16s %6s %6s %5s %5s %6s  %s\n", "Rank", "Player", "Score", "Gold", "Depth", "Kills", "Turns", "Result")
+		for i, e := range entries {
+			if i >= leaderboardSize {
+				break
+			}
+			result := "died"
+			if e.Won {
+				result = "escaped"
+			}
+			if board == "" {
+				result += " in " + e.Dungeon
+			}
+			line := fmt.Sprintf("%4d. %-16s %6d %6d %5d %5d %6d  %s", i+1, e.Name, e.Score, e.Gold, e.Depth, e.Kills, e.Turns, result)
+			if lm.key != "" && e.Key == lm.key {
+				line = highlight.Render(line)
+			}
+			b.WriteString("  " + line + "\n")
+		}
+	}
+
+	help := "← → switch board • q quit"
+	if lm.parent != nil {
+		help = "← → switch board • esc back • q quit"
+	}
+	fmt.Fprintf(&b, "\n  %s\n", dim.Render(fmt.Sprintf("Board %d/%d • %s", lm.index+1, len(lm.boards), help)))
+	return b.String()
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestScore(t *testing.T) {
+	if got := score(50, 3, 4, 200, false); got != 50+300+100-20 {
+		t.Errorf("Expected gold, depth and kills minus turns, got %d", got)
+	}
+	if got := score(50, 3, 4, 200, true); got != 50+300+100-20+scoreForEscape {
+		t.Errorf("Expected a bonus for escaping, got %d", got)
+	}
+	if got := score(0, 0, 0, 100000, false); got != 0 {
+		t.Errorf("Expected scores never to go below zero, got %d", got)
+	}
+}
+
+func TestLeaderboardStore(t *testing.T) {
+	store, err := newLeaderboardStore(filepath.Join(t.TempDir(), "leaderboard.json"))
+	if err != nil {
+		t.Fatalf("Failed to create leaderboard: %v", err)
+	}
+
+	runs := []scoreEntry{
+		{Key: "a", Name: "alice", Dungeon: "Crypt", Score: 300},
+		{Key: "b", Name: "bob", Dungeon: "Crypt", Score: 500},
+		{Key: "c", Name: "carol", Dungeon: randomDungeonName, Score: 400},
+	}
+	var ranks []int
+	for _, run := range runs {
+		rank, err := store.Add(run)
+		if err != nil {
+			t.Fatalf("Failed to add score: %v", err)
+		}
+		ranks = append(ranks, rank)
+	}
+	if want := []int{1, 1, 1}; !reflect.DeepEqual(ranks, want) {
+		t.Errorf("Expected ranks %v within each dungeon, got %v", want, ranks)
+	}
+
+	names := func(dungeonName string) []string {
+		entries, err := store.Entries(dungeonName)
+		if err != nil {
+			t.Fatalf("Failed to read entries: %v", err)
+		}
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name)
+		}
+		return names
+	}
+	if want := []string{"bob", "carol", "alice"}; !reflect.DeepEqual(names(""), want) {
+		t.Errorf("Expected global ranking %v, got %v", want, names(""))
+	}
+	if want := []string{"bob", "alice"}; !reflect.DeepEqual(names("Crypt"), want) {
+		t.Errorf("Expected Crypt ranking %v, got %v", want, names("Crypt"))
+	}
+
+	// A run tied with an earlier one ranks below it, even when identical
+	rank, err := store.Add(runs[0])
+	if err != nil {
+		t.Fatalf("Failed to add score: %v", err)
+	}
+	if rank != 3 {
+		t.Errorf("Expected a tied run to rank 3rd, got %d", rank)
+	}
+
+	leaderboard = store
+	defer func() { leaderboard = nil }()
+	lm := newLeaderboardModel(nil, "", "Crypt")
+	if want := []string{"", "Crypt", randomDungeonName}; !reflect.DeepEqual(lm.boards, want) {
+		t.Errorf("Expected boards %v, got %v", want, lm.boards)
+	}
+	if lm.boards[lm.index] != "Crypt" {
+		t.Errorf("Expected the screen to start on the Crypt board, got %q", lm.boards[lm.index])
+	}
+}
+
+func TestFinishRunSubmitsScore(t *testing.T) {
+	store, err := newLeaderboardStore(filepath.Join(t.TempDir(), "leaderboard.json"))
+	if err != nil {
+		t.Fatalf("Failed to create leaderboard: %v", err)
+	}
+	leaderboard = store
+	defer func() { leaderboard = nil }()
+
+	m := newModel(1)
+	m.playerName = "alice"
+	m.gold = 42
+	m.kills = map[string]int{"rat": 2}
+	m.gameOver = true
+	m.finishRun()
+
+	if m.scoreRank != 1 {
+		t.Errorf("Expected the run to be ranked first, got %d", m.scoreRank)
+	}
+	entries, _ := store.Entries("")
+	if len(entries) != 1 || entries[0].Gold != 42 || entries[0].Kills != 2 {
+		t.Errorf("Expected the run on the leaderboard, got %+v", entries)
+	}
+}
+
+func TestTitleMenu(t *testing.T) {
+	title := newTitleModel("", "alice", &sessionGame{})
+	for _, option := range title.options {
+		if option == titleContinue || option == titleDaily {
+			t.Errorf("Expected no %s option without a save or key", option)
+		}
+	}
+
+	// Moving up from the first entry wraps around to Quit
+	next, _ := title.Update(tea.KeyMsg{Type: tea.KeyUp})
+	title = next.(titleModel)
+	if title.options[title.cursor] != titleQuit {
+		t.Errorf("Expected the cursor to wrap to Quit, got %s", title.options[title.cursor])
+	}
+
+	title.cursor = 0
+	next, _ = title.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	game, ok := next.(model)
+	if !ok {
+		t.Fatalf("Expected New game to start a game, got %T", next)
+	}
+	if game.playerName != "alice" || game.session == nil || !game.session.started {
+		t.Error("Expected the game to belong to the player's session")
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"cryptcrawl/internal/dungeon"
+)
+
+func TestTravelRestoresLevels(t *testing.T) {
+	m := initialModel()
+	m.revealMap = true
+	before := m.dungeonToString()
+	monsters := append([]Entity(nil), m.monsters...)
+	start := m.player.Pos
+
+	m.descend()
+	if m.level != 2 {
+		t.Fatalf("Expected to be on level 2, got %d", m.level)
+	}
+
+	// The player arrives on the stairs leading back up
+	i := m.stairsAt(m.player.Pos.X, m.player.Pos.Y)
+	if i < 0 || m.stairs[i].Tile != StairsUp || m.stairs[i].Target != 1 {
+		t.Fatalf("Expected to arrive on stairs up to level 1, got %v", m.stairs)
+	}
+
+	m.useStairs(m.player.Pos.X, m.player.Pos.Y)
+	if m.level != 1 {
+		t.Fatalf("Expected to be back on level 1, got %d", m.level)
+	}
+
+	// Level 1 is restored, with the player standing on the exit instead of the start
+	if !reflect.DeepEqual(m.monsters, monsters) {
+		t.Error("Expected the monsters on level 1 to be restored")
+	}
+	if i := m.stairsAt(m.player.Pos.X, m.player.Pos.Y); i < 0 || m.stairs[i].Tile != Exit {
+		t.Errorf("Expected to arrive on the exit of level 1, got %v", m.player.Pos)
+	}
+
+	m.vacate(m.player.Pos.X, m.player.Pos.Y)
+	m.player.Pos = start
+	m.dungeon[start.Y][start.X] = Player
+	if m.dungeonToString() != before {
+		t.Error("Expected level 1 to be restored exactly")
+	}
+}
+
+func TestTravelKeepsPlayer(t *testing.T) {
+	m := initialModel()
+	m.player.Damage = 5
+	m.player.Health = 4
+
+	m.descend()
+
+	if m.player.Damage != 5 || m.player.Health != 4 {
+		t.Errorf("Expected the player to keep their stats, got damage %d health %d", m.player.Damage, m.player.Health)
+	}
+}
+
+func TestEscapeFromLastLevel(t *testing.T) {
+	m := initialModel()
+	for m.level < m.maxDepth() {
+		m.descend()
+	}
+
+	m.descend()
+	if !m.gameWon {
+		t.Error("Expected leaving the last level to win the game")
+	}
+}
+
+func TestDefinitionStairLinks(t *testing.T) {
+	def := dungeon.CreateExampleDungeon()
+	lower := def.Levels[0]
+	lower.ID = "level2"
+	lower.Encounters = nil
+	lower.Traps = nil
+	lower.Stairs = []dungeon.StairLink{
+		{Position: dungeon.Position{X: 1, Y: 8}, Direction: dungeon.StairsUp, TargetLevel: "level1"},
+	}
+	def.Levels = append(def.Levels, lower)
+
+	m := newDefinitionModel(t, def)
+	exit := def.Levels[0].ExitPos
+
+	m.useStairs(exit.X, exit.Y)
+	if m.level != 2 {
+		t.Fatalf("Expected the exit to lead to level 2, got %d", m.level)
+	}
+	if m.player.Pos != (Position{X: 1, Y: 8}) {
+		t.Errorf("Expected to arrive on the linked stairs, got %v", m.player.Pos)
+	}
+
+	m.useStairs(1, 8)
+	if m.level != 1 {
+		t.Fatalf("Expected the linked stairs to lead back to level 1, got %d", m.level)
+	}
+	if m.player.Pos != (Position{X: exit.X, Y: exit.Y}) {
+		t.Errorf("Expected to arrive on the exit of level 1, got %v", m.player.Pos)
+	}
+}
+
+func TestLockedExit(t *testing.T) {
+	def := dungeon.CreateExampleDungeon()
+	key := def.Items[0]
+	secret := def.Levels[0]
+	secret.ID = "secret"
+	secret.Encounters = nil
+	secret.Traps = nil
+	secret.Exits = []dungeon.ExitDefinition{{Position: secret.ExitPos, TargetLevel: dungeon.ExitOut}}
+	def.Levels = append(def.Levels, secret)
+	def.Levels[0].Exits = []dungeon.ExitDefinition{
+		{Position: dungeon.Position{X: 1, Y: 8}, TargetLevel: "secret", RequiresItem: key.ID},
+	}
+	if err := def.ValidateLevelGraph(); err != nil {
+		t.Fatalf("Expected a valid level graph, got %v", err)
+	}
+
+	m := newDefinitionModel(t, def)
+	m.useStairs(1, 8)
+	if m.level != 1 {
+		t.Fatal("Expected the locked exit to stay shut without the key")
+	}
+
+	m.addToInventory(ItemInstance{Template: key, Count: 1})
+	m.useStairs(1, 8)
+	if m.level != len(def.Levels) {
+		t.Fatalf("Expected the key to open the way to the secret level, got level %d", m.level)
+	}
+
+	exit := secret.ExitPos
+	m.useStairs(exit.X, exit.Y)
+	if !m.gameWon {
+		t.Error("Expected the secret level's exit to lead out of the dungeon")
+	}
+}
+
+func TestUpdateExplored(t *testing.T) {
+	m := initialModel()
+	m.updateExplored()
+
+	if !m.isExplored(m.player.Pos.X, m.player.Pos.Y) {
+		t.Error("Expected the player's tile to be explored")
+	}
+
+	far := Position{X: m.player.Pos.X + 20, Y: m.player.Pos.Y}
+	if m.inBounds(far.X, far.Y) && m.isExplored(far.X, far.Y) {
+		t.Error("Expected a distant tile to be unexplored")
+	}
+}
+
+func TestGenerateDungeon(t *testing.T) {
+	for seed := int64(0); seed < 20; seed++ {
+		m := newModel(seed)
+		if m.dungeon[m.player.Pos.Y][m.player.Pos.X] != Player {
+			t.Fatalf("Seed %d: expected the player on the map", seed)
+		}
+		exits := 0
+		for _, s := range m.stairs {
+			if s.Tile == Exit {
+				exits++
+				if m.dungeon[s.Pos.Y][s.Pos.X] != Exit {
+					t.Errorf("Seed %d: expected the exit tile at %v", seed, s.Pos)
+				}
+			}
+		}
+		if exits != 1 {
+			t.Errorf("Seed %d: expected one exit, got %d", seed, exits)
+		}
+	}
+}
+
+func TestGenerateLayoutFallsBack(t *testing.T) {
+	m := initialModel()
+	level := m.generateLayout("no_such_generator", dungeon.GeneratorParams{Width: 10, Height: 6})
+	if len(level.Rooms) != 1 || level.Grid[level.StartPos.Y][level.StartPos.X] != '.' || level.Grid[level.ExitPos.Y][level.ExitPos.X] != '.' {
+		t.Errorf("Expected a single open room to fall back on, got %+v", level)
+	}
+}
+
+func TestGeneratedDefinitionLevel(t *testing.T) {
+	def := dungeon.CreateExampleDungeon()
+	def.Levels[0] = dungeon.LevelDefinition{
+		ID:        "caves",
+		Generator: &dungeon.GeneratorDefinition{Algorithm: "cave", Width: 40, Height: 20},
+		Stations:  []dungeon.StationSpawn{{StationID: "bone_altar"}},
+	}
+	m := newDefinitionModel(t, def)
+	if m.levelDef == nil || len(m.dungeon) != 20 || len(m.dungeon[0]) != 40 {
+		t.Fatal("Expected the level to be generated from the definition")
+	}
+
+	station := m.levelDef.Stations[0].Position
+	if m.dungeon[station.Y][station.X] != Station || m.stationAt(station.X, station.Y) == nil {
+		t.Errorf("Expected the station at its generated position %v", station)
+	}
+	exit := m.levelDef.ExitPos
+	if i := m.stairsAt(exit.X, exit.Y); i < 0 || m.stairs[i].Tile != Exit {
+		t.Errorf("Expected the exit at its generated position %v", exit)
+	}
+
+	// The generated level survives leaving and coming back
+	m.travel(2)
+	if m.levelDef != nil {
+		t.Error("Expected the random second level to have no definition")
+	}
+	m.travel(1)
+	if m.levelDef == nil || m.levelDef.Stations[0].Position != station {
+		t.Error("Expected the generated level to come back with its stations")
+	}
+}
@@ -2,10 +2,15 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strconv"
 	"syscall"
 	"time"
@@ -15,6 +20,7 @@ import (
 	"github.com/charmbracelet/wish"
 	bm "github.com/charmbracelet/wish/bubbletea"
 	lm "github.com/charmbracelet/wish/logging"
+	gossh "golang.org/x/crypto/ssh"
 
 	"cryptcrawl/internal/dungeon"
 )
@@ -62,12 +68,59 @@ func main() {
 	}
 	dungeonLoader = loader
 
+	// Initialize the daily challenge leaderboard
+	dataDir := getEnv("DATA_DIR", "data")
+	board, err := newDailyStore(filepath.Join(dataDir, "daily"))
+	if err != nil {
+		log.Fatalf("Failed to initialize daily challenge store: %v", err)
+	}
+	dailyBoard = board
+
+	// Initialize saved games
+	saveStore, err := newSaveStore(filepath.Join(dataDir, "saves"))
+	if err != nil {
+		log.Fatalf("Failed to initialize saved games: %v", err)
+	}
+	saves = saveStore
+
+	// Initialize replays
+	replayStore, err := newReplayStore(filepath.Join(dataDir, "replays"))
+	if err != nil {
+		log.Fatalf("Failed to initialize replays: %v", err)
+	}
+	replays = replayStore
+
+	// Initialize morgue files
+	morgueStore, err := newMorgueStore(filepath.Join(dataDir, "morgue"))
+	if err != nil {
+		log.Fatalf("Failed to initialize morgue files: %v", err)
+	}
+	morgues = morgueStore
+
+	// Initialize the high-score leaderboard
+	scores, err := newLeaderboardStore(filepath.Join(dataDir, "leaderboard.json"))
+	if err != nil {
+		log.Fatalf("Failed to initialize leaderboard: %v", err)
+	}
+	leaderboard = scores
+
+	// Initialize achievements
+	unlocked, err := newAchievementsStore(filepath.Join(dataDir, "achievements"))
+	if err != nil {
+		log.Fatalf("Failed to initialize achievements: %v", err)
+	}
+	achievementStore = unlocked
+
 	// Create SSH server
 	s, err := wish.NewServer(
 		wish.WithAddress(fmt.Sprintf("%s:%d", host, port)),
 		wish.WithHostKeyPath(".ssh/cryptcrawl_ed25519"),
+		// Accept everyone, but ask for a public key so players can be told apart
+		wish.WithPublicKeyAuth(func(ssh.Context, ssh.PublicKey) bool { return true }),
+		wish.WithKeyboardInteractiveAuth(func(ssh.Context, gossh.KeyboardInteractiveChallenge) bool { return true }),
 		wish.WithMiddleware(
 			bm.Middleware(teaHandler),
+			saveMiddleware(),
 			lm.Middleware(),
 		),
 	)
@@ -102,13 +155,155 @@ func teaHandler(s ssh.Session) (tea.Model, []tea.ProgramOption) {
 		return nil, nil
 	}
 
-	m := initialModel()
+	opts, err := parseSessionArgs(s.Command())
+	if err != nil {
+		wish.Fatalln(s, err)
+		return nil, nil
+	}
+
+	if opts.Replay != "" {
+		r, err := replays.Load(opts.Replay)
+		if err != nil {
+			wish.Fatalln(s, fmt.Sprintf("Can't load replay %s: %v", opts.Replay, err))
+			return nil, nil
+		}
+		return newReplayModel(r, opts.Replay), []tea.ProgramOption{tea.WithAltScreen()}
+	}
+
+	var key string
+	if s.PublicKey() != nil {
+		key = keyFingerprint(s.PublicKey())
+	}
+
+	if opts.Morgue {
+		return newMorgueModel(key), []tea.ProgramOption{tea.WithAltScreen()}
+	}
+
+	session := &sessionGame{}
+	s.Context().SetValue(sessionGameKey, session)
+
+	var m model
+	switch {
+	case opts.Daily:
+		if key == "" {
+			wish.Fatalln(s, "The daily challenge needs an SSH public key to tell players apart.")
+			return nil, nil
+		}
+		date := dailyDate(time.Now())
+		if err := dailyBoard.Start(date, key, s.User()); err != nil {
+			if entries, err := dailyBoard.Entries(date); err == nil {
+				wish.Println(s, renderDailyBoard(date, entries, key))
+			}
+			wish.Fatalln(s, err)
+			return nil, nil
+		}
+		m = newDailyModel(date)
+	case opts.Endless:
+		seed := newSeed()
+		if opts.HasSeed {
+			seed = opts.Seed
+		}
+		m = newEndlessModel(seed)
+	case opts.HasSeed:
+		m = newModel(opts.Seed)
+	case opts.New:
+		m = newModel(newSeed())
+	default:
+		// Let the player pick between continuing, a new game and the leaderboards
+		return newTitleModel(key, s.User(), session), []tea.ProgramOption{
+			tea.WithAltScreen(),
+			tea.WithMouseCellMotion(),
+		}
+	}
+
+	m.attach(key, s.User(), session)
 	return m, []tea.ProgramOption{
 		tea.WithAltScreen(),
 		tea.WithMouseCellMotion(),
 	}
 }
 
+type sessionGameKeyType struct{}
+
+// sessionGameKey is the session context key holding the session's game
+var sessionGameKey sessionGameKeyType
+
+// saveMiddleware saves the session's game once the player quits or disconnects
+func saveMiddleware() wish.Middleware {
+	return func(next ssh.Handler) ssh.Handler {
+		return func(s ssh.Session) {
+			next(s)
+			if game, ok := s.Context().Value(sessionGameKey).(*sessionGame); ok {
+				if err := game.save(); err != nil {
+					log.Printf("Failed to save game for %s: %v", s.User(), err)
+				}
+			}
+		}
+	}
+}
+
+// sessionOptions holds the options a player passes after the SSH command,
+// e.g. ssh -t host -- --seed 1234
+type sessionOptions struct {
+	Seed    int64
+	HasSeed bool
+	Daily   bool
+	New     bool   // Start a new game instead of resuming the saved one
+	Endless bool   // Start an endless descent
+	Replay  string // ID of a replay to watch instead of playing
+	Morgue  bool   // Read the player's morgue files instead of playing
+}
+
+// parseSessionArgs parses the arguments of an SSH session
+func parseSessionArgs(args []string) (sessionOptions, error) {
+	var opts sessionOptions
+
+	fs := flag.NewFlagSet("cryptcrawl", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Int64Var(&opts.Seed, "seed", 0, "seed for a reproducible run")
+	fs.BoolVar(&opts.Daily, "daily", false, "play today's daily challenge")
+	fs.BoolVar(&opts.New, "new", false, "start a new game instead of resuming")
+	fs.BoolVar(&opts.Endless, "endless", false, "start an endless descent")
+	fs.StringVar(&opts.Replay, "replay", "", "watch a recorded replay")
+	fs.BoolVar(&opts.Morgue, "morgue", false, "read your morgue files")
+	if err := fs.Parse(args); err != nil {
+		return opts, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if fs.NArg() > 0 {
+		return opts, fmt.Errorf("unexpected argument: %s", fs.Arg(0))
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			opts.HasSeed = true
+		}
+	})
+	if opts.Daily && opts.HasSeed {
+		return opts, fmt.Errorf("the daily challenge can't be played with a custom seed")
+	}
+	if opts.Daily && opts.Endless {
+		return opts, fmt.Errorf("the daily challenge can't be played as an endless descent")
+	}
+	if opts.Replay != "" && (opts.Daily || opts.HasSeed || opts.New || opts.Morgue || opts.Endless) {
+		return opts, fmt.Errorf("--replay can't be combined with other options")
+	}
+	if opts.Morgue && (opts.Daily || opts.HasSeed || opts.New || opts.Endless) {
+		return opts, fmt.Errorf("--morgue can't be combined with other options")
+	}
+	return opts, nil
+}
+
+// keyFingerprint identifies a player by the SHA256 fingerprint of their public key
+func keyFingerprint(key ssh.PublicKey) string {
+	sum := sha256.Sum256(key.Marshal())
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// newSeed picks a seed for a run the player didn't seed themselves
+func newSeed() int64 {
+	return time.Now().UnixNano()
+}
+
 // getEnv gets an environment variable or returns a default value
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
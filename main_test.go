@@ -57,3 +57,59 @@ func TestGetEnv(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSessionArgs(t *testing.T) {
+	opts, err := parseSessionArgs([]string{"--seed", "1234"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !opts.HasSeed || opts.Seed != 1234 {
+		t.Errorf("Expected seed 1234, got %+v", opts)
+	}
+
+	opts, err = parseSessionArgs(nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if opts.HasSeed {
+		t.Errorf("Expected no seed without arguments, got %+v", opts)
+	}
+
+	opts, err = parseSessionArgs([]string{"--daily"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !opts.Daily {
+		t.Errorf("Expected the daily challenge, got %+v", opts)
+	}
+
+	opts, err = parseSessionArgs([]string{"--new"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !opts.New {
+		t.Errorf("Expected a new game, got %+v", opts)
+	}
+
+	opts, err = parseSessionArgs([]string{"--replay", "0123456789ab"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if opts.Replay != "0123456789ab" {
+		t.Errorf("Expected a replay, got %+v", opts)
+	}
+
+	opts, err = parseSessionArgs([]string{"--endless", "--seed", "7"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !opts.Endless || opts.Seed != 7 {
+		t.Errorf("Expected a seeded endless descent, got %+v", opts)
+	}
+
+	for _, args := range [][]string{{"--seed", "abc"}, {"--color"}, {"extra"}, {"--daily", "--seed", "1"}, {"--replay", "0123456789ab", "--new"}, {"--morgue", "--daily"}, {"--daily", "--endless"}} {
+		if _, err := parseSessionArgs(args); err == nil {
+			t.Errorf("Expected an error for %v", args)
+		}
+	}
+}
@@ -2,14 +2,19 @@ package main
 
 import (
 	"fmt"
+	"log"
+	"math"
 	"math/rand"
 	"os"
+	"strings"
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+
+	"cryptcrawl/internal/dungeon"
 )
 
 // Define key mappings
@@ -21,6 +26,16 @@ type keyMap struct {
 	Help   key.Binding
 	Quit   key.Binding
 	Attack key.Binding
+	Eat    key.Binding
+	Search key.Binding
+	Disarm key.Binding
+	Replay key.Binding
+	Morgue key.Binding
+	Scores key.Binding
+	Trophy key.Binding
+	Use    key.Binding
+	Known  key.Binding
+	Craft  key.Binding
 }
 
 func (k keyMap) ShortHelp() []key.Binding {
@@ -30,8 +45,8 @@ func (k keyMap) ShortHelp() []key.Binding {
 func (k keyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
 		{k.Up, k.Down, k.Left, k.Right},
-		{k.Attack},
-		{k.Help, k.Quit},
+		{k.Attack, k.Eat, k.Use, k.Craft, k.Search, k.Disarm},
+		{k.Known, k.Trophy, k.Help, k.Quit},
 	}
 }
 
@@ -56,6 +71,46 @@ var keys = keyMap{
 		key.WithKeys("space"),
 		key.WithHelp("space", "attack"),
 	),
+	Eat: key.NewBinding(
+		key.WithKeys("e"),
+		key.WithHelp("e", "eat"),
+	),
+	Use: key.NewBinding(
+		key.WithKeys("u"),
+		key.WithHelp("u", "use item"),
+	),
+	Craft: key.NewBinding(
+		key.WithKeys("c"),
+		key.WithHelp("c", "craft"),
+	),
+	Known: key.NewBinding(
+		key.WithKeys("\\"),
+		key.WithHelp("\\", "discoveries"),
+	),
+	Search: key.NewBinding(
+		key.WithKeys("f"),
+		key.WithHelp("f", "search"),
+	),
+	Disarm: key.NewBinding(
+		key.WithKeys("x"),
+		key.WithHelp("x", "disarm trap"),
+	),
+	Replay: key.NewBinding(
+		key.WithKeys("r"),
+		key.WithHelp("r", "watch replay"),
+	),
+	Morgue: key.NewBinding(
+		key.WithKeys("m"),
+		key.WithHelp("m", "read morgue file"),
+	),
+	Scores: key.NewBinding(
+		key.WithKeys("b"),
+		key.WithHelp("b", "view leaderboard"),
+	),
+	Trophy: key.NewBinding(
+		key.WithKeys("v"),
+		key.WithHelp("v", "view achievements"),
+	),
 	Help: key.NewBinding(
 		key.WithKeys("?"),
 		key.WithHelp("?", "toggle help"),
@@ -83,19 +138,36 @@ type Position struct {
 
 // Entity represents a game entity
 type Entity struct {
-	Pos       Position
-	Symbol    rune
-	Health    int
-	MaxHealth int
-	Damage    int
-	Name      string
+	Pos        Position
+	Symbol     rune
+	Health     int
+	MaxHealth  int
+	Damage     int
+	DamageDice dungeon.Dice // Rolled instead of Damage when set
+	Accuracy   int          // Bonus to hit rolls
+	Evasion    int          // Makes the entity harder to hit
+	Name       string
+	TemplateID string // Monster template ID for dungeon-defined monsters
+	Alert      int    // Turns left hunting the player after an alarm
+	Stuck      int    // Turns left caught in a net
+	Phase      int    // Boss phases entered so far
 }
 
 // Model represents the game state
 type model struct {
-	dungeon   [][]TileType // Using TileType instead of rune
+	def       *dungeon.DungeonDefinition // Loaded dungeon definition, nil for random dungeons
+	dungeon   [][]TileType               // Using TileType instead of rune
 	player    Entity
 	monsters  []Entity
+	items     []ItemInstance // Items lying on the floor
+	traps     []TrapInstance
+	stairs    []Stairs
+	explored  [][]bool            // Tiles the player has seen on this level
+	levels    map[int]*levelState // Levels the player has left, keyed by depth
+	inventory []ItemInstance      // Items carried by the player
+	turns     int
+	hunger    hungerClock
+	status    statusEffects
 	width     int
 	height    int
 	viewport  viewport.Model
@@ -107,11 +179,73 @@ type model struct {
 	level     int
 	gameOver  bool
 	gameWon   bool
-	revealMap bool // Debug option to reveal the entire map
+	revealMap bool            // Debug option to reveal the entire map
+	seed      int64           // Seed the run was started from
+	rng       *rand.Rand      // Source of every random roll in the run's gameplay
+	levelRNG  *rand.Rand      // Builds the current level, apart from the gameplay rolls
+	rngSource *countingSource // Tracks the RNG state for saving
+	finished  bool            // Whether the end of the run has been recorded
+
+	playerName   string         // SSH user name of the player
+	playerKey    string         // Fingerprint of the player's SSH public key
+	dailyDate    string         // Date of the daily challenge being played, if any
+	dailyEntries []dailyEntry   // The day's leaderboard, read once the daily run is over
+	dailyErr     error          // Why the day's leaderboard couldn't be read
+	session      *sessionGame   // Keeps the latest state for saving when the session ends
+	actions      []action       // Every action the player has taken, for the replay
+	replayID     string         // ID of the stored replay once the run is over
+	kills        map[string]int // Monsters killed by the player, by name
+	deathCause   string         // What killed the player, e.g. "killed by a skeleton"
+	morgueName   string         // Name of the stored morgue file once the run is over
+	scoreRank    int            // Rank of the run on its dungeon's leaderboard, 0 if not ranked
+
+	achievements   unlockedAchievements // The player's unlocked achievements, nil when not tracked
+	toast          string               // Latest achievement unlocked, shown for a few turns
+	toastTurn      int                  // Turn the toast appeared on
+	flawlessLevels int                  // Levels left without taking damage on them
+	hurtOnLevel    bool                 // Whether the player has taken damage on this level
+	bossKills      int                  // Bosses the player has defeated
+	endless        bool                 // Whether the stairs keep going down forever
+	deepest        int                  // Deepest level reached
+
+	hub       *dungeon.HubDefinition  // Hub town of a campaign, nil for a single dungeon
+	inHub     bool                    // Whether the player is in the hub town
+	conquered map[string]bool         // Dungeons of the campaign the player has conquered, by name
+	flags     map[string]bool         // Quest flags set by the dungeon's events
+	shop      *dungeon.ShopDefinition // The hub shop whose wares are shown, nil when none
+
+	appearances map[string]string        // Disguised names of unidentified item templates, by ID
+	identified  map[string]bool          // Item templates the player has identified, by ID
+	levelDef    *dungeon.LevelDefinition // The current level as generated, for procedural definition levels
+	choosing    bool                     // Whether the use item menu is open
 }
 
-// Initialize the model
+// Initialize the model with a fresh random seed
 func initialModel() model {
+	return newModel(newSeed())
+}
+
+// newModel starts a campaign in the loader's hub town, or a run in its
+// current dungeon, or in a random one
+func newModel(seed int64) model {
+	if dungeonLoader != nil && dungeonLoader.Hub != nil {
+		return newCampaignModel(seed, dungeonLoader.Hub)
+	}
+	return newGame(seed, currentDungeon())
+}
+
+// currentDungeon returns the loader's current dungeon, nil for a random one
+func currentDungeon() *dungeon.DungeonDefinition {
+	if dungeonLoader == nil {
+		return nil
+	}
+	return dungeonLoader.GetCurrentDungeon()
+}
+
+// newGame starts a run whose randomness comes entirely from the given seed,
+// so the same seed and inputs always play out the same way. A nil definition
+// plays a randomly generated dungeon.
+func newGame(seed int64, def *dungeon.DungeonDefinition) model {
 	// Set up the help model
 	h := help.New()
 	h.ShowAll = false
@@ -132,72 +266,30 @@ func initialModel() model {
 		gameOver:  false,
 		gameWon:   false,
 		revealMap: debugMode, // Reveal the entire map in debug mode
+		seed:      seed,
+	}
+	m.rng, m.rngSource = newRNG(seed)
+	m.levelRNG = levelRNG(seed, m.level)
+
+	// Try to load the dungeon definition
+	loaded := false
+	if def != nil {
+		m.addMessage(fmt.Sprintf("Loaded dungeon: %s", def.Name))
+		m.addMessage(def.Description)
+		m.def = def
+		m.hunger = newHungerClock(def.Hunger)
+		loaded = m.loadDefinitionLevel()
 	}
 
-	// Try to load a dungeon from the dungeon loader
-	if dungeonLoader != nil {
-		def := dungeonLoader.GetCurrentDungeon()
-		if def != nil {
-			m.addMessage(fmt.Sprintf("Loaded dungeon: %s", def.Name))
-			m.addMessage(def.Description)
-
-			// Generate the dungeon from the definition
-			grid, _, err := dungeonLoader.GenerateCurrentLevel(m.level - 1)
-			if err == nil && grid != nil {
-				// Convert the rune grid to TileType grid
-				m.dungeon = make([][]TileType, len(grid))
-				for y := range grid {
-					m.dungeon[y] = make([]TileType, len(grid[y]))
-					for x, r := range grid[y] {
-						switch r {
-						case '#':
-							m.dungeon[y][x] = Wall
-						case '@':
-							m.dungeon[y][x] = Player
-							// Set player position
-							m.player.Pos.X = x
-							m.player.Pos.Y = y
-						case 'E':
-							m.dungeon[y][x] = Exit
-						case '$':
-							m.dungeon[y][x] = Gold
-						case 'M', 'S', 'Z', 'W':
-							m.dungeon[y][x] = Monster
-							// Add monster
-							monster := Entity{
-								Pos:       Position{X: x, Y: y},
-								Symbol:    r,
-								Health:    5,
-								MaxHealth: 5,
-								Damage:    2,
-								Name:      "Monster",
-							}
-							m.monsters = append(m.monsters, monster)
-						case '?':
-							m.dungeon[y][x] = Chest
-						case '^':
-							m.dungeon[y][x] = Trap
-						case '+':
-							m.dungeon[y][x] = Door
-						case '~':
-							// Could be water or lava
-							if rand.Intn(2) == 0 {
-								m.dungeon[y][x] = Water
-							} else {
-								m.dungeon[y][x] = Lava
-							}
-						default:
-							m.dungeon[y][x] = Empty
-						}
-					}
-				}
-				return m
-			}
-		}
+	if !loaded {
+		m.def = nil
+		m.hunger = newHungerClock(nil)
+		// Generate the dungeon
+		m.generateDungeon()
 	}
 
-	// Generate the dungeon
-	m.generateDungeon()
+	m.appearances = assignAppearances(seed, m.runItems())
+	m.updateExplored()
 
 	// Set up the viewport
 	vp := viewport.New(m.width, m.height-5) // Leave room for messages and status
@@ -207,6 +299,17 @@ func initialModel() model {
 	return m
 }
 
+// attach hands the game to a connected player
+func (m *model) attach(key, name string, session *sessionGame) {
+	m.playerKey = key
+	m.playerName = name
+	m.achievements = loadAchievements(key)
+	m.session = session
+	if session != nil {
+		session.set(*m)
+	}
+}
+
 // Init initializes the model
 func (m model) Init() tea.Cmd {
 	return nil
@@ -220,31 +323,59 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch {
+		case m.choosing:
+			m.updateUseMenu(msg)
+		case m.shop != nil:
+			m.updateShopMenu(msg)
 		case key.Matches(msg, m.keys.Quit):
 			return m, tea.Quit
 		case key.Matches(msg, m.keys.Help):
 			m.showHelp = !m.showHelp
 		case key.Matches(msg, m.keys.Up):
-			if !m.gameOver && !m.gameWon {
-				m.movePlayer(0, -1)
-			}
+			m.act(actionUp)
 		case key.Matches(msg, m.keys.Down):
-			if !m.gameOver && !m.gameWon {
-				m.movePlayer(0, 1)
-			}
+			m.act(actionDown)
 		case key.Matches(msg, m.keys.Left):
-			if !m.gameOver && !m.gameWon {
-				m.movePlayer(-1, 0)
-			}
+			m.act(actionLeft)
 		case key.Matches(msg, m.keys.Right):
-			if !m.gameOver && !m.gameWon {
-				m.movePlayer(1, 0)
-			}
+			m.act(actionRight)
 		case key.Matches(msg, m.keys.Attack):
+			m.act(actionAttack)
+		case key.Matches(msg, m.keys.Eat):
+			m.act(actionEat)
+		case key.Matches(msg, m.keys.Search):
+			m.act(actionSearch)
+		case key.Matches(msg, m.keys.Disarm):
+			m.act(actionDisarm)
+		case key.Matches(msg, m.keys.Use):
+			m.choosing = !m.gameOver && !m.gameWon
+		case key.Matches(msg, m.keys.Known):
+			return newDiscoveriesModel(m), nil
+		case key.Matches(msg, m.keys.Craft):
 			if !m.gameOver && !m.gameWon {
-				m.attackNearbyMonsters()
+				return newCraftingModel(m), nil
+			}
+		case key.Matches(msg, m.keys.Replay):
+			if m.gameOver || m.gameWon {
+				r := newReplayModel(m.replay(), m.replayID)
+				return r, r.Init()
+			}
+		case key.Matches(msg, m.keys.Morgue):
+			if m.gameOver || m.gameWon {
+				if m.morgueName == "" {
+					return newMorgueViewer([]morguePage{{text: m.morgue()}}), nil
+				}
+				return newMorgueModel(m.playerKey), nil
+			}
+		case key.Matches(msg, m.keys.Trophy):
+			return newAchievementsModel(m, m.achievements).Update(tea.WindowSizeMsg{Width: m.width, Height: m.height})
+		case key.Matches(msg, m.keys.Scores):
+			if m.gameOver || m.gameWon {
+				return newLeaderboardModel(m, m.playerKey, m.dungeonName()), nil
 			}
 		}
+	case craftMsg:
+		m.act(craftAction(msg.recipe))
 	case tea.WindowSizeMsg:
 		m.viewport.Width = msg.Width
 		m.viewport.Height = msg.Height - 5 // Leave room for messages and status
@@ -252,24 +383,71 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.height = msg.Height
 	}
 
+	m.checkAchievements()
+	if (m.gameOver || m.gameWon) && !m.finished {
+		m.finishRun()
+	}
+
 	// Update viewport
 	m.viewport.SetContent(m.dungeonToString())
 	m.viewport, cmd = m.viewport.Update(msg)
 	cmds = append(cmds, cmd)
 
+	if m.session != nil {
+		m.session.set(m)
+	}
+
 	return m, tea.Batch(cmds...)
 }
 
+// finishRun records the end of the run once the player dies or escapes
+func (m *model) finishRun() {
+	m.finished = true
+	m.submitDaily()
+	m.submitScore()
+	m.storeReplay()
+	m.writeMorgue()
+}
+
 // View renders the UI
 func (m model) View() string {
 	if m.gameOver {
-		return fmt.Sprintf("\n\n  GAME OVER\n\n  You reached level %d and collected %d gold.\n\n  Press q to quit.", m.level, m.gold)
+		return fmt.Sprintf("\n\n  GAME OVER\n\n  You reached level %d and collected %d gold.\n  Seed: %d\n%s\n%s%s", m.level, m.gold, m.seed, m.scoreView(), m.dailyBoardView(), m.endOfRunHelp())
+	}
+
+	if m.gameWon && m.hub != nil {
+		return fmt.Sprintf("\n\n  VICTORY!\n\n  You conquered every dungeon of %s with %d gold!\n  Seed: %d\n%s\n%s%s", m.hub.Name, m.gold, m.seed, m.scoreView(), m.dailyBoardView(), m.endOfRunHelp())
 	}
 
 	if m.gameWon {
-		return fmt.Sprintf("\n\n  VICTORY!\n\n  You escaped the dungeon with %d gold!\n\n  Press q to quit.", m.gold)
+		return fmt.Sprintf("\n\n  VICTORY!\n\n  You escaped the dungeon with %d gold!\n  Seed: %d\n%s\n%s%s", m.gold, m.seed, m.scoreView(), m.dailyBoardView(), m.endOfRunHelp())
+	}
+
+	// Render help if needed
+	helpView := ""
+	if m.showHelp {
+		helpView = "\n" + m.help.View(m.keys)
 	}
 
+	return m.playView() + helpView
+}
+
+// endOfRunHelp tells the player what they can do once the run is over
+func (m model) endOfRunHelp() string {
+	var b strings.Builder
+	if m.replayID != "" {
+		fmt.Fprintf(&b, "  Replay %s: share it with ssh -t <host> -- --replay %s\n\n", m.replayID, m.replayID)
+	}
+	b.WriteString("  Press m to read the morgue file")
+	if len(m.actions) > 0 {
+		b.WriteString(", r to watch the replay")
+	}
+	b.WriteString(", b to view the leaderboard or q to quit.")
+	return b.String()
+}
+
+// playView renders the dungeon, status bar and message log
+func (m model) playView() string {
 	// Render the dungeon
 	dungeonView := m.viewport.View()
 
@@ -280,9 +458,26 @@ func (m model) View() string {
 
 	healthBar := fmt.Sprintf("❤️ %s%d/%d", healthStyle.Render(""), m.player.Health, m.player.MaxHealth)
 	goldBar := fmt.Sprintf("💰 %s%d", goldStyle.Render(""), m.gold)
-	levelBar := fmt.Sprintf("📜 %sLevel %d", levelStyle.Render(""), m.level)
+	levelBar := fmt.Sprintf("📜 %s%s", levelStyle.Render(""), m.locationName())
 
 	statusBar := fmt.Sprintf("%s | %s | %s", healthBar, goldBar, levelBar)
+	if m.status.Poisoned > 0 {
+		statusBar += " | " + lipgloss.NewStyle().Foreground(lipgloss.Color("#00ff00")).Render("Poisoned")
+	}
+	if m.status.Netted > 0 {
+		statusBar += " | " + lipgloss.NewStyle().Foreground(lipgloss.Color("#cccccc")).Render("Netted")
+	}
+	if status := m.hunger.State().String(); status != "" {
+		statusBar += " | " + hungerStyle(m.hunger.State()).Render(status)
+	}
+	statusBar += " | " + lipgloss.NewStyle().Foreground(lipgloss.Color("#888888")).Render(fmt.Sprintf("Seed %d", m.seed))
+
+	if m.choosing {
+		return fmt.Sprintf("%s\n%s%s\n%s\n%s", dungeonView, m.bossBarView(), m.toastView(), statusBar, m.useMenuView())
+	}
+	if m.shop != nil {
+		return fmt.Sprintf("%s\n%s%s\n%s\n%s", dungeonView, m.bossBarView(), m.toastView(), statusBar, m.shopMenuView())
+	}
 
 	// Render the message log (last 3 messages)
 	messageLog := ""
@@ -294,116 +489,96 @@ func (m model) View() string {
 		messageLog += fmt.Sprintf("  %s\n", m.messages[i])
 	}
 
-	// Render help if needed
-	helpView := ""
-	if m.showHelp {
-		helpView = "\n" + m.help.View(m.keys)
-	}
-
 	// Combine all views
-	return fmt.Sprintf("%s\n\n%s\n%s%s", dungeonView, statusBar, messageLog, helpView)
+	return fmt.Sprintf("%s\n%s%s\n%s\n%s", dungeonView, m.bossBarView(), m.toastView(), statusBar, messageLog)
 }
 
 // Generate a random dungeon
 func (m *model) generateDungeon() {
-	// No need to seed in Go 1.20+
-	// rand.Seed is deprecated
-
-	// Create an empty dungeon filled with walls
-	m.dungeon = make([][]TileType, m.height)
-	for i := range m.dungeon {
-		m.dungeon[i] = make([]TileType, m.width)
-		for j := range m.dungeon[i] {
-			m.dungeon[i][j] = Wall
-		}
-	}
-
-	// Create rooms
-	numRooms := rand.Intn(5) + 5 // 5-10 rooms
-	rooms := make([]struct{ x, y, w, h int }, 0, numRooms)
-
-	for i := 0; i < numRooms; i++ {
-		roomW := rand.Intn(8) + 5 // 5-12 width
-		roomH := rand.Intn(5) + 3 // 3-7 height
-		roomX := rand.Intn(m.width-roomW-2) + 1
-		roomY := rand.Intn(m.height-roomH-2) + 1
-
-		// Check for overlap with existing rooms
-		overlap := false
-		for _, r := range rooms {
-			if roomX <= r.x+r.w+1 && roomX+roomW+1 >= r.x &&
-				roomY <= r.y+r.h+1 && roomY+roomH+1 >= r.y {
-				overlap = true
-				break
+	level := m.generateLayout(dungeon.DefaultGenerator, dungeon.GeneratorParams{Width: randomDungeonWidth, Height: randomDungeonHeight})
+
+	// Convert the rune grid to tiles
+	m.dungeon = make([][]TileType, len(level.Grid))
+	for y := range level.Grid {
+		m.dungeon[y] = make([]TileType, len(level.Grid[y]))
+		for x, r := range level.Grid[y] {
+			switch r {
+			case '#':
+				m.dungeon[y][x] = Wall
+			case dungeon.RuneWater:
+				m.dungeon[y][x] = Water
+			case dungeon.RuneLava:
+				m.dungeon[y][x] = Lava
+			default:
+				m.dungeon[y][x] = Empty
 			}
 		}
-
-		if !overlap {
-			// Carve out the room
-			for y := roomY; y < roomY+roomH; y++ {
-				for x := roomX; x < roomX+roomW; x++ {
-					m.dungeon[y][x] = Empty
-				}
-			}
-
-			// Add the room to our list
-			rooms = append(rooms, struct{ x, y, w, h int }{roomX, roomY, roomW, roomH})
-		}
 	}
 
-	// Connect rooms with corridors
-	for i := 0; i < len(rooms)-1; i++ {
-		startX := rooms[i].x + rooms[i].w/2
-		startY := rooms[i].y + rooms[i].h/2
-		endX := rooms[i+1].x + rooms[i+1].w/2
-		endY := rooms[i+1].y + rooms[i+1].h/2
+	// Work with the rooms' floors
+	rooms := make([]struct{ x, y, w, h int }, 0, len(level.Rooms))
+	for _, r := range level.Rooms {
+		rooms = append(rooms, struct{ x, y, w, h int }{r.X + 1, r.Y + 1, r.Width - 2, r.Height - 2})
+	}
 
-		// Horizontal corridor
-		for x := min(startX, endX); x <= max(startX, endX); x++ {
-			m.dungeon[startY][x] = Empty
+	// Hide some of the doorways where corridors enter rooms
+	for _, r := range rooms {
+		for x := r.x; x < r.x+r.w; x++ {
+			for _, y := range []int{r.y - 1, r.y + r.h} {
+				if m.dungeon[y][x] == Empty && m.dungeon[y][x-1] == Wall && m.dungeon[y][x+1] == Wall && m.levelRNG.Intn(6) == 0 {
+					m.dungeon[y][x] = SecretDoor
+				}
+			}
 		}
-
-		// Vertical corridor
-		for y := min(startY, endY); y <= max(startY, endY); y++ {
-			m.dungeon[y][endX] = Empty
+		for y := r.y; y < r.y+r.h; y++ {
+			for _, x := range []int{r.x - 1, r.x + r.w} {
+				if m.dungeon[y][x] == Empty && m.dungeon[y-1][x] == Wall && m.dungeon[y+1][x] == Wall && m.levelRNG.Intn(6) == 0 {
+					m.dungeon[y][x] = SecretDoor
+				}
+			}
 		}
 	}
 
-	// Place player in the first room
-	playerX := rooms[0].x + rooms[0].w/2
-	playerY := rooms[0].y + rooms[0].h/2
-	m.player = Entity{
-		Pos:       Position{X: playerX, Y: playerY},
-		Symbol:    TilePlayer,
-		Health:    10,
-		MaxHealth: 10,
-		Damage:    2,
-		Name:      "Player",
+	// Start on the stairs up below the first level, and put the exit as far away as possible
+	m.stairs = nil
+	start := Position{X: level.StartPos.X, Y: level.StartPos.Y}
+	if m.level > 1 {
+		m.addStairs(start, StairsUp, m.level-1)
 	}
-	m.dungeon[playerY][playerX] = Player
-
-	// Place exit in the last room
-	exitX := rooms[len(rooms)-1].x + rooms[len(rooms)-1].w/2
-	exitY := rooms[len(rooms)-1].y + rooms[len(rooms)-1].h/2
-	m.dungeon[exitY][exitX] = Exit
+	m.placePlayer(start)
+	m.addStairs(Position{X: level.ExitPos.X, Y: level.ExitPos.Y}, Exit, m.level+1)
 
-	// Place monsters and gold
+	// Place monsters and gold. Treasure vaults are unguarded and twice as rich.
 	m.monsters = []Entity{}
-	for i := 1; i < len(rooms)-1; i++ {
+	m.items = []ItemInstance{}
+	m.traps = []TrapInstance{}
+	exit := level.ExitPos
+	kind := m.levelKind()
+	scale := m.monsterScale()
+	for i := 1; i < len(rooms); i++ {
+		// Leave the exit room empty
+		if exit.X >= rooms[i].x && exit.X < rooms[i].x+rooms[i].w && exit.Y >= rooms[i].y && exit.Y < rooms[i].y+rooms[i].h {
+			continue
+		}
+
 		// Add 1-3 monsters per room
-		numMonsters := rand.Intn(3) + 1
+		numMonsters := m.levelRNG.Intn(3) + 1
+		if kind == treasureLevel {
+			numMonsters = 0
+		}
 		for j := 0; j < numMonsters; j++ {
-			monsterX := rooms[i].x + rand.Intn(rooms[i].w)
-			monsterY := rooms[i].y + rand.Intn(rooms[i].h)
+			monsterX := rooms[i].x + m.levelRNG.Intn(rooms[i].w)
+			monsterY := rooms[i].y + m.levelRNG.Intn(rooms[i].h)
 
 			// Make sure the position is empty
 			if m.dungeon[monsterY][monsterX] == Empty {
+				health := int(math.Round(float64(3+m.level) * scale))
 				monster := Entity{
 					Pos:       Position{X: monsterX, Y: monsterY},
 					Symbol:    TileMonster,
-					Health:    3 + m.level,
-					MaxHealth: 3 + m.level,
-					Damage:    1 + m.level/2,
+					Health:    health,
+					MaxHealth: health,
+					Damage:    int(math.Round(float64(1+m.level/2) * scale)),
 					Name:      "Monster",
 				}
 				m.monsters = append(m.monsters, monster)
@@ -412,17 +587,270 @@ func (m *model) generateDungeon() {
 		}
 
 		// Add 1-5 gold piles per room
-		numGold := rand.Intn(5) + 1
+		numGold := m.levelRNG.Intn(5) + 1
+		if kind == treasureLevel {
+			numGold *= 2
+		}
 		for j := 0; j < numGold; j++ {
-			goldX := rooms[i].x + rand.Intn(rooms[i].w)
-			goldY := rooms[i].y + rand.Intn(rooms[i].h)
+			goldX := rooms[i].x + m.levelRNG.Intn(rooms[i].w)
+			goldY := rooms[i].y + m.levelRNG.Intn(rooms[i].h)
 
 			// Make sure the position is empty
 			if m.dungeon[goldY][goldX] == Empty {
 				m.dungeon[goldY][goldX] = Gold
 			}
 		}
+
+		// Some rooms hide a trap
+		if m.levelRNG.Intn(3) == 0 {
+			trapX := rooms[i].x + m.levelRNG.Intn(rooms[i].w)
+			trapY := rooms[i].y + m.levelRNG.Intn(rooms[i].h)
+			if m.dungeon[trapY][trapX] == Empty {
+				traps := dungeon.DefaultTrapTemplates()
+				trap := traps[m.levelRNG.Intn(len(traps))]
+				m.addTrap(Position{X: trapX, Y: trapY}, trap, true)
+			}
+		}
+	}
+
+	switch kind {
+	case treasureLevel:
+		m.addMessage("Gold glitters everywhere. You have found a treasure vault!")
+	case bossLevel:
+		m.placeFloorBoss(Position{X: exit.X, Y: exit.Y})
+	}
+}
+
+// generateLayout runs a registered level generator and digs through anything
+// that cuts the level off from its start. If the generator can't build a
+// level, it falls back to a single room filling the level, so the
+// game always has somewhere to play.
+func (m *model) generateLayout(name string, params dungeon.GeneratorParams) *dungeon.GeneratedLevel {
+	generator, err := dungeon.GetGenerator(name)
+	if err == nil {
+		level, genErr := generator.Generate(m.levelRNG, params)
+		if genErr == nil {
+			dungeon.ConnectLevel(level.Grid, level.StartPos)
+			return level
+		}
+		err = genErr
+	}
+	log.Printf("Failed to generate level %d: %v", m.level, err)
+
+	grid := make([][]rune, params.Height)
+	for y := range grid {
+		grid[y] = make([]rune, params.Width)
+		for x := range grid[y] {
+			grid[y][x] = '#'
+			if x > 0 && y > 0 && x < params.Width-1 && y < params.Height-1 {
+				grid[y][x] = '.'
+			}
+		}
+	}
+	room := dungeon.RoomDefinition{ID: "room1", Width: params.Width, Height: params.Height}
+	return &dungeon.GeneratedLevel{
+		Grid:     grid,
+		Rooms:    []dungeon.RoomDefinition{room},
+		StartPos: dungeon.Position{X: 1, Y: 1},
+		ExitPos:  dungeon.Position{X: params.Width - 2, Y: params.Height - 2},
+	}
+}
+
+// loadDefinitionLevel builds the current level from the loaded dungeon definition
+func (m *model) loadDefinitionLevel() bool {
+	grid, metadata, err := dungeon.GenerateDungeonFromDefinition(m.def, m.level-1, m.levelRNG)
+	if err != nil || grid == nil {
+		return false
+	}
+
+	if unreachable, ok := metadata["unreachable"].([]dungeon.Unreachable); ok {
+		for _, u := range unreachable {
+			log.Printf("%s level %d: %s", m.def.Name, m.level, u)
+		}
+	}
+
+	// Generated levels come with their own rooms, stairs and stations
+	m.levelDef = nil
+	generated, _ := metadata["generated"].(bool)
+	if levelDef, ok := metadata["level"].(dungeon.LevelDefinition); ok && generated {
+		m.levelDef = &levelDef
+	}
+
+	// Index the monsters placed by the definition by position
+	placed := make(map[Position]Entity)
+	if monsters, ok := metadata["monsters"].([]map[string]interface{}); ok {
+		for _, data := range monsters {
+			pos := metadataPosition(data)
+			health, _ := data["health"].(int)
+			damage, _ := data["damage"].(int)
+			damageDice, _ := data["damageDice"].(string)
+			accuracy, _ := data["accuracy"].(int)
+			evasion, _ := data["evasion"].(int)
+			name, _ := data["name"].(string)
+			id, _ := data["id"].(string)
+			symbol, _ := data["symbol"].(string)
+			monster := Entity{
+				Pos:        pos,
+				Symbol:     TileMonster,
+				Health:     max(health, 1),
+				MaxHealth:  max(health, 1),
+				Damage:     damage,
+				DamageDice: dungeon.Dice(damageDice),
+				Accuracy:   accuracy,
+				Evasion:    evasion,
+				Name:       name,
+				TemplateID: id,
+			}
+			if symbol != "" {
+				monster.Symbol = []rune(symbol)[0]
+			}
+			placed[pos] = monster
+		}
+	}
+
+	// Water drawn through the legend is never lava, and only lava drawn
+	// through the legend is lava in hand-drawn layouts
+	water := make(map[Position]bool)
+	if positions, ok := metadata["water"].([]dungeon.Position); ok {
+		for _, p := range positions {
+			water[Position{X: p.X, Y: p.Y}] = true
+		}
+	}
+	lava := make(map[Position]bool)
+	if positions, ok := metadata["lava"].([]dungeon.Position); ok {
+		for _, p := range positions {
+			lava[Position{X: p.X, Y: p.Y}] = true
+		}
+	}
+
+	// Convert the rune grid to TileType grid
+	m.monsters = []Entity{}
+	m.items = []ItemInstance{}
+	m.traps = []TrapInstance{}
+	m.dungeon = make([][]TileType, len(grid))
+	for y := range grid {
+		m.dungeon[y] = make([]TileType, len(grid[y]))
+		for x, r := range grid[y] {
+			switch r {
+			case '#':
+				m.dungeon[y][x] = Wall
+			case '@':
+				m.dungeon[y][x] = Player
+			case 'E':
+				m.dungeon[y][x] = Exit
+			case '$':
+				m.dungeon[y][x] = Gold
+			case 'M', 'S', 'Z', 'W':
+				m.dungeon[y][x] = Monster
+				// Add monster
+				monster, ok := placed[Position{X: x, Y: y}]
+				if !ok {
+					monster = Entity{
+						Pos:       Position{X: x, Y: y},
+						Symbol:    r,
+						Health:    5,
+						MaxHealth: 5,
+						Damage:    2,
+						Name:      "Monster",
+					}
+				}
+				m.monsters = append(m.monsters, monster)
+			case '?':
+				m.dungeon[y][x] = Chest
+			case '^':
+				m.dungeon[y][x] = Trap
+			case '+':
+				m.dungeon[y][x] = Door
+			case '<':
+				m.dungeon[y][x] = StairsUp
+			case '>':
+				m.dungeon[y][x] = StairsDown
+			case '=':
+				m.dungeon[y][x] = SecretDoor
+			case ';':
+				m.dungeon[y][x] = HiddenTrap
+			case '&':
+				m.dungeon[y][x] = Station
+			case dungeon.RuneLava:
+				// Hand-drawn layouts have always used % as floor
+				if generated || lava[Position{X: x, Y: y}] {
+					m.dungeon[y][x] = Lava
+				} else {
+					m.dungeon[y][x] = Empty
+				}
+			case '~':
+				// Generators and legends draw water, hand-drawn layouts could mean water or lava
+				if generated || water[Position{X: x, Y: y}] || m.levelRNG.Intn(2) == 0 {
+					m.dungeon[y][x] = Water
+				} else {
+					m.dungeon[y][x] = Lava
+				}
+			default:
+				m.dungeon[y][x] = Empty
+				// Monsters with their own symbols, such as bosses
+				if monster, ok := placed[Position{X: x, Y: y}]; ok {
+					m.dungeon[y][x] = Monster
+					m.monsters = append(m.monsters, monster)
+				}
+			}
+		}
+	}
+
+	// Place the items rolled by the definition, gold stays a plain tile
+	if items, ok := metadata["items"].([]map[string]interface{}); ok {
+		for _, data := range items {
+			id, _ := data["id"].(string)
+			template := m.itemTemplate(id)
+			if template == nil || template.Type == "currency" {
+				continue
+			}
+			m.placeItem(ItemInstance{Pos: metadataPosition(data), Template: *template, Count: 1})
+		}
+	}
+
+	// Arm the traps rolled by the definition, layout traps get the plain dart trap
+	if traps, ok := metadata["traps"].([]map[string]interface{}); ok {
+		for _, data := range traps {
+			id, _ := data["id"].(string)
+			hidden, _ := data["hidden"].(bool)
+			if template := m.trapTemplate(id); template != nil {
+				m.addTrap(metadataPosition(data), *template, hidden)
+			}
+		}
+	}
+	for y := range m.dungeon {
+		for x, tile := range m.dungeon[y] {
+			if (tile == Trap || tile == HiddenTrap) && m.trapAt(x, y) < 0 {
+				m.addTrap(Position{X: x, Y: y}, plainTrap, tile == HiddenTrap)
+			}
+		}
 	}
+
+	// Link the exit and staircases
+	m.stairs = nil
+	m.linkDefinitionStairs(*m.currentLevelDef())
+
+	// Set up the player
+	m.placePlayer(levelStartPosition(metadata))
+
+	return true
+}
+
+// metadataPosition reads a position written by GenerateDungeonFromDefinition
+func metadataPosition(data map[string]interface{}) Position {
+	pos, _ := data["position"].(map[string]int)
+	return Position{X: pos["x"], Y: pos["y"]}
+}
+
+// levelStartPosition reads the player start position from level metadata
+func levelStartPosition(metadata map[string]interface{}) Position {
+	start, _ := metadata["startPos"].(dungeon.Position)
+	return Position{X: start.X, Y: start.Y}
+}
+
+// inBounds reports whether a position lies inside the current level
+func (m model) inBounds(x, y int) bool {
+	return y >= 0 && y < len(m.dungeon) && x >= 0 && x < len(m.dungeon[y])
 }
 
 // Convert the dungeon to a string for display
@@ -430,9 +858,20 @@ func (m model) dungeonToString() string {
 	var result string
 	for y := 0; y < len(m.dungeon); y++ {
 		for x := 0; x < len(m.dungeon[y]); x++ {
-			// If the tile is not visible to the player and revealMap is false, show a blank space
+			// If the tile is not visible to the player and revealMap is false, show a blank
+			// space, or the remembered map without its monsters if it was seen before
 			if !m.revealMap && !m.isVisible(x, y) {
-				result += " "
+				if m.isExplored(x, y) && m.dungeon[y][x] != Monster {
+					result += RenderTile(m.dungeon[y][x])
+				} else {
+					result += " "
+				}
+			} else if m.dungeon[y][x] == Item {
+				result += m.renderItemAt(x, y)
+			} else if m.dungeon[y][x] == Trap {
+				result += m.renderTrapAt(x, y)
+			} else if m.dungeon[y][x] == Station {
+				result += m.renderStationAt(x, y)
 			} else {
 				result += RenderTile(m.dungeon[y][x])
 			}
@@ -464,66 +903,83 @@ func (m *model) movePlayer(dx, dy int) {
 	newY := m.player.Pos.Y + dy
 
 	// Check if the new position is valid
-	if newX < 0 || newX >= m.width || newY < 0 || newY >= m.height {
+	if !m.inBounds(newX, newY) {
+		return
+	}
+
+	// A net holds the player in place until they struggle free
+	if m.status.Netted > 0 && m.dungeon[newY][newX] != Monster {
+		m.struggle()
 		return
 	}
 
 	// Check what's at the new position
 	switch m.dungeon[newY][newX] {
-	case Wall, Water, Lava:
+	case Wall, SecretDoor, Water, Lava:
 		// Can't move through walls or hazards
 		return
+	case Station:
+		if station := m.stationAt(newX, newY); station != nil {
+			m.addMessage(fmt.Sprintf("%s. %s Press c to craft.", station.Name, station.Description))
+		}
+		return
+	case LockedDoor:
+		m.addMessage("The door is sealed shut.")
+		return
+	case Shop:
+		if m.shop = m.shopAt(newX, newY); m.shop != nil && len(m.shop.Wares) == 0 {
+			m.addMessage(fmt.Sprintf("%s has nothing for sale.", m.shop.Name))
+			m.shop = nil
+		}
+		return
+	case Inn:
+		m.rest(newX, newY)
+		m.endTurn()
+		return
+	case Portal:
+		m.enterPortal(newX, newY)
+		m.endTurn()
+		return
 	case Monster:
 		// Attack the monster
 		for i, monster := range m.monsters {
 			if monster.Pos.X == newX && monster.Pos.Y == newY {
-				// Player attacks monster
-				damage := m.player.Damage
-				m.monsters[i].Health -= damage
-				m.addMessage(fmt.Sprintf("You hit the monster for %d damage!", damage))
-
-				// Check if monster is dead
-				if m.monsters[i].Health <= 0 {
-					m.addMessage("You killed the monster!")
-					m.dungeon[newY][newX] = Empty
-					// Remove the monster from the list
-					m.monsters = append(m.monsters[:i], m.monsters[i+1:]...)
-				} else {
-					// Monster attacks back
-					damage := m.monsters[i].Damage
-					m.player.Health -= damage
-					m.addMessage(fmt.Sprintf("The monster hits you for %d damage!", damage))
-
-					// Check if player is dead
-					if m.player.Health <= 0 {
-						m.gameOver = true
-						m.addMessage("You died!")
-					}
+				// Player attacks monster, and a survivor attacks back
+				if !m.playerAttack(i) {
+					m.monsterAttack(i)
 				}
 				break
 			}
 		}
 	case Gold:
 		// Collect gold
-		goldAmount := rand.Intn(10) + 1
+		goldAmount := m.scaleGold(m.rng.Intn(10) + 1)
 		m.gold += goldAmount
 		m.addMessage(fmt.Sprintf("You found %d gold!", goldAmount))
 		m.dungeon[newY][newX] = Empty
 		// Move player
-		m.dungeon[m.player.Pos.Y][m.player.Pos.X] = Empty
+		m.vacate(m.player.Pos.X, m.player.Pos.Y)
+		m.player.Pos.X = newX
+		m.player.Pos.Y = newY
+		m.dungeon[newY][newX] = Player
+	case Item:
+		// Pick up everything lying here
+		m.pickUpItems(newX, newY)
+		// Move player
+		m.vacate(m.player.Pos.X, m.player.Pos.Y)
 		m.player.Pos.X = newX
 		m.player.Pos.Y = newY
 		m.dungeon[newY][newX] = Player
 	case Chest:
 		// Open chest
-		itemType := rand.Intn(3)
+		itemType := m.rng.Intn(3)
 		switch itemType {
 		case 0: // Gold
-			goldAmount := rand.Intn(20) + 10
+			goldAmount := m.rng.Intn(20) + 10
 			m.gold += goldAmount
 			m.addMessage(fmt.Sprintf("You found %d gold in the chest!", goldAmount))
 		case 1: // Health potion
-			healthAmount := rand.Intn(5) + 3
+			healthAmount := m.rng.Intn(5) + 3
 			m.player.Health = min(m.player.Health+healthAmount, m.player.MaxHealth)
 			m.addMessage(fmt.Sprintf("You found a health potion! +%d HP", healthAmount))
 		case 2: // Damage boost
@@ -532,39 +988,30 @@ func (m *model) movePlayer(dx, dy int) {
 		}
 		m.dungeon[newY][newX] = Empty
 		// Move player
-		m.dungeon[m.player.Pos.Y][m.player.Pos.X] = Empty
+		m.vacate(m.player.Pos.X, m.player.Pos.Y)
 		m.player.Pos.X = newX
 		m.player.Pos.Y = newY
 		m.dungeon[newY][newX] = Player
-	case Trap:
-		// Trigger trap
-		damage := rand.Intn(3) + 1
-		m.player.Health -= damage
-		m.addMessage(fmt.Sprintf("You triggered a trap! -%d HP", damage))
-		// Check if player is dead
-		if m.player.Health <= 0 {
-			m.gameOver = true
-			m.addMessage("You died!")
-			return
-		}
-		// Move player
-		m.dungeon[m.player.Pos.Y][m.player.Pos.X] = Empty
+	case Trap, HiddenTrap:
+		// Move player onto the trap and set it off
+		m.vacate(m.player.Pos.X, m.player.Pos.Y)
 		m.player.Pos.X = newX
 		m.player.Pos.Y = newY
 		m.dungeon[newY][newX] = Player
-	case Exit:
-		// Go to next level or win the game
-		if m.level < 3 {
-			m.level++
-			m.addMessage(fmt.Sprintf("You descend to level %d...", m.level))
-			m.generateDungeon()
+		m.triggerTrap(newX, newY)
+		if m.gameOver {
+			return
+		}
+	case Exit, StairsUp, StairsDown:
+		// Take the stairs, or go to the next level if they lead nowhere in particular
+		if m.stairsAt(newX, newY) >= 0 {
+			m.useStairs(newX, newY)
 		} else {
-			m.gameWon = true
-			m.addMessage("You escaped the dungeon!")
+			m.descend()
 		}
 	case Empty, Door:
 		// Move player
-		m.dungeon[m.player.Pos.Y][m.player.Pos.X] = Empty
+		m.vacate(m.player.Pos.X, m.player.Pos.Y)
 		m.player.Pos.X = newX
 		m.player.Pos.Y = newY
 		m.dungeon[newY][newX] = Player
@@ -572,6 +1019,7 @@ func (m *model) movePlayer(dx, dy int) {
 
 	// Move monsters after player's turn
 	m.moveMonsters()
+	m.endTurn()
 }
 
 // Move all monsters
@@ -582,8 +1030,16 @@ func (m *model) moveMonsters() {
 			continue
 		}
 
-		// 50% chance to move
-		if rand.Intn(2) == 0 {
+		// Monsters caught in a net can't move
+		if m.monsters[i].Stuck > 0 {
+			m.monsters[i].Stuck--
+			continue
+		}
+
+		// 50% chance to move, alerted monsters always hunt the player
+		if m.monsters[i].Alert > 0 {
+			m.monsters[i].Alert--
+		} else if m.rng.Intn(2) == 0 {
 			continue
 		}
 
@@ -606,7 +1062,7 @@ func (m *model) moveMonsters() {
 		}
 
 		// Randomly choose to move in x or y direction
-		if rand.Intn(2) == 0 && dx != 0 {
+		if m.rng.Intn(2) == 0 && dx != 0 {
 			dy = 0
 		} else if dy != 0 {
 			dx = 0
@@ -617,31 +1073,36 @@ func (m *model) moveMonsters() {
 		newY := oldY + dy
 
 		// Check if the new position is valid
-		if newX < 0 || newX >= m.width || newY < 0 || newY >= m.height {
+		if !m.inBounds(newX, newY) {
 			continue
 		}
 
 		// Check what's at the new position
 		switch m.dungeon[newY][newX] {
-		case Empty, Gold, Trap, Chest, Door:
+		case Empty, Gold, Trap, HiddenTrap, Chest, Door:
 			// Move monster
-			m.dungeon[oldY][oldX] = Empty
+			onTrap := m.dungeon[newY][newX] == Trap || m.dungeon[newY][newX] == HiddenTrap
+			m.vacate(oldX, oldY)
 			m.monsters[i].Pos.X = newX
 			m.monsters[i].Pos.Y = newY
 			m.dungeon[newY][newX] = Monster
+			if onTrap {
+				m.triggerTrapOnMonster(i)
+			}
 		case Player:
 			// Attack player
-			damage := m.monsters[i].Damage
-			m.player.Health -= damage
-			m.addMessage(fmt.Sprintf("The monster hits you for %d damage!", damage))
-
-			// Check if player is dead
-			if m.player.Health <= 0 {
-				m.gameOver = true
-				m.addMessage("You died!")
-			}
+			m.monsterAttack(i)
 		}
 	}
+
+	// Clear out monsters killed by traps
+	alive := m.monsters[:0]
+	for _, monster := range m.monsters {
+		if monster.Health > 0 {
+			alive = append(alive, monster)
+		}
+	}
+	m.monsters = alive
 }
 
 // Attack all monsters adjacent to the player
@@ -660,7 +1121,7 @@ func (m *model) attackNearbyMonsters() {
 			newY := m.player.Pos.Y + dy
 
 			// Check bounds
-			if newX < 0 || newX >= m.width || newY < 0 || newY >= m.height {
+			if !m.inBounds(newX, newY) {
 				continue
 			}
 
@@ -672,17 +1133,7 @@ func (m *model) attackNearbyMonsters() {
 				for i, monster := range m.monsters {
 					if monster.Pos.X == newX && monster.Pos.Y == newY {
 						// Player attacks monster
-						damage := m.player.Damage
-						m.monsters[i].Health -= damage
-						m.addMessage(fmt.Sprintf("You hit the monster for %d damage!", damage))
-
-						// Check if monster is dead
-						if m.monsters[i].Health <= 0 {
-							m.addMessage("You killed the monster!")
-							m.dungeon[newY][newX] = Empty
-							// Remove the monster from the list
-							m.monsters = append(m.monsters[:i], m.monsters[i+1:]...)
-						}
+						m.playerAttack(i)
 						break
 					}
 				}
@@ -693,6 +1144,34 @@ func (m *model) attackNearbyMonsters() {
 	if !attacked {
 		m.addMessage("You swing at the air!")
 	}
+
+	m.endTurn()
+}
+
+// vacate clears a tile someone is leaving, restoring any trap set in it
+func (m *model) vacate(x, y int) {
+	m.dungeon[y][x] = Empty
+	if i := m.stairsAt(x, y); i >= 0 {
+		m.dungeon[y][x] = m.stairs[i].Tile
+	}
+	if i := m.trapAt(x, y); i >= 0 {
+		if m.traps[i].Hidden {
+			m.dungeon[y][x] = HiddenTrap
+		} else {
+			m.dungeon[y][x] = Trap
+		}
+	}
+}
+
+// endTurn advances the turn counter and the per-turn clocks
+func (m *model) endTurn() {
+	m.turns++
+	m.updateExplored()
+	m.searchAround(passiveSearchChance)
+	m.tickStatus()
+	m.tickHunger()
+	m.regenerateMonsters()
+	m.lockBossRooms()
 }
 
 // Add a message to the message log
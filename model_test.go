@@ -1,7 +1,10 @@
 package main
 
 import (
+	"reflect"
 	"testing"
+
+	"cryptcrawl/internal/dungeon"
 )
 
 func TestInitialModel(t *testing.T) {
@@ -148,3 +151,100 @@ func TestDungeonToString(t *testing.T) {
 		t.Errorf("Expected %d lines in dungeonToString() result, got %d", m.height, lines)
 	}
 }
+
+// newDefinitionModel builds a model playing the first level of a dungeon definition
+func newDefinitionModel(t *testing.T, def *dungeon.DungeonDefinition) model {
+	t.Helper()
+	m := initialModel()
+	m.def = def
+	m.level = 1
+	m.hunger = newHungerClock(def.Hunger)
+	if !m.loadDefinitionLevel() {
+		t.Fatalf("Failed to load level 1 of %q", def.Name)
+	}
+	return m
+}
+
+func TestLoadDefinitionLevel(t *testing.T) {
+	def := dungeon.CreateExampleDungeon()
+	m := newDefinitionModel(t, def)
+
+	start := def.Levels[0].StartPos
+	if m.player.Pos.X != start.X || m.player.Pos.Y != start.Y {
+		t.Errorf("Expected player at %v, got %v", start, m.player.Pos)
+	}
+
+	if m.player.Health <= 0 {
+		t.Errorf("Expected player health to be positive, got %d", m.player.Health)
+	}
+
+	for _, monster := range m.monsters {
+		if monster.TemplateID == "" {
+			continue
+		}
+		if m.monsterTemplate(monster.TemplateID) == nil {
+			t.Errorf("Monster %q has unknown template %q", monster.Name, monster.TemplateID)
+		}
+		if m.dungeon[monster.Pos.Y][monster.Pos.X] != Monster {
+			t.Errorf("Expected monster tile at %v", monster.Pos)
+		}
+	}
+
+	for _, item := range m.items {
+		if m.dungeon[item.Pos.Y][item.Pos.X] != Item {
+			t.Errorf("Expected item tile at %v for %s", item.Pos, item.Name())
+		}
+	}
+}
+
+func TestLoadDefinitionLevelLegend(t *testing.T) {
+	def := dungeon.CreateExampleDungeon()
+	level := &def.Levels[0]
+	level.Encounters, level.Items, level.Traps = nil, nil, nil
+	level.Layout[1] = "#z~~L%...#.........#"
+	level.Legend = map[string]dungeon.LegendEntry{
+		"z": {Monster: "zombie"},
+		"~": {Tile: "water"},
+		"L": {Tile: "lava"},
+	}
+
+	m := newDefinitionModel(t, def)
+	if m.dungeon[1][2] != Water || m.dungeon[1][3] != Water || m.dungeon[1][4] != Lava {
+		t.Errorf("Expected the legend to decide water and lava, got %v", m.dungeon[1][1:5])
+	}
+	if m.dungeon[1][5] != Empty {
+		t.Errorf("Expected %% in a hand-drawn layout to stay floor, got %v", m.dungeon[1][5])
+	}
+	if len(m.monsters) != 1 || m.monsters[0].TemplateID != "zombie" || m.monsters[0].Pos != (Position{X: 1, Y: 1}) {
+		t.Errorf("Expected a zombie where the layout puts it, got %v", m.monsters)
+	}
+}
+
+func TestSeededRunsAreReproducible(t *testing.T) {
+	play := func() model {
+		m := newModel(1234)
+		for _, move := range [][2]int{{1, 0}, {0, 1}, {-1, 0}, {0, -1}, {1, 0}, {1, 0}, {0, 1}} {
+			m.movePlayer(move[0], move[1])
+		}
+		m.search()
+		return m
+	}
+
+	a, b := play(), play()
+	if a.seed != 1234 {
+		t.Errorf("Expected seed 1234, got %d", a.seed)
+	}
+	if !reflect.DeepEqual(a.dungeon, b.dungeon) {
+		t.Error("Expected the same seed and moves to produce the same dungeon")
+	}
+	if !reflect.DeepEqual(a.monsters, b.monsters) || a.player != b.player {
+		t.Error("Expected the same seed and moves to produce the same monsters and player")
+	}
+	if !reflect.DeepEqual(a.messages, b.messages) {
+		t.Errorf("Expected the same messages, got %v and %v", a.messages, b.messages)
+	}
+
+	if c := newModel(4321); reflect.DeepEqual(a.dungeon, c.dungeon) {
+		t.Error("Expected a different seed to produce a different dungeon")
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"cryptcrawl/internal/dungeon"
+)
+
+func TestWithArticle(t *testing.T) {
+	if got := withArticle("skeleton"); got != "a skeleton" {
+		t.Errorf("withArticle(skeleton) = %q", got)
+	}
+	if got := withArticle("imp"); got != "an imp" {
+		t.Errorf("withArticle(imp) = %q", got)
+	}
+}
+
+func TestDeathCause(t *testing.T) {
+	m := initialModel()
+	m.monsters = []Entity{{Pos: Position{X: 1, Y: 1}, Health: 5, MaxHealth: 5, Damage: 50, Accuracy: 50, Name: "Skeleton"}}
+	m.player.Health = 1
+
+	for i := 0; i < 20 && !m.gameOver; i++ {
+		m.monsterAttack(0)
+	}
+	if !m.gameOver {
+		t.Fatal("Expected the skeleton to kill the player")
+	}
+	if m.deathCause != "killed by a skeleton" {
+		t.Errorf("Expected the death cause to name the skeleton, got %q", m.deathCause)
+	}
+
+	m = initialModel()
+	m.player.Health = 1
+	m.status.Poisoned = 3
+	m.status.PoisonDamage = 1
+	m.tickStatus()
+	if m.deathCause != "succumbed to poison" {
+		t.Errorf("Expected death by poison, got %q", m.deathCause)
+	}
+}
+
+func TestMorgue(t *testing.T) {
+	m := initialModel()
+	m.playerName = "alice"
+	m.kills = map[string]int{"skeleton": 3, "zombie": 1}
+	m.addToInventory(ItemInstance{Template: dungeon.ItemTemplate{ID: "bread", Name: "Stale Bread"}, Count: 2})
+	m.die("killed by a skeleton")
+
+	text := m.morgue()
+	for _, want := range []string{
+		"Player:  alice",
+		"Dungeon: Random dungeon",
+		"Killed by a skeleton on level 1",
+		"Kills (4)",
+		"3 skeleton",
+		"2 x Stale Bread",
+		"@",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected the morgue file to contain %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestMorgueStore(t *testing.T) {
+	store, err := newMorgueStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create morgue store: %v", err)
+	}
+	old := morgues
+	morgues = store
+	defer func() { morgues = old }()
+
+	m := initialModel()
+	m.playerKey = "key1"
+	m.playerName = "alice"
+	m.die("fell into a pit")
+	m.finishRun()
+	if m.morgueName == "" {
+		t.Fatal("Expected a morgue file to be written when the run ends")
+	}
+
+	id, _ := saveID("key1")
+	second, err := store.Save(id, "second run")
+	if err != nil {
+		t.Fatalf("Failed to save morgue file: %v", err)
+	}
+
+	names, err := store.List(id)
+	if err != nil {
+		t.Fatalf("Failed to list morgue files: %v", err)
+	}
+	if len(names) != 2 || names[0] != second {
+		t.Fatalf("Expected 2 morgue files, newest first, got %v", names)
+	}
+
+	text, err := store.Load(id, names[1])
+	if err != nil {
+		t.Fatalf("Failed to load morgue file: %v", err)
+	}
+	if !strings.Contains(text, "Fell into a pit") {
+		t.Errorf("Expected the first morgue file to describe the death, got:\n%s", text)
+	}
+
+	viewer := newMorgueModel("key1")
+	if len(viewer.pages) != 2 {
+		t.Errorf("Expected the viewer to show 2 morgue files, got %d", len(viewer.pages))
+	}
+}
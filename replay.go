@@ -0,0 +1,421 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// action is a single player action, stored as one character in replay files
+type action byte
+
+// Player actions
+const (
+	actionUp     action = 'u'
+	actionDown   action = 'd'
+	actionLeft   action = 'l'
+	actionRight  action = 'r'
+	actionAttack action = 'a'
+	actionEat    action = 'e'
+	actionSearch action = 'f'
+	actionDisarm action = 'x'
+
+	// Using the item in inventory slot i is stored as actionUseFirst+i
+	actionUseFirst action = 'A'
+	maxUseSlots           = 26
+
+	// Crafting the recipe at index i is stored as actionCraftFirst+i
+	actionCraftFirst action = '0'
+	maxRecipes              = 16
+)
+
+// act performs a player action and records it for the replay
+func (m *model) act(a action) {
+	if m.gameOver || m.gameWon {
+		return
+	}
+	m.actions = append(m.actions, a)
+	m.apply(a)
+}
+
+// apply performs a player action without recording it
+func (m *model) apply(a action) {
+	switch a {
+	case actionUp:
+		m.movePlayer(0, -1)
+	case actionDown:
+		m.movePlayer(0, 1)
+	case actionLeft:
+		m.movePlayer(-1, 0)
+	case actionRight:
+		m.movePlayer(1, 0)
+	case actionAttack:
+		m.attackNearbyMonsters()
+	case actionEat:
+		m.eat()
+	case actionSearch:
+		m.search()
+	case actionDisarm:
+		m.disarm()
+	default:
+		switch {
+		case a >= actionUseFirst && a < actionUseFirst+maxUseSlots:
+			m.useItem(int(a - actionUseFirst))
+		case a >= actionCraftFirst && a < actionCraftFirst+maxRecipes:
+			m.craft(int(a - actionCraftFirst))
+		case a >= actionBuyFirst && a < actionBuyFirst+maxWares:
+			m.buy(int(a - actionBuyFirst))
+		}
+	}
+}
+
+// replayVersion is the version of the replay file format written by this build.
+// Version 2 builds every level from its own generator, so older replays no
+// longer play back the same.
+const replayVersion = 2
+
+// errNoReplay is returned when a replay doesn't exist
+var errNoReplay = errors.New("no such replay")
+
+// Global replay store
+var replays *replayStore
+
+// replayFile is the on-disk format of a replay: the seed and every action taken
+type replayFile struct {
+	Version  int       `json:"version"`
+	Recorded time.Time `json:"recorded"`
+	Player   string    `json:"player,omitempty"`
+	Dungeon  string    `json:"dungeon,omitempty"` // Dungeon definition name, empty for random dungeons
+	Seed     int64     `json:"seed"`
+	Endless  bool      `json:"endless,omitempty"`
+	Campaign bool      `json:"campaign,omitempty"` // Whether the run started in the hub town
+	Actions  string    `json:"actions"`
+}
+
+// replay returns the replay of the run so far
+func (m model) replay() replayFile {
+	r := replayFile{
+		Version:  replayVersion,
+		Recorded: time.Now().UTC(),
+		Player:   m.playerName,
+		Seed:     m.seed,
+		Endless:  m.endless,
+		Actions:  string(m.actions),
+	}
+	switch {
+	case m.hub != nil:
+		r.Campaign = true
+	case m.def != nil:
+		r.Dungeon = m.def.Name
+	}
+	return r
+}
+
+// start creates the game the replay was recorded in, before any action was taken
+func (r replayFile) start() (model, error) {
+	if r.Version > replayVersion {
+		return model{}, fmt.Errorf("replay version %d is newer than supported version %d", r.Version, replayVersion)
+	}
+	if r.Version < replayVersion {
+		return model{}, fmt.Errorf("replay version %d was recorded by an older build and can't be played back", r.Version)
+	}
+	if r.Campaign {
+		hub, err := findHub()
+		if err != nil {
+			return model{}, err
+		}
+		return newCampaignModel(r.Seed, hub), nil
+	}
+	def, err := findDungeon(r.Dungeon)
+	if err != nil {
+		return model{}, err
+	}
+	m := newGame(r.Seed, def)
+	m.endless = r.Endless
+	return m, nil
+}
+
+// storeReplay writes the replay of a finished run to the replay store
+func (m *model) storeReplay() {
+	if replays == nil || len(m.actions) == 0 {
+		return
+	}
+	id, err := replays.Save(m.replay())
+	if err != nil {
+		m.addMessage(fmt.Sprintf("Could not save the replay: %v", err))
+		return
+	}
+	m.replayID = id
+}
+
+// replayStore keeps replays on disk, one file per replay
+type replayStore struct {
+	dir string
+}
+
+// newReplayStore creates a replay store in the given directory
+func newReplayStore(dir string) (*replayStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create replays directory: %w", err)
+	}
+	return &replayStore{dir: dir}, nil
+}
+
+// validReplayID reports whether an ID looks like one handed out by the store
+func validReplayID(id string) bool {
+	if len(id) != 12 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+// Save writes a replay and returns the ID to share it by
+func (s *replayStore) Save(r replayFile) (string, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode replay: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	id := hex.EncodeToString(sum[:6])
+	if err := os.WriteFile(filepath.Join(s.dir, id+".json"), data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write replay: %w", err)
+	}
+	return id, nil
+}
+
+// Load reads a replay by ID
+func (s *replayStore) Load(id string) (replayFile, error) {
+	var r replayFile
+	if !validReplayID(id) {
+		return r, errNoReplay
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.dir, id+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return r, errNoReplay
+	}
+	if err != nil {
+		return r, fmt.Errorf("failed to read replay: %w", err)
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return r, fmt.Errorf("failed to parse replay: %w", err)
+	}
+	return r, nil
+}
+
+// Playback speeds, as the delay between actions
+var replaySpeeds = []time.Duration{
+	time.Second,
+	500 * time.Millisecond,
+	250 * time.Millisecond,
+	100 * time.Millisecond,
+	50 * time.Millisecond,
+	20 * time.Millisecond,
+}
+
+// replaySeekStep is how many actions a seek skips
+const replaySeekStep = 50
+
+// replayKeyMap defines the replay viewer's key bindings
+type replayKeyMap struct {
+	Play      key.Binding
+	Forward   key.Binding
+	Back      key.Binding
+	SkipAhead key.Binding
+	SkipBack  key.Binding
+	Start     key.Binding
+	End       key.Binding
+	Faster    key.Binding
+	Slower    key.Binding
+	Quit      key.Binding
+}
+
+var replayKeys = replayKeyMap{
+	Play:      key.NewBinding(key.WithKeys("space", "p"), key.WithHelp("space", "play/pause")),
+	Forward:   key.NewBinding(key.WithKeys("right", "l"), key.WithHelp("→", "step")),
+	Back:      key.NewBinding(key.WithKeys("left", "h"), key.WithHelp("←", "step back")),
+	SkipAhead: key.NewBinding(key.WithKeys("]"), key.WithHelp("]", "skip ahead")),
+	SkipBack:  key.NewBinding(key.WithKeys("["), key.WithHelp("[", "skip back")),
+	Start:     key.NewBinding(key.WithKeys("home", "g"), key.WithHelp("g", "start")),
+	End:       key.NewBinding(key.WithKeys("end", "G"), key.WithHelp("G", "end")),
+	Faster:    key.NewBinding(key.WithKeys("+", "="), key.WithHelp("+", "faster")),
+	Slower:    key.NewBinding(key.WithKeys("-"), key.WithHelp("-", "slower")),
+	Quit:      key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "quit")),
+}
+
+// replayTickMsg advances a playing replay by one action
+type replayTickMsg struct {
+	tick int
+}
+
+// replayModel plays back a recorded run
+type replayModel struct {
+	replay  replayFile
+	id      string
+	game    model
+	pos     int // Number of actions applied so far
+	playing bool
+	speed   int // Index into replaySpeeds
+	tick    int // Identifies the current playback timer, so stale ticks are dropped
+	err     error
+}
+
+// newReplayModel creates a replay viewer that starts playing right away
+func newReplayModel(r replayFile, id string) replayModel {
+	rm := replayModel{replay: r, id: id, playing: true, speed: 2}
+	rm.game, rm.err = r.start()
+	rm.refresh()
+	return rm
+}
+
+// Init starts playback
+func (r replayModel) Init() tea.Cmd {
+	return r.schedule()
+}
+
+// schedule queues the next playback step
+func (r replayModel) schedule() tea.Cmd {
+	if !r.playing || r.err != nil {
+		return nil
+	}
+	tick := r.tick
+	return tea.Tick(replaySpeeds[r.speed], func(time.Time) tea.Msg {
+		return replayTickMsg{tick: tick}
+	})
+}
+
+// step applies the next recorded action
+func (r *replayModel) step() {
+	if r.err != nil || r.pos >= len(r.replay.Actions) {
+		return
+	}
+	r.game.apply(action(r.replay.Actions[r.pos]))
+	r.pos++
+	r.refresh()
+}
+
+// seek jumps to the point where n actions have been taken, replaying from the
+// start when going backwards
+func (r *replayModel) seek(n int) {
+	if r.err != nil {
+		return
+	}
+	n = max(0, min(n, len(r.replay.Actions)))
+	if n < r.pos {
+		game, err := r.replay.start()
+		if err != nil {
+			r.err = err
+			return
+		}
+		game.viewport = r.game.viewport
+		r.game = game
+		r.pos = 0
+	}
+	for r.pos < n {
+		r.game.apply(action(r.replay.Actions[r.pos]))
+		r.pos++
+	}
+	r.refresh()
+}
+
+// refresh redraws the game's viewport after the game has changed
+func (r *replayModel) refresh() {
+	if r.err == nil {
+		r.game.viewport.SetContent(r.game.dungeonToString())
+	}
+}
+
+// Update handles playback controls and timer ticks
+func (r replayModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case replayTickMsg:
+		if msg.tick != r.tick || !r.playing {
+			return r, nil
+		}
+		r.step()
+		if r.pos >= len(r.replay.Actions) {
+			r.playing = false
+		}
+		return r, r.schedule()
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, replayKeys.Quit):
+			return r, tea.Quit
+		case key.Matches(msg, replayKeys.Play):
+			r.playing = !r.playing
+			if r.playing && r.pos >= len(r.replay.Actions) {
+				r.seek(0)
+			}
+		case key.Matches(msg, replayKeys.Forward):
+			r.playing = false
+			r.step()
+		case key.Matches(msg, replayKeys.Back):
+			r.playing = false
+			r.seek(r.pos - 1)
+		case key.Matches(msg, replayKeys.SkipAhead):
+			r.seek(r.pos + replaySeekStep)
+		case key.Matches(msg, replayKeys.SkipBack):
+			r.seek(r.pos - replaySeekStep)
+		case key.Matches(msg, replayKeys.Start):
+			r.seek(0)
+		case key.Matches(msg, replayKeys.End):
+			r.playing = false
+			r.seek(len(r.replay.Actions))
+		case key.Matches(msg, replayKeys.Faster):
+			r.speed = min(r.speed+1, len(replaySpeeds)-1)
+		case key.Matches(msg, replayKeys.Slower):
+			r.speed = max(r.speed-1, 0)
+		default:
+			return r, nil
+		}
+		// Restart the timer so the new state and speed take effect now
+		r.tick++
+		return r, r.schedule()
+	case tea.WindowSizeMsg:
+		r.game.viewport.Width = msg.Width
+		r.game.viewport.Height = msg.Height - 6 // Leave room for messages, status and controls
+	}
+	return r, nil
+}
+
+// View renders the game as it was at the current point of the replay
+func (r replayModel) View() string {
+	if r.err != nil {
+		return fmt.Sprintf("\n\n  Can't play this replay: %v\n\n  Press q to quit.", r.err)
+	}
+
+	title := "Replay"
+	if r.id != "" {
+		title += " " + r.id
+	}
+	if r.replay.Player != "" {
+		title += " by " + r.replay.Player
+	}
+
+	state := "paused"
+	if r.playing {
+		state = fmt.Sprintf("playing %s/step", replaySpeeds[r.speed])
+	}
+	bar := fmt.Sprintf("%s | step %d/%d | %s", title, r.pos, len(r.replay.Actions), state)
+
+	var controls []string
+	for _, b := range []key.Binding{replayKeys.Play, replayKeys.Back, replayKeys.Forward, replayKeys.SkipBack, replayKeys.SkipAhead, replayKeys.Faster, replayKeys.Slower, replayKeys.Quit} {
+		controls = append(controls, b.Help().Key+" "+b.Help().Desc)
+	}
+
+	barStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#00aaff")).Bold(true)
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#888888"))
+	return fmt.Sprintf("%s  %s\n  %s", r.game.playView(), barStyle.Render(bar), helpStyle.Render(strings.Join(controls, " • ")))
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+var testActions = []action{actionRight, actionDown, actionSearch, actionLeft, actionUp, actionAttack, actionRight, actionRight, actionEat, actionDisarm}
+
+func TestUpdateRecordsActions(t *testing.T) {
+	m := newModel(5)
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRight})
+	updated, _ = updated.(model).Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'f'}})
+	updated, _ = updated.(model).Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'?'}})
+
+	got := updated.(model).actions
+	if want := []action{actionRight, actionSearch}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected recorded actions %q, got %q", want, got)
+	}
+
+	m.gameOver = true
+	m.act(actionUp)
+	if len(m.actions) != 0 {
+		t.Error("Expected no actions to be recorded after the game is over")
+	}
+}
+
+func TestReplayReproducesGame(t *testing.T) {
+	m := newModel(5)
+	for _, a := range testActions {
+		m.act(a)
+	}
+
+	game, err := m.replay().start()
+	if err != nil {
+		t.Fatalf("Failed to start replay: %v", err)
+	}
+	for _, a := range m.actions {
+		game.apply(a)
+	}
+
+	if !reflect.DeepEqual(m.dungeon, game.dungeon) || m.player != game.player {
+		t.Error("Expected the replay to end in the same state as the game")
+	}
+	if !reflect.DeepEqual(m.messages, game.messages) {
+		t.Errorf("Expected the replay to produce the same messages, got %v and %v", m.messages, game.messages)
+	}
+
+	old := m.replay()
+	old.Version = replayVersion - 1
+	if _, err := old.start(); err == nil {
+		t.Error("Expected a replay recorded by an older build to be refused")
+	}
+}
+
+func TestReplaySeek(t *testing.T) {
+	m := newModel(5)
+	for _, a := range testActions {
+		m.act(a)
+	}
+
+	r := newReplayModel(m.replay(), "")
+	r.seek(8)
+	r.seek(3)
+
+	fresh := newReplayModel(m.replay(), "")
+	fresh.seek(3)
+
+	if r.pos != 3 {
+		t.Errorf("Expected to be at step 3, got %d", r.pos)
+	}
+	if !reflect.DeepEqual(r.game.dungeon, fresh.game.dungeon) || r.game.player != fresh.game.player {
+		t.Error("Expected seeking back to give the same state as playing forward")
+	}
+
+	r.seek(1000)
+	if r.pos != len(testActions) {
+		t.Errorf("Expected seeking past the end to stop at %d, got %d", len(testActions), r.pos)
+	}
+}
+
+func TestReplayStore(t *testing.T) {
+	store, err := newReplayStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create replay store: %v", err)
+	}
+
+	m := newModel(5)
+	for _, a := range testActions {
+		m.act(a)
+	}
+
+	id, err := store.Save(m.replay())
+	if err != nil {
+		t.Fatalf("Failed to save replay: %v", err)
+	}
+	r, err := store.Load(id)
+	if err != nil {
+		t.Fatalf("Failed to load replay: %v", err)
+	}
+	if r.Seed != 5 || r.Actions != string(m.actions) {
+		t.Errorf("Expected the loaded replay to match, got seed %d actions %q", r.Seed, r.Actions)
+	}
+
+	for _, bad := range []string{"", "../../etc/passwd", "zzzzzzzzzzzz", "000000000000"} {
+		if _, err := store.Load(bad); !errors.Is(err, errNoReplay) {
+			t.Errorf("Expected no replay for %q, got %v", bad, err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// playMoves walks the player around and searches, advancing the game a few turns
+func playMoves(m *model) {
+	for _, move := range [][2]int{{1, 0}, {0, 1}, {-1, 0}, {0, -1}, {1, 0}, {0, 1}} {
+		m.movePlayer(move[0], move[1])
+	}
+	m.search()
+}
+
+func TestSaveAndRestoreGame(t *testing.T) {
+	m := newModel(99)
+	playMoves(&m)
+	m.act(actionSearch)
+
+	data, err := json.Marshal(m.snapshot())
+	if err != nil {
+		t.Fatalf("Failed to encode save: %v", err)
+	}
+	var save saveFile
+	if err := json.Unmarshal(data, &save); err != nil {
+		t.Fatalf("Failed to decode save: %v", err)
+	}
+
+	restored, err := restoreGame(save)
+	if err != nil {
+		t.Fatalf("Failed to restore game: %v", err)
+	}
+
+	if !reflect.DeepEqual(m.dungeon, restored.dungeon) {
+		t.Error("Expected the restored map to match")
+	}
+	if m.player != restored.player || m.level != restored.level || m.turns != restored.turns {
+		t.Error("Expected the restored player, level and turns to match")
+	}
+	if !reflect.DeepEqual(m.messages, restored.messages) {
+		t.Error("Expected the restored messages to match")
+	}
+	if !reflect.DeepEqual(m.actions, restored.actions) {
+		t.Error("Expected the recorded actions to be restored for the replay")
+	}
+
+	// The RNG picks up where it left off, so both games keep playing the same
+	playMoves(&m)
+	playMoves(&restored)
+	if !reflect.DeepEqual(m.dungeon, restored.dungeon) || !reflect.DeepEqual(m.monsters, restored.monsters) {
+		t.Error("Expected the restored game to continue exactly like the original")
+	}
+	if m.rngSource.draws != restored.rngSource.draws {
+		t.Errorf("Expected the same RNG draws, got %d and %d", m.rngSource.draws, restored.rngSource.draws)
+	}
+}
+
+func TestRestoreGameRejectsNewerVersion(t *testing.T) {
+	save := newModel(1).snapshot()
+	save.Version = saveVersion + 1
+	if _, err := restoreGame(save); err == nil {
+		t.Error("Expected a newer save version to be rejected")
+	}
+}
+
+func TestSaveStore(t *testing.T) {
+	store, err := newSaveStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create save store: %v", err)
+	}
+	old := saves
+	saves = store
+	defer func() { saves = old }()
+
+	m := newModel(7)
+	m.playerKey = "key1"
+	m.playerName = "alice"
+	if err := m.saveGame(); err != nil {
+		t.Fatalf("Failed to save game: %v", err)
+	}
+
+	resumed, err := resumeGame("key1", "alice")
+	if err != nil {
+		t.Fatalf("Failed to resume game: %v", err)
+	}
+	if resumed.seed != 7 {
+		t.Errorf("Expected the resumed game to have seed 7, got %d", resumed.seed)
+	}
+	if _, err := resumeGame("key2", "alice"); !errors.Is(err, errNoSave) {
+		t.Errorf("Expected no save for another player, got %v", err)
+	}
+
+	// Anyone can claim a user name, so players without a key aren't saved
+	keyless := newModel(8)
+	keyless.playerName = "alice"
+	if err := keyless.saveGame(); err != nil {
+		t.Fatalf("Failed to save game: %v", err)
+	}
+	if _, err := resumeGame("", "alice"); !errors.Is(err, errNoSave) {
+		t.Errorf("Expected no save for a player without a key, got %v", err)
+	}
+	if resumed, err := resumeGame("key1", "alice"); err != nil || resumed.seed != 7 {
+		t.Errorf("Expected a keyless game not to overwrite the save, got seed %d, %v", resumed.seed, err)
+	}
+
+	// A finished run is removed rather than saved
+	m.gameOver = true
+	if err := m.saveGame(); err != nil {
+		t.Fatalf("Failed to save game: %v", err)
+	}
+	if _, err := resumeGame("key1", "alice"); !errors.Is(err, errNoSave) {
+		t.Errorf("Expected the save to be removed after death, got %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+// Search chances per hidden tile in the player's neighbourhood
+const (
+	activeSearchChance  = 0.5
+	passiveSearchChance = 0.05
+)
+
+// search spends a turn looking for hidden doors and traps around the player
+func (m *model) search() {
+	if !m.searchAround(activeSearchChance) {
+		m.addMessage("You search but find nothing.")
+	}
+	m.moveMonsters()
+	m.endTurn()
+}
+
+// searchAround rolls to discover each hidden tile next to the player
+func (m *model) searchAround(chance float64) bool {
+	found := false
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			x := m.player.Pos.X + dx
+			y := m.player.Pos.Y + dy
+			if !m.inBounds(x, y) {
+				continue
+			}
+
+			switch m.dungeon[y][x] {
+			case SecretDoor:
+				if m.rng.Float64() < chance {
+					m.dungeon[y][x] = Door
+					m.addMessage("You find a hidden door!")
+					found = true
+				}
+			case HiddenTrap:
+				if m.rng.Float64() < chance {
+					m.revealTrap(x, y)
+					m.addMessage("You find a hidden trap!")
+					found = true
+				}
+			}
+		}
+	}
+	return found
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+)
+
+// surroundWithSecrets clears the player's neighbourhood and hides a door and a trap in it
+func surroundWithSecrets(m *model) (Position, Position) {
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			if dx != 0 || dy != 0 {
+				m.dungeon[m.player.Pos.Y+dy][m.player.Pos.X+dx] = Wall
+			}
+		}
+	}
+	door := Position{X: m.player.Pos.X + 1, Y: m.player.Pos.Y}
+	trap := Position{X: m.player.Pos.X - 1, Y: m.player.Pos.Y}
+	m.dungeon[door.Y][door.X] = SecretDoor
+	m.dungeon[trap.Y][trap.X] = HiddenTrap
+	m.monsters = nil
+	return door, trap
+}
+
+func TestSearchAround(t *testing.T) {
+	m := initialModel()
+	door, trap := surroundWithSecrets(&m)
+
+	if m.searchAround(0) {
+		t.Error("Expected a search with no chance to find nothing")
+	}
+
+	if !m.searchAround(1) {
+		t.Fatal("Expected a certain search to find something")
+	}
+
+	if m.dungeon[door.Y][door.X] != Door {
+		t.Errorf("Expected the secret door to be revealed, got %v", m.dungeon[door.Y][door.X])
+	}
+
+	if m.dungeon[trap.Y][trap.X] != Trap {
+		t.Errorf("Expected the hidden trap to be revealed, got %v", m.dungeon[trap.Y][trap.X])
+	}
+}
+
+func TestSearchTakesATurn(t *testing.T) {
+	m := initialModel()
+	surroundWithSecrets(&m)
+	turns := m.turns
+
+	m.search()
+
+	if m.turns != turns+1 {
+		t.Errorf("Expected search to take a turn, turns went from %d to %d", turns, m.turns)
+	}
+}
+
+func TestSecretDoorBlocksMovement(t *testing.T) {
+	m := initialModel()
+	door, _ := surroundWithSecrets(&m)
+	start := m.player.Pos
+
+	m.movePlayer(1, 0)
+	if m.player.Pos != start {
+		t.Errorf("Expected the secret door to block movement, player moved to %v", m.player.Pos)
+	}
+
+	m.dungeon[door.Y][door.X] = Door
+	m.movePlayer(1, 0)
+	if m.player.Pos != door {
+		t.Errorf("Expected the player to walk through the found door, got %v", m.player.Pos)
+	}
+}
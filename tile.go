@@ -20,6 +20,16 @@ const (
 	Door
 	Water
 	Lava
+	Item
+	SecretDoor
+	HiddenTrap
+	StairsUp
+	StairsDown
+	Station
+	LockedDoor
+	Portal
+	Shop
+	Inn
 )
 
 // Tile represents a dungeon tile with a type and visual representation
@@ -110,6 +120,82 @@ var TileMap = map[TileType]Tile{
 		Walkable:    false,
 		Description: "Deadly lava.",
 	},
+	Item: {
+		Type:        Item,
+		Symbol:      '!',
+		Style:       lipgloss.NewStyle().Foreground(lipgloss.Color("#ffffff")),
+		Walkable:    true,
+		Description: "Something lying on the floor.",
+	},
+	SecretDoor: {
+		Type:        SecretDoor,
+		Symbol:      '=',
+		Style:       lipgloss.NewStyle().Foreground(lipgloss.Color("#666666")).Background(lipgloss.Color("#333333")),
+		Walkable:    false,
+		Description: "A door hidden in the wall.",
+	},
+	HiddenTrap: {
+		Type:        HiddenTrap,
+		Symbol:      ';',
+		Style:       lipgloss.NewStyle(),
+		Walkable:    true,
+		Description: "A trap nobody has noticed yet.",
+	},
+	StairsUp: {
+		Type:        StairsUp,
+		Symbol:      '<',
+		Style:       lipgloss.NewStyle().Foreground(lipgloss.Color("#00ff00")).Bold(true),
+		Walkable:    true,
+		Description: "A staircase leading up.",
+	},
+	StairsDown: {
+		Type:        StairsDown,
+		Symbol:      '>',
+		Style:       lipgloss.NewStyle().Foreground(lipgloss.Color("#00ff00")).Bold(true),
+		Walkable:    true,
+		Description: "A staircase leading down.",
+	},
+	Station: {
+		Type:        Station,
+		Symbol:      '&',
+		Style:       lipgloss.NewStyle().Foreground(lipgloss.Color("#ddddaa")).Bold(true),
+		Walkable:    false,
+		Description: "A crafting station.",
+	},
+	LockedDoor: {
+		Type:        LockedDoor,
+		Symbol:      '+',
+		Style:       lipgloss.NewStyle().Foreground(lipgloss.Color("#ff0000")).Bold(true),
+		Walkable:    false,
+		Description: "A door sealed until the room's guardian falls.",
+	},
+	Portal: {
+		Type:        Portal,
+		Symbol:      'O',
+		Style:       lipgloss.NewStyle().Foreground(lipgloss.Color("#aa55ff")).Bold(true),
+		Walkable:    true,
+		Description: "A shimmering portal to a dungeon.",
+	},
+	Shop: {
+		Type:        Shop,
+		Symbol:      'T',
+		Style:       lipgloss.NewStyle().Foreground(lipgloss.Color("#ffff00")).Bold(true),
+		Walkable:    false,
+		Description: "A shop counter.",
+	},
+	Inn: {
+		Type:        Inn,
+		Symbol:      'I',
+		Style:       lipgloss.NewStyle().Foreground(lipgloss.Color("#ffaa00")).Bold(true),
+		Walkable:    false,
+		Description: "An inn where weary adventurers rest.",
+	},
+}
+
+// DisguisedTiles maps hidden tiles to the tile they look like until discovered
+var DisguisedTiles = map[TileType]TileType{
+	SecretDoor: Wall,
+	HiddenTrap: Empty,
 }
 
 // GetTileBySymbol returns a tile by its symbol
@@ -129,6 +215,9 @@ func GetTileByType(tileType TileType) Tile {
 
 // RenderTile returns a styled string representation of a tile
 func RenderTile(tileType TileType) string {
+	if disguise, ok := DisguisedTiles[tileType]; ok {
+		tileType = disguise
+	}
 	tile := TileMap[tileType]
 	return tile.Style.Render(string(tile.Symbol))
 }
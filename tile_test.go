@@ -77,7 +77,7 @@ func TestRenderSymbol(t *testing.T) {
 
 func TestTileMapCompleteness(t *testing.T) {
 	// Ensure all tile types have an entry in the map
-	for i := TileType(0); i <= Lava; i++ {
+	for i := TileType(0); i <= Inn; i++ {
 		if _, ok := TileMap[i]; !ok {
 			t.Errorf("TileType %d is not defined in TileMap", i)
 		}
@@ -103,3 +103,11 @@ func TestTileProperties(t *testing.T) {
 		// The style is initialized in the TileMap
 	}
 }
+
+func TestRenderDisguisedTiles(t *testing.T) {
+	for hidden, disguise := range DisguisedTiles {
+		if RenderTile(hidden) != RenderTile(disguise) {
+			t.Errorf("RenderTile(%v) should look like RenderTile(%v)", hidden, disguise)
+		}
+	}
+}
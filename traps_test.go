@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"cryptcrawl/internal/dungeon"
+)
+
+// newTrapModel returns a model with an empty room around the player and a trap to its right
+func newTrapModel(t *testing.T, template dungeon.TrapTemplate, hidden bool) (model, Position) {
+	t.Helper()
+	m := initialModel()
+	m.monsters = nil
+	m.traps = nil
+	for y := range m.dungeon {
+		for x := range m.dungeon[y] {
+			m.dungeon[y][x] = Empty
+		}
+	}
+	m.player.Pos = Position{X: 10, Y: 10}
+	m.dungeon[10][10] = Player
+	pos := Position{X: 11, Y: 10}
+	m.addTrap(pos, template, hidden)
+	return m, pos
+}
+
+func trapTemplate(t *testing.T, id string) dungeon.TrapTemplate {
+	t.Helper()
+	for _, trap := range dungeon.DefaultTrapTemplates() {
+		if trap.ID == id {
+			return trap
+		}
+	}
+	t.Fatalf("No built-in trap %q", id)
+	return dungeon.TrapTemplate{}
+}
+
+func TestTrapSurvivesBeingWalkedOver(t *testing.T) {
+	m, pos := newTrapModel(t, trapTemplate(t, "alarm_trap"), true)
+
+	if m.dungeon[pos.Y][pos.X] != HiddenTrap {
+		t.Fatalf("Expected a hidden trap tile, got %v", m.dungeon[pos.Y][pos.X])
+	}
+
+	m.movePlayer(1, 0)
+	if m.traps[0].Hidden {
+		t.Error("Expected stepping on the trap to reveal it")
+	}
+
+	m.movePlayer(1, 0)
+	if m.dungeon[pos.Y][pos.X] != Trap {
+		t.Errorf("Expected the trap to remain after leaving it, got %v", m.dungeon[pos.Y][pos.X])
+	}
+}
+
+func TestDartTrap(t *testing.T) {
+	template := trapTemplate(t, "dart_trap")
+	m, _ := newTrapModel(t, template, false)
+	health := m.player.Health
+
+	m.movePlayer(1, 0)
+
+	if m.player.Health != health-template.Damage {
+		t.Errorf("Expected dart damage %d, health went from %d to %d", template.Damage, health, m.player.Health)
+	}
+}
+
+func TestPitTrapInDeepDungeon(t *testing.T) {
+	def := dungeon.CreateExampleDungeon()
+	for i := 2; i <= 5; i++ {
+		level := def.Levels[0]
+		level.ID = fmt.Sprintf("level%d", i)
+		level.Encounters, level.Traps = nil, nil
+		def.Levels = append(def.Levels, level)
+	}
+	m := newDefinitionModel(t, def)
+	m.player.Health = 100
+	pit := trapTemplate(t, "pit")
+
+	m.travel(3)
+	m.springTrap(pit)
+	if m.level != 4 {
+		t.Fatalf("Expected a pit on level 3 of 5 to drop to level 4, got %d", m.level)
+	}
+
+	// The last level has nothing below it
+	m.travel(m.maxDepth())
+	m.springTrap(pit)
+	if m.level != m.maxDepth() || m.gameWon {
+		t.Errorf("Expected a pit on the last level to leave the player there, got level %d", m.level)
+	}
+}
+
+func TestTeleportTrap(t *testing.T) {
+	m, pos := newTrapModel(t, trapTemplate(t, "teleport_trap"), false)
+
+	m.movePlayer(1, 0)
+
+	if m.player.Pos == pos {
+		t.Error("Expected the player to be teleported away from the trap")
+	}
+	if m.dungeon[m.player.Pos.Y][m.player.Pos.X] != Player {
+		t.Error("Expected the player tile at the new position")
+	}
+}
+
+func TestNetTrap(t *testing.T) {
+	template := trapTemplate(t, "net_trap")
+	m, pos := newTrapModel(t, template, false)
+
+	m.movePlayer(1, 0)
+	if m.status.Netted != template.Duration {
+		t.Fatalf("Expected to be netted for %d turns, got %d", template.Duration, m.status.Netted)
+	}
+
+	for i := 0; i < template.Duration; i++ {
+		m.movePlayer(1, 0)
+		if m.player.Pos != pos {
+			t.Fatalf("Expected the net to hold the player, moved to %v", m.player.Pos)
+		}
+	}
+
+	m.movePlayer(1, 0)
+	if m.player.Pos == pos {
+		t.Error("Expected the player to be free of the net")
+	}
+}
+
+func TestPoisonGasTrap(t *testing.T) {
+	template := trapTemplate(t, "gas_trap")
+	m, _ := newTrapModel(t, template, false)
+	health := m.player.Health
+
+	m.movePlayer(1, 0)
+
+	// The poison ticks once at the end of the turn that set it off
+	if m.player.Health != health-template.Damage {
+		t.Errorf("Expected poison damage %d, health went from %d to %d", template.Damage, health, m.player.Health)
+	}
+	if m.status.Poisoned != template.Duration-1 {
+		t.Errorf("Expected %d poisoned turns left, got %d", template.Duration-1, m.status.Poisoned)
+	}
+}
+
+func TestAlarmTrap(t *testing.T) {
+	template := trapTemplate(t, "alarm_trap")
+	m, _ := newTrapModel(t, template, false)
+	m.monsters = []Entity{{Pos: Position{X: 1, Y: 1}, Health: 5, MaxHealth: 5, Name: "Monster"}}
+	m.dungeon[1][1] = Monster
+
+	m.movePlayer(1, 0)
+
+	if m.monsters[0].Alert == 0 {
+		t.Error("Expected the alarm to alert the monster")
+	}
+	if m.monsters[0].Pos == (Position{X: 1, Y: 1}) {
+		t.Error("Expected the alerted monster to move towards the player")
+	}
+}
+
+func TestMonsterTriggersTrap(t *testing.T) {
+	template := trapTemplate(t, "dart_trap")
+	m, pos := newTrapModel(t, template, true)
+	m.player.Pos = Position{X: 13, Y: 10}
+	m.dungeon[10][10] = Empty
+	m.dungeon[10][13] = Player
+	m.monsters = []Entity{{Pos: Position{X: 10, Y: 10}, Health: 1, MaxHealth: 1, Name: "Skeleton"}}
+	m.dungeon[10][10] = Monster
+
+	// Move the monster onto the trap by hand
+	m.vacate(10, 10)
+	m.monsters[0].Pos = pos
+	m.dungeon[pos.Y][pos.X] = Monster
+	m.triggerTrapOnMonster(0)
+
+	if m.monsters[0].Health > 0 {
+		t.Error("Expected the dart to kill the monster")
+	}
+	if m.dungeon[pos.Y][pos.X] != Trap {
+		t.Errorf("Expected the visible trap to be revealed under the dead monster, got %v", m.dungeon[pos.Y][pos.X])
+	}
+}
+
+func TestDisarm(t *testing.T) {
+	template := trapTemplate(t, "dart_trap")
+	template.DisarmChance = 1
+	m, pos := newTrapModel(t, template, true)
+
+	m.disarm()
+	if len(m.traps) != 1 {
+		t.Fatal("Expected a hidden trap not to be disarmable")
+	}
+
+	m.revealTrap(pos.X, pos.Y)
+	m.disarm()
+	if len(m.traps) != 0 {
+		t.Error("Expected the trap to be disarmed")
+	}
+	if m.dungeon[pos.Y][pos.X] != Empty {
+		t.Errorf("Expected the trap tile to be cleared, got %v", m.dungeon[pos.Y][pos.X])
+	}
+}